@@ -0,0 +1,195 @@
+package clockwork
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SQL dialects accepted in QueryDetails.Dialect. They select the placeholder syntax and
+// literal formatting used for DatabaseQuery.Runnable.
+const (
+	DialectPostgres  = "postgres"
+	DialectMySQL     = "mysql"
+	DialectSQLite    = "sqlite"
+	DialectSQLServer = "sqlserver"
+)
+
+// bindingsEstimate approximates the payload bytes bindings add to a query.
+func bindingsEstimate(bindings []interface{}) int {
+	n := 0
+	for _, v := range bindings {
+		switch value := v.(type) {
+		case string:
+			n += len(value) + 4
+		case []byte:
+			n += len(value) + 4
+		default:
+			n += 16
+		}
+	}
+	return n
+}
+
+// bindingValue resolves driver.Valuer arguments to the value sent to the database.
+func bindingValue(v interface{}) interface{} {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if resolved, err := valuer.Value(); err == nil {
+			return resolved
+		}
+	}
+	return v
+}
+
+// normalizeBinding converts a bound argument to a JSON-friendly value. Strings are
+// truncated and binary values summarized.
+func (c *Collector) normalizeBinding(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return value
+	case string:
+		return c.truncate(value)
+	case []byte:
+		if utf8.Valid(value) {
+			return c.truncate(string(value))
+		}
+		return fmt.Sprintf("[%d bytes]", len(value))
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return c.truncate(value.String())
+	default:
+		return c.truncate(fmt.Sprintf("%v", value))
+	}
+}
+
+// InterpolateQuery replaces the placeholders in query with bindings formatted as SQL
+// literals for dialect, producing a statement that can be pasted into a database shell.
+// Postgres uses $n, MySQL and SQLite use ? (SQLite also ?NNN, :name, @name and $name,
+// numbered after the largest ?NNN seen so far, as SQLite does) and SQL Server uses @pN. Placeholders inside quoted strings and
+// identifiers are left alone, as are placeholders without a matching binding.
+func InterpolateQuery(query string, bindings []interface{}, dialect string) string {
+	if len(bindings) == 0 {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 16*len(bindings))
+	next := 0
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := skipQuoted(query, i, ch, dialect == DialectMySQL)
+			b.WriteString(query[i:end])
+			i = end
+			continue
+		case ch == '?' && dialect != DialectPostgres:
+			end := scan(query, i+1, isDigit)
+			index := next
+			if end > i+1 {
+				index, _ = strconv.Atoi(query[i+1 : end])
+				next = max(next, index)
+				index--
+			} else {
+				next++
+			}
+			if writeBinding(&b, bindings, index, dialect) {
+				i = end
+				continue
+			}
+		case ch == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			end := scan(query, i+1, isDigit)
+			index, _ := strconv.Atoi(query[i+1 : end])
+			if writeBinding(&b, bindings, index-1, dialect) {
+				i = end
+				continue
+			}
+		case ch == '@' && dialect == DialectSQLServer && i+2 < len(query) && (query[i+1] == 'p' || query[i+1] == 'P') && isDigit(query[i+2]):
+			end := scan(query, i+2, isDigit)
+			index, _ := strconv.Atoi(query[i+2 : end])
+			if writeBinding(&b, bindings, index-1, dialect) {
+				i = end
+				continue
+			}
+		case (ch == ':' || ch == '@' || ch == '$') && dialect == DialectSQLite && i+1 < len(query) && isIdentStart(query[i+1]) && (i == 0 || query[i-1] != ':'):
+			end := scan(query, i+1, isWordByte)
+			if writeBinding(&b, bindings, next, dialect) {
+				next++
+				i = end
+				continue
+			}
+		}
+		b.WriteByte(ch)
+		i++
+	}
+	return b.String()
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func writeBinding(b *strings.Builder, bindings []interface{}, index int, dialect string) bool {
+	if index < 0 || index >= len(bindings) {
+		return false
+	}
+	b.WriteString(sqlLiteral(bindings[index], dialect))
+	return true
+}
+
+// sqlLiteral formats v as a SQL literal for dialect.
+func sqlLiteral(v interface{}, dialect string) string {
+	switch value := bindingValue(v).(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if dialect == DialectPostgres {
+			return strings.ToUpper(strconv.FormatBool(value))
+		}
+		if value {
+			return "1"
+		}
+		return "0"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", value)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case []byte:
+		if utf8.Valid(value) {
+			return quoteString(string(value), dialect)
+		}
+		switch dialect {
+		case DialectPostgres:
+			return `'\x` + hex.EncodeToString(value) + `'`
+		case DialectSQLServer:
+			return "0x" + hex.EncodeToString(value)
+		default:
+			return "X'" + hex.EncodeToString(value) + "'"
+		}
+	case time.Time:
+		return quoteString(value.Format("2006-01-02 15:04:05.999999Z07:00"), dialect)
+	case string:
+		return quoteString(value, dialect)
+	case fmt.Stringer:
+		return quoteString(value.String(), dialect)
+	default:
+		return quoteString(fmt.Sprintf("%v", value), dialect)
+	}
+}
+
+func quoteString(s, dialect string) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	if dialect == DialectMySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	if dialect == DialectSQLServer {
+		return "N'" + s + "'"
+	}
+	return "'" + s + "'"
+}
@@ -0,0 +1,53 @@
+package clockwork
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolateQuery(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		dialect  string
+		query    string
+		bindings []interface{}
+		want     string
+	}{
+		{DialectPostgres, "SELECT * FROM users WHERE id = $2 AND name = $1 AND data ? 'k'", []interface{}{"O'Brien", 7}, "SELECT * FROM users WHERE id = 7 AND name = 'O''Brien' AND data ? 'k'"},
+		{DialectPostgres, "UPDATE flags SET on = $1, blob = $2", []interface{}{true, []byte{0xff, 0x00}}, `UPDATE flags SET on = TRUE, blob = '\xff00'`},
+		{DialectMySQL, "SELECT * FROM t WHERE a = ? AND b = ? AND c = '?'", []interface{}{`C:\tmp`, nil}, `SELECT * FROM t WHERE a = 'C:\\tmp' AND b = NULL AND c = '?'`},
+		{DialectSQLite, "SELECT * FROM t WHERE a = ?2 AND b = :name", []interface{}{1.5, false}, "SELECT * FROM t WHERE a = 0 AND b = :name"},
+		{DialectSQLite, "SELECT * FROM t WHERE a = ?2 AND b = ? AND c = :name AND d = ?1", []interface{}{1, 2, 3, 4}, "SELECT * FROM t WHERE a = 2 AND b = 3 AND c = 4 AND d = 1"},
+		{DialectSQLServer, "SELECT * FROM t WHERE a = @p1 AND b = @p2", []interface{}{"x", at}, "SELECT * FROM t WHERE a = N'x' AND b = N'2024-05-01 10:30:00Z'"},
+		{DialectMySQL, "SELECT * FROM t WHERE a = ? AND b = ?", []interface{}{1}, "SELECT * FROM t WHERE a = 1 AND b = ?"},
+		{DialectPostgres, `SELECT 'C:\' AS p, $1`, []interface{}{5}, `SELECT 'C:\' AS p, 5`},
+		{DialectSQLite, `SELECT 'C:\' AS p, ?`, []interface{}{5}, `SELECT 'C:\' AS p, 5`},
+		{DialectSQLServer, `SELECT 'C:\' AS p, @p1`, []interface{}{5}, `SELECT 'C:\' AS p, 5`},
+		{DialectMySQL, `SELECT 'it\'s ?' AS p, ?`, []interface{}{5}, `SELECT 'it\'s ?' AS p, 5`},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, InterpolateQuery(tt.query, tt.bindings, tt.dialect), tt.query)
+	}
+}
+
+func TestCollector_RecordsBindings(t *testing.T) {
+	collector := NewCollector("GET", "/users", collectorLimits{maxStringLen: 80})
+	collector.AddDatabaseQueryDetailed("SELECT * FROM users WHERE email = $1 AND id = $2", time.Millisecond, "pg", false, "", "users.go", 3,
+		QueryDetails{Bindings: []interface{}{"ann@example.com", int64(7)}, Dialect: DialectPostgres})
+	collector.AddDatabaseQueryDetailed("SELECT * FROM notes WHERE body = ?", time.Millisecond, "mysql", false, "", "notes.go", 9,
+		QueryDetails{Bindings: []interface{}{strings.Repeat("n", 100)}})
+
+	meta := collector.GetMetadata()
+	require.Equal(t, []interface{}{"ann@example.com", int64(7)}, meta.DatabaseQueries[0].Bindings)
+	require.Equal(t, "SELECT * FROM users WHERE email = 'ann@example.com' AND id = 7", meta.DatabaseQueries[0].Runnable)
+	require.Equal(t, []interface{}{strings.Repeat("n", 80)}, meta.DatabaseQueries[1].Bindings)
+	require.Empty(t, meta.DatabaseQueries[1].Runnable)
+
+	redacted := defaultRedactor.Metadata(meta)
+	require.Equal(t, "[REDACTED]", redacted.DatabaseQueries[0].Bindings[0])
+	require.NotContains(t, redacted.DatabaseQueries[0].Runnable, "ann@example.com")
+	require.Equal(t, "ann@example.com", meta.DatabaseQueries[0].Bindings[0], "redaction copies")
+}
@@ -0,0 +1,298 @@
+package clockwork
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BodyBuffer keeps the first limit bytes written to it and counts the rest.
+// Methods on a nil *BodyBuffer are no-ops, so adapters can tee into it unconditionally.
+type BodyBuffer struct {
+	limit int
+	buf   bytes.Buffer
+	size  int64
+}
+
+// NewBodyBuffer creates a buffer that retains at most limit bytes; limit <= 0 retains nothing.
+func NewBodyBuffer(limit int) *BodyBuffer {
+	return &BodyBuffer{limit: limit}
+}
+
+// Write records p up to the limit and always reports the full length as written.
+func (b *BodyBuffer) Write(p []byte) (int, error) {
+	if b == nil {
+		return len(p), nil
+	}
+	b.size += int64(len(p))
+	if room := b.limit - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// Bytes returns the retained prefix.
+func (b *BodyBuffer) Bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	return b.buf.Bytes()
+}
+
+// Size returns the total number of bytes written, including those not retained.
+func (b *BodyBuffer) Size() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.size
+}
+
+type teeReadCloser struct {
+	io.ReadCloser
+	buf *BodyBuffer
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = t.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+// CaptureRequestBody wraps r.Body so the bytes the handler reads are copied into the returned buffer.
+// It returns nil when request body capture is disabled, the request has no body, or its content type
+// is not listed in Config.BodyContentTypes. Pass the buffer to RecordRequestBody once the handler returns.
+func (c *Clockwork) CaptureRequestBody(r *http.Request) *BodyBuffer {
+	if c == nil || !c.config.CaptureRequestBody || r == nil || r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	if !c.BodyContentTypeAllowed(r.Header.Get("Content-Type")) {
+		return nil
+	}
+	buf := NewBodyBuffer(c.config.MaxRequestPayloadBytes)
+	r.Body = &teeReadCloser{ReadCloser: r.Body, buf: buf}
+	return buf
+}
+
+// ResponseBodyBuffer returns a buffer for adapters to tee response writes into,
+// or nil when response body capture is disabled.
+func (c *Clockwork) ResponseBodyBuffer() *BodyBuffer {
+	if c == nil || !c.config.CaptureResponseBody {
+		return nil
+	}
+	return NewBodyBuffer(c.config.MaxRequestPayloadBytes)
+}
+
+// RecordRequestBody decodes a captured request body onto the collector: form and multipart
+// fields go to postData (files are summarized, not stored), anything else to requestData.
+func (c *Clockwork) RecordRequestBody(collector *Collector, contentType string, body *BodyBuffer) {
+	if c == nil || collector == nil || body == nil || body.Size() == 0 || !c.BodyContentTypeAllowed(contentType) {
+		return
+	}
+	collector.SetRequestBody(contentType, body.Bytes(), body.Size())
+}
+
+// RecordResponseBody records a captured response body as responseData.
+func (c *Clockwork) RecordResponseBody(collector *Collector, contentType string, body *BodyBuffer) {
+	if c == nil || collector == nil || body == nil || body.Size() == 0 || !c.BodyContentTypeAllowed(contentType) {
+		return
+	}
+	collector.SetResponseBody(contentType, body.Bytes(), body.Size())
+}
+
+// BodyContentTypeAllowed reports whether bodies of contentType match Config.BodyContentTypes.
+func (c *Clockwork) BodyContentTypeAllowed(contentType string) bool {
+	if c == nil {
+		return false
+	}
+	mediaType := mediaTypeOf(contentType)
+	return mediaType != "" && matchAny(c.bodyTypes, mediaType)
+}
+
+// SetRequestBody decodes body (the first bytes of a body of size bytes) into postData or requestData.
+// The retained bytes count against the MaxRequestPayloadBytes budget; for multipart bodies only the
+// field values and file summaries are retained, so file contents are not charged.
+func (c *Collector) SetRequestBody(contentType string, body []byte, size int64) {
+	if c == nil || len(body) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if mediaType == "multipart/form-data" && params["boundary"] != "" {
+		room := -1
+		if c.limits.maxRequestBytes > 0 {
+			room = max(c.limits.maxRequestBytes-c.usedBytes, 0)
+		}
+		if data, used, cut := parseMultipart(body, params["boundary"], room); len(data) > 0 || cut {
+			c.usedBytes += used
+			if cut || int64(len(body)) < size {
+				c.dropped["requestBody"]++
+				c.truncated = true
+			}
+			if len(data) > 0 {
+				c.postData = data
+			}
+			return
+		}
+	}
+
+	body, complete := c.reserveBodyLocked("requestBody", body, size)
+	if len(body) == 0 {
+		return
+	}
+	if mediaType == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			c.postData = formValues(values)
+			return
+		}
+	}
+	c.requestData = decodeBody(mediaType, body, complete)
+}
+
+// SetResponseBody records body (the first bytes of a body of size bytes) as responseData.
+func (c *Collector) SetResponseBody(contentType string, body []byte, size int64) {
+	if c == nil || len(body) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	body, complete := c.reserveBodyLocked("responseBody", body, size)
+	if len(body) == 0 {
+		return
+	}
+	c.responseData = decodeBody(mediaTypeOf(contentType), body, complete)
+}
+
+// reserveBodyLocked trims body to the remaining payload budget and reports whether the
+// result is the complete body.
+func (c *Collector) reserveBodyLocked(bucket string, body []byte, size int64) ([]byte, bool) {
+	if c.limits.maxRequestBytes > 0 {
+		room := c.limits.maxRequestBytes - c.usedBytes
+		if room <= 0 {
+			c.dropped[bucket]++
+			c.truncated = true
+			return nil, false
+		}
+		if len(body) > room {
+			body = body[:room]
+		}
+	}
+	c.usedBytes += len(body)
+
+	complete := int64(len(body)) >= size
+	if !complete {
+		c.dropped[bucket]++
+		c.truncated = true
+	}
+	return body, complete
+}
+
+// decodeBody returns parsed JSON for complete JSON bodies and the raw text otherwise.
+func decodeBody(mediaType string, body []byte, complete bool) interface{} {
+	if complete && isJSONMediaType(mediaType) {
+		var decoded interface{}
+		if err := json.Unmarshal(body, &decoded); err == nil {
+			return decoded
+		}
+	}
+	return string(body)
+}
+
+func formValues(values url.Values) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for key, items := range values {
+		if len(items) == 1 {
+			out[key] = items[0]
+			continue
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = item
+		}
+		out[key] = list
+	}
+	return out
+}
+
+// parseMultipart returns form fields and file summaries (filename, content type, size) from
+// a possibly truncated multipart body, with the number of bytes they retain. File contents are
+// never kept; a file cut off by the end of body is marked truncated, its size being the bytes
+// seen. With room >= 0, parsing stops at the first part that does not fit and cut is set.
+func parseMultipart(body []byte, boundary string, room int) (out map[string]interface{}, used int, cut bool) {
+	out = make(map[string]interface{})
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		name := part.FormName()
+		if name == "" {
+			_ = part.Close()
+			continue
+		}
+		if filename := part.FileName(); filename != "" {
+			contentType := part.Header.Get("Content-Type")
+			retained := len(name) + len(filename) + len(contentType)
+			if room >= 0 && used+retained > room {
+				_ = part.Close()
+				return out, used, true
+			}
+			size, copyErr := io.Copy(io.Discard, part)
+			_ = part.Close()
+			summary := map[string]interface{}{
+				"filename":    filename,
+				"contentType": contentType,
+				"size":        size,
+			}
+			if copyErr != nil {
+				summary["truncated"] = true
+			}
+			out[name] = summary
+			used += retained
+			if copyErr != nil {
+				break
+			}
+			continue
+		}
+		var value strings.Builder
+		_, copyErr := io.Copy(&value, part)
+		_ = part.Close()
+		retained := len(name) + value.Len()
+		if room >= 0 && used+retained > room {
+			return out, used, true
+		}
+		out[name] = value.String()
+		used += retained
+		if copyErr != nil && !errors.Is(copyErr, io.EOF) {
+			break
+		}
+	}
+	return out, used, false
+}
+
+func mediaTypeOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	return mediaType
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
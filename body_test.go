@@ -0,0 +1,114 @@
+package clockwork
+
+import (
+	"bytes"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyBuffer_Limit(t *testing.T) {
+	buf := NewBodyBuffer(4)
+	n, err := buf.Write([]byte("abcdef"))
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+	require.Equal(t, "abcd", string(buf.Bytes()))
+	require.EqualValues(t, 6, buf.Size())
+
+	var nilBuf *BodyBuffer
+	n, _ = nilBuf.Write([]byte("x"))
+	require.Equal(t, 1, n)
+}
+
+func TestCollector_SetRequestBody(t *testing.T) {
+	c := NewCollector("POST", "/orders", limitsFromConfig(DefaultConfig()))
+	c.SetRequestBody("application/json; charset=utf-8", []byte(`{"id":1}`), 8)
+	require.Equal(t, map[string]interface{}{"id": float64(1)}, c.GetMetadata().RequestData)
+
+	c = NewCollector("POST", "/login", limitsFromConfig(DefaultConfig()))
+	c.SetRequestBody("application/x-www-form-urlencoded", []byte("user=jane&tag=a&tag=b"), 21)
+	require.Equal(t, map[string]interface{}{"user": "jane", "tag": []interface{}{"a", "b"}}, c.GetMetadata().PostData)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("title", "report"))
+	fw, err := mw.CreateFormFile("upload", "report.csv")
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte("a,b,c\n1,2,3\n"))
+	require.NoError(t, mw.Close())
+
+	c = NewCollector("POST", "/upload", limitsFromConfig(DefaultConfig()))
+	c.SetRequestBody(mw.FormDataContentType(), body.Bytes(), int64(body.Len()))
+	meta := c.GetMetadata()
+	require.Equal(t, "report", meta.PostData["title"])
+	require.Equal(t, map[string]interface{}{
+		"filename":    "report.csv",
+		"contentType": "application/octet-stream",
+		"size":        int64(12),
+	}, meta.PostData["upload"])
+	require.Nil(t, meta.RequestData)
+}
+
+func multipartBody(t *testing.T, upload []byte, fields ...string) (string, []byte) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("title", "report"))
+	fw, err := mw.CreateFormFile("upload", "report.csv")
+	require.NoError(t, err)
+	_, _ = fw.Write(upload)
+	for i := 0; i+1 < len(fields); i += 2 {
+		require.NoError(t, mw.WriteField(fields[i], fields[i+1]))
+	}
+	require.NoError(t, mw.Close())
+	return mw.FormDataContentType(), body.Bytes()
+}
+
+func TestCollector_SetRequestBody_MultipartBudget(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxRequestPayloadBytes = 64
+	contentType, body := multipartBody(t, bytes.Repeat([]byte("x"), 4096), "note", "urgent")
+
+	c := NewCollector("POST", "/upload", limitsFromConfig(cfg))
+	c.SetRequestBody(contentType, body, int64(len(body)))
+	meta := c.GetMetadata()
+	require.Equal(t, "report", meta.PostData["title"])
+	require.Equal(t, "urgent", meta.PostData["note"], "file contents are not charged")
+	require.Equal(t, int64(4096), meta.PostData["upload"].(map[string]interface{})["size"])
+	require.False(t, meta.Truncated)
+
+	contentType, body = multipartBody(t, bytes.Repeat([]byte("x"), 4096), "comment", string(bytes.Repeat([]byte("y"), 100)))
+	c = NewCollector("POST", "/upload", limitsFromConfig(cfg))
+	c.SetRequestBody(contentType, body, int64(len(body)))
+	meta = c.GetMetadata()
+	require.Contains(t, meta.PostData, "upload")
+	require.NotContains(t, meta.PostData, "comment", "fields beyond the budget are dropped")
+	require.True(t, meta.Truncated)
+	require.Equal(t, 1, meta.Dropped["requestBody"])
+}
+
+func TestCollector_SetRequestBody_TruncatedUpload(t *testing.T) {
+	contentType, body := multipartBody(t, bytes.Repeat([]byte("x"), 4096))
+	cut := body[:len(body)/2]
+
+	c := NewCollector("POST", "/upload", limitsFromConfig(DefaultConfig()))
+	c.SetRequestBody(contentType, cut, int64(len(body)))
+	meta := c.GetMetadata()
+	upload := meta.PostData["upload"].(map[string]interface{})
+	require.Equal(t, true, upload["truncated"])
+	require.Less(t, upload["size"].(int64), int64(4096))
+	require.True(t, meta.Truncated)
+}
+
+func TestCollector_SetResponseBody_Truncated(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxRequestPayloadBytes = 5
+	c := NewCollector("GET", "/", limitsFromConfig(cfg))
+	c.SetResponseBody("application/json", []byte(`{"a":1}`), 7)
+
+	meta := c.GetMetadata()
+	require.Equal(t, `{"a":`, meta.ResponseData)
+	require.True(t, meta.Truncated)
+	require.Equal(t, 1, meta.Dropped["responseBody"])
+}
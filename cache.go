@@ -0,0 +1,92 @@
+package clockwork
+
+import (
+	"time"
+)
+
+// Cache operation types accepted by Collector.AddCacheQuery. Hits and misses count as
+// reads in Metadata.CacheReads; other types only add to Metadata.CacheTime.
+const (
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheWrite  = "write"
+	CacheDelete = "delete"
+)
+
+// CacheDetails carries optional data for Collector.AddCacheQueryDetailed.
+type CacheDetails struct {
+	// Connection names the cache store, e.g. "redis" or "local".
+	Connection string
+	// Size is the value size in bytes, when known.
+	Size int
+	// Expiration is the TTL of a write; zero means none.
+	Expiration time.Duration
+	Err        error
+	// SkipTimeline leaves the operation off the timeline, for stores whose integration
+	// already adds an event for it, such as a Redis command.
+	SkipTimeline bool
+}
+
+// cacheStats accumulates the Metadata.Cache* summary, including dropped operations.
+type cacheStats struct {
+	reads   int
+	hits    int
+	writes  int
+	deletes int
+	time    float64
+}
+
+// AddCacheQueryDetailed adds a cache operation with the store name, value size, TTL and
+// error from details. Operations count toward the cache summary even when the list is full.
+func (c *Collector) AddCacheQueryDetailed(cacheType, key string, duration time.Duration, details ...CacheDetails) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	durationMS := DurationMs(duration)
+	switch cacheType {
+	case CacheHit:
+		c.cacheStats.reads++
+		c.cacheStats.hits++
+	case CacheMiss:
+		c.cacheStats.reads++
+	case CacheWrite:
+		c.cacheStats.writes++
+	case CacheDelete:
+		c.cacheStats.deletes++
+	}
+	c.cacheStats.time += durationMS
+
+	if !c.reserveLocked("cache", c.limits.maxCacheQueries, len(c.cacheQueries), len(key)+64) {
+		return
+	}
+
+	cq := CacheQuery{
+		Type:      c.truncate(cacheType),
+		Key:       c.truncate(key),
+		Duration:  durationMS,
+		Timestamp: unixTimestamp(),
+	}
+	skipTimeline := false
+	for _, d := range details {
+		skipTimeline = d.SkipTimeline
+		cq.Connection = c.truncate(d.Connection)
+		cq.Size = d.Size
+		cq.Expiration = d.Expiration.Seconds()
+		if d.Err != nil {
+			cq.Error = c.truncate(d.Err.Error())
+		}
+	}
+	c.cacheQueries = append(c.cacheQueries, cq)
+	if skipTimeline {
+		return
+	}
+
+	color := "purple"
+	if cq.Error != "" {
+		color = "red"
+	}
+	c.appendTimelineLocked("cache", cq.Type+": "+cq.Key, cq.Timestamp-durationMS/1000, cq.Timestamp, color)
+}
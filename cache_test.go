@@ -0,0 +1,43 @@
+package clockwork
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector_AddCacheQueryDetailed(t *testing.T) {
+	collector := NewCollector("GET", "/users", collectorLimits{maxCacheQueries: 3})
+	collector.AddCacheQueryDetailed(CacheHit, "user:1", 2*time.Millisecond, CacheDetails{Connection: "redis", Size: 42})
+	collector.AddCacheQueryDetailed(CacheMiss, "user:2", time.Millisecond, CacheDetails{Connection: "redis", Err: errors.New("timeout")})
+	collector.AddCacheQueryDetailed(CacheWrite, "user:2", time.Millisecond, CacheDetails{Expiration: 90 * time.Second, Size: 40})
+	collector.AddCacheQuery(CacheDelete, "user:3", time.Millisecond)
+	collector.AddCacheQuery(CacheHit, "user:4", time.Millisecond)
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.CacheQueries, 3)
+	require.Equal(t, CacheQuery{Type: CacheHit, Key: "user:1", Duration: 2, Timestamp: meta.CacheQueries[0].Timestamp, Connection: "redis", Size: 42}, meta.CacheQueries[0])
+	require.Equal(t, "timeout", meta.CacheQueries[1].Error)
+	require.Equal(t, 90.0, meta.CacheQueries[2].Expiration)
+	require.Equal(t, 2, meta.Dropped["cache"])
+
+	require.Equal(t, 3, meta.CacheReads, "dropped operations still count")
+	require.Equal(t, 2, meta.CacheHits)
+	require.Equal(t, 1, meta.CacheWrites)
+	require.Equal(t, 1, meta.CacheDeletes)
+	require.InDelta(t, 6.0, meta.CacheTime, 0.001)
+	require.Equal(t, "red", meta.TimelineEvents[1].Color)
+}
+
+func TestCollector_AddCacheQueryDetailedSkipsTimeline(t *testing.T) {
+	collector := NewCollector("GET", "/users", collectorLimits{})
+	collector.AddCacheQueryDetailed(CacheHit, "user:1", time.Millisecond, CacheDetails{Connection: "redis", SkipTimeline: true})
+	collector.AddCacheQuery(CacheMiss, "user:2", time.Millisecond)
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.CacheQueries, 2)
+	require.Len(t, meta.TimelineEvents, 1)
+	require.Equal(t, "miss: user:2", meta.TimelineEvents[0].Description)
+}
@@ -0,0 +1,101 @@
+package clockwork
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+)
+
+type captureDecision int
+
+const (
+	captureNone captureDecision = iota
+	captureKeep
+	// captureTail collects the request and decides whether to keep it once the response is known.
+	captureTail
+)
+
+type routeSampleRate struct {
+	pattern *regexp.Regexp
+	rate    float64
+}
+
+// compileRouteSampleRates orders route rates so the longest (most specific) pattern is tried first.
+func compileRouteSampleRates(rates map[string]float64) []routeSampleRate {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	patterns := make([]string, 0, len(rates))
+	for pattern := range rates {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	out := make([]routeSampleRate, 0, len(patterns))
+	for _, pattern := range patterns {
+		re := compileGlob(pattern)
+		if re == nil {
+			re = regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$")
+		}
+		out = append(out, routeSampleRate{pattern: re, rate: rates[pattern]})
+	}
+	return out
+}
+
+func (c *Clockwork) decideCapture(r *http.Request) captureDecision {
+	if policy := c.CapturePolicy(); policy != nil {
+		switch policy.Evaluate(r) {
+		case CaptureDeny:
+			return captureNone
+		case CaptureAllow:
+			return captureKeep
+		}
+	}
+
+	if ShouldCapture(r.Header, c.config.HeaderName) || c.profileRequested(r) {
+		return captureKeep
+	}
+
+	switch c.config.CaptureMode {
+	case CaptureModeAlways:
+		return captureKeep
+	case CaptureModeSample:
+		if rate := c.sampleRate(r.URL.Path); rate > 0 && rand.Float64() < rate {
+			return captureKeep
+		}
+	}
+
+	if c.tailSamplingEnabled() {
+		return captureTail
+	}
+	return captureNone
+}
+
+func (c *Clockwork) sampleRate(path string) float64 {
+	for _, route := range c.routeRates {
+		if route.pattern.MatchString(path) {
+			return route.rate
+		}
+	}
+	return c.config.SampleRate
+}
+
+func (c *Clockwork) tailSamplingEnabled() bool {
+	return c.config.TailSlowThreshold > 0 || c.config.TailServerErrors
+}
+
+// keepTailSampled reports whether a tail-sampled request qualifies for storage.
+func (c *Clockwork) keepTailSampled(status int, duration time.Duration) bool {
+	if c.config.TailServerErrors && status >= http.StatusInternalServerError {
+		return true
+	}
+	return c.config.TailSlowThreshold > 0 && duration >= c.config.TailSlowThreshold
+}
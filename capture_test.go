@@ -0,0 +1,89 @@
+package clockwork
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRequestCapture_Modes(t *testing.T) {
+	header := http.Header{"X-Clockwork": {""}}
+
+	cfg := DefaultConfig()
+	cw := NewClockwork(cfg, NewInMemoryStorage(10, 1024*1024))
+	_, ok := NewRequestCapture(cw, "GET", "/ok", "/ok", http.Header{})
+	require.False(t, ok)
+	_, ok = NewRequestCapture(cw, "GET", "/ok", "/ok", header)
+	require.True(t, ok)
+
+	cfg.CaptureMode = CaptureModeAlways
+	cw = NewClockwork(cfg, NewInMemoryStorage(10, 1024*1024))
+	_, ok = NewRequestCapture(cw, "GET", "/ok", "/ok", http.Header{})
+	require.True(t, ok)
+	_, ok = NewRequestCapture(cw, "GET", "/__clockwork/latest", "/__clockwork/latest", header)
+	require.False(t, ok)
+
+	cfg.CaptureMode = CaptureModeSample
+	cfg.SampleRate = 0
+	cfg.RouteSampleRates = map[string]float64{"/orders/*": 1, "/orders/health": 0}
+	cw = NewClockwork(cfg, NewInMemoryStorage(10, 1024*1024))
+	_, ok = NewRequestCapture(cw, "GET", "/users", "/users", http.Header{})
+	require.False(t, ok)
+	_, ok = NewRequestCapture(cw, "GET", "/orders/42", "/orders/42", http.Header{})
+	require.True(t, ok)
+	_, ok = NewRequestCapture(cw, "GET", "/orders/health", "/orders/health", http.Header{})
+	require.False(t, ok)
+}
+
+func TestCompleteRequest_TailSampling(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TailSlowThreshold = 500 * time.Millisecond
+	cfg.TailServerErrors = true
+	store := NewInMemoryStorage(10, 1024*1024)
+	cw := NewClockwork(cfg, store)
+	ctx := context.Background()
+
+	fast, ok := NewRequestCapture(cw, "GET", "/fast", "/fast", http.Header{})
+	require.True(t, ok)
+	require.NoError(t, cw.CompleteRequest(ctx, fast, http.StatusOK, 10*time.Millisecond))
+
+	slow, ok := NewRequestCapture(cw, "GET", "/slow", "/slow", http.Header{})
+	require.True(t, ok)
+	require.NoError(t, cw.CompleteRequest(ctx, slow, http.StatusOK, time.Second))
+
+	failed, ok := NewRequestCapture(cw, "GET", "/failed", "/failed", http.Header{})
+	require.True(t, ok)
+	require.NoError(t, cw.CompleteRequest(ctx, failed, http.StatusBadGateway, 10*time.Millisecond))
+
+	headerTriggered, ok := NewRequestCapture(cw, "GET", "/header", "/header", http.Header{"X-Clockwork": {""}})
+	require.True(t, ok)
+	require.NoError(t, cw.CompleteRequest(ctx, headerTriggered, http.StatusOK, time.Millisecond))
+
+	_, err := store.Get(ctx, fast.ID())
+	require.Error(t, err)
+	for _, id := range []string{slow.ID(), failed.ID(), headerTriggered.ID()} {
+		_, err := store.Get(ctx, id)
+		require.NoError(t, err)
+	}
+}
+
+func TestCaptureRequest_RecordsParentAndSubrequests(t *testing.T) {
+	cw := NewClockwork(DefaultConfig(), NewInMemoryStorage(10, 1024*1024))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("X-Clockwork", "1")
+	req.Header.Set(ParentIDHeader, "gateway-1")
+	collector, ok := CaptureRequest(cw, req)
+	require.True(t, ok)
+
+	collector.AddSubrequest("http://billing.local/invoices?id=3", "billing-9", "")
+	collector.AddSubrequest("http://ignored.local", "", "")
+
+	meta := collector.GetMetadata()
+	require.Equal(t, "gateway-1", meta.Parent)
+	require.Equal(t, []Subrequest{{URL: "http://billing.local/invoices?id=3", ID: "billing-9", Path: DefaultPath}}, meta.Subrequests)
+}
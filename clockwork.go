@@ -3,8 +3,8 @@ package clockwork
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -16,16 +16,33 @@ type Clockwork struct {
 	dataSources   []DataSource
 	dataSourcesMu sync.RWMutex
 
-	activeByTrace sync.Map // map[traceID]*Collector
-	activeCount   atomic.Int64
+	routeRates []routeSampleRate
+	policy     CapturePolicy
+	policyMu   sync.RWMutex
+	redactor   *Redactor
+	bodyTypes  []*regexp.Regexp
+
+	session   SessionResolver
+	sessionMu sync.RWMutex
+
+	registry correlationRegistry
+
+	// deferred tracks completions waiting for query plans.
+	deferred sync.WaitGroup
 }
 
 // NewClockwork creates a new Clockwork service.
 func NewClockwork(cfg Config, storage Storage) *Clockwork {
 	cfg.Normalize()
+	// Invalid redaction patterns are skipped here; config.Load reports them.
+	redactor, _ := NewRedactor(cfg.Redaction)
 	return &Clockwork{
-		config:  cfg,
-		storage: storage,
+		config:     cfg,
+		storage:    storage,
+		routeRates: compileRouteSampleRates(cfg.RouteSampleRates),
+		policy:     PolicyFromConfig(cfg.CapturePolicy),
+		redactor:   redactor,
+		bodyTypes:  compileGlobs(cfg.BodyContentTypes),
 	}
 }
 
@@ -45,12 +62,20 @@ func (c *Clockwork) Storage() Storage {
 	return c.storage
 }
 
+// Redactor returns the redactor applied to metadata before it is stored.
+func (c *Clockwork) Redactor() *Redactor {
+	if c == nil {
+		return nil
+	}
+	return c.redactor
+}
+
 // IsEnabled indicates whether Clockwork collection is enabled.
 func (c *Clockwork) IsEnabled() bool {
 	return c != nil && c.config.Enabled
 }
 
-// SaveMetadata stores request metadata.
+// SaveMetadata redacts and stores request metadata.
 func (c *Clockwork) SaveMetadata(ctx context.Context, metadata *Metadata) error {
 	if c == nil || !c.config.Enabled || c.storage == nil {
 		return nil
@@ -58,7 +83,7 @@ func (c *Clockwork) SaveMetadata(ctx context.Context, metadata *Metadata) error
 	if metadata == nil {
 		return nil
 	}
-	return c.storage.Store(ctx, metadata)
+	return c.storage.Store(ctx, c.redactor.Metadata(metadata))
 }
 
 // GetMetadata fetches metadata by request id.
@@ -77,6 +102,45 @@ func (c *Clockwork) ListMetadata(ctx context.Context, limit int) ([]*Metadata, e
 	return c.storage.List(ctx, limit)
 }
 
+// LatestMetadata returns the most recently stored metadata entry.
+func (c *Clockwork) LatestMetadata(ctx context.Context) (*Metadata, error) {
+	if c == nil || c.storage == nil {
+		return nil, fmt.Errorf("clockwork storage is not configured")
+	}
+	items, err := c.storage.List(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("clockwork metadata not found: latest")
+	}
+	return items[0], nil
+}
+
+// PreviousMetadata returns up to limit entries stored before id, oldest first.
+func (c *Clockwork) PreviousMetadata(ctx context.Context, id string, limit int) ([]*Metadata, error) {
+	if c == nil || c.storage == nil {
+		return nil, fmt.Errorf("clockwork storage is not configured")
+	}
+	return c.storage.Previous(ctx, id, limit)
+}
+
+// NextMetadata returns up to limit entries stored after id, oldest first.
+func (c *Clockwork) NextMetadata(ctx context.Context, id string, limit int) ([]*Metadata, error) {
+	if c == nil || c.storage == nil {
+		return nil, fmt.Errorf("clockwork storage is not configured")
+	}
+	return c.storage.Next(ctx, id, limit)
+}
+
+// SearchMetadata returns stored entries matching query, most recent first.
+func (c *Clockwork) SearchMetadata(ctx context.Context, query Query) ([]*Metadata, error) {
+	if c == nil || c.storage == nil {
+		return nil, fmt.Errorf("clockwork storage is not configured")
+	}
+	return c.storage.Search(ctx, query)
+}
+
 // Cleanup removes old entries from storage.
 func (c *Clockwork) Cleanup(ctx context.Context) error {
 	if c == nil || c.storage == nil {
@@ -112,6 +176,27 @@ func (c *Clockwork) RegisterDataSource(ds DataSource) {
 	c.dataSources = append(c.dataSources, ds)
 }
 
+// SetCapturePolicy replaces the policy built from Config.CapturePolicy.
+// Use ChainPolicies with PolicyFromConfig to keep the configured rules alongside custom ones.
+func (c *Clockwork) SetCapturePolicy(policy CapturePolicy) {
+	if c == nil {
+		return
+	}
+	c.policyMu.Lock()
+	defer c.policyMu.Unlock()
+	c.policy = policy
+}
+
+// CapturePolicy returns the active capture policy.
+func (c *Clockwork) CapturePolicy() CapturePolicy {
+	if c == nil {
+		return nil
+	}
+	c.policyMu.RLock()
+	defer c.policyMu.RUnlock()
+	return c.policy
+}
+
 // CompleteRequest finalizes and stores collected request data.
 func (c *Clockwork) CompleteRequest(ctx context.Context, collector *Collector, status int, duration time.Duration) error {
 	if c == nil || collector == nil {
@@ -119,6 +204,12 @@ func (c *Clockwork) CompleteRequest(ctx context.Context, collector *Collector, s
 	}
 
 	collector.SetResponseData(status, duration)
+	profiles := c.finishProfile(collector)
+
+	if collector.tailSampled && !c.keepTailSampled(status, duration) {
+		c.registry.remove(collector)
+		return nil
+	}
 
 	c.dataSourcesMu.RLock()
 	sources := c.dataSources
@@ -126,38 +217,68 @@ func (c *Clockwork) CompleteRequest(ctx context.Context, collector *Collector, s
 	for _, ds := range sources {
 		ds.Resolve(ctx, collector)
 	}
+	c.resolveSession(ctx, collector)
+
+	c.registry.remove(collector)
+
+	// Query plans run after the response; the request is saved once they are attached.
+	// Save errors on this path are dropped since the caller has moved on.
+	if collector.hasPendingExplains() {
+		ctx = context.WithoutCancel(ctx)
+		c.deferred.Add(1)
+		go func() {
+			defer c.deferred.Done()
+			collector.runExplains(ctx)
+			_ = c.saveCollector(ctx, collector, profiles)
+		}()
+		return nil
+	}
+	return c.saveCollector(ctx, collector, profiles)
+}
 
+func (c *Clockwork) saveCollector(ctx context.Context, collector *Collector, profiles map[string][]byte) error {
 	metadata := collector.GetMetadata()
 	if metadata == nil {
 		return nil
 	}
 
-	if metadata.TraceID != "" {
-		c.unregisterTrace(metadata.TraceID)
+	if err := c.SaveMetadata(ctx, metadata); err != nil {
+		return err
 	}
+	return c.storeProfiles(ctx, metadata.ID, profiles)
+}
 
-	return c.SaveMetadata(ctx, metadata)
+// RegisterCollector makes an in-flight request's collector available for log correlation
+// under its ID and the trace and span IDs set on it. Middleware calls it after SetTrace;
+// CompleteRequest removes it again.
+func (c *Clockwork) RegisterCollector(collector *Collector) {
+	if c == nil || collector == nil {
+		return
+	}
+	traceID, spanID := collector.Trace()
+	c.registry.add(collector, traceID, spanID)
 }
 
-// RegisterTrace associates a trace id with the active request collector.
+// RegisterTrace registers collector like RegisterCollector and also under traceID, which
+// may differ from the collector's own trace. Several collectors may share a trace ID.
 func (c *Clockwork) RegisterTrace(traceID string, collector *Collector) {
-	if c == nil || traceID == "" || collector == nil {
+	if c == nil || collector == nil {
 		return
 	}
-
-	_, loaded := c.activeByTrace.LoadOrStore(traceID, collector)
-	if !loaded {
-		c.activeCount.Add(1)
+	ownTrace, spanID := collector.Trace()
+	c.registry.add(collector, ownTrace, spanID)
+	if traceID != "" && traceID != ownTrace {
+		c.registry.add(collector, traceID, "")
 	}
 }
 
-func (c *Clockwork) unregisterTrace(traceID string) {
-	if c == nil || traceID == "" {
-		return
-	}
-	if _, loaded := c.activeByTrace.LoadAndDelete(traceID); loaded {
-		c.activeCount.Add(-1)
+// ActiveCollector returns the registered collector of an in-flight request by collector
+// ID (the X-Clockwork-Id value), or nil.
+func (c *Clockwork) ActiveCollector(id string) *Collector {
+	if c == nil || id == "" {
+		return nil
 	}
+	return c.registry.collector(id)
 }
 
 // HasActiveTraces reports whether any request currently has active Clockwork capture.
@@ -165,31 +286,44 @@ func (c *Clockwork) HasActiveTraces() bool {
 	if c == nil {
 		return false
 	}
-	return c.activeCount.Load() > 0
+	return c.registry.active.Load() > 0
 }
 
-// RecordLogForTrace appends a log entry for a traced active request.
+// CorrelationStats reports the registered requests and how logs passed to the
+// RecordLogFor* methods were attributed since startup.
+func (c *Clockwork) CorrelationStats() CorrelationStats {
+	if c == nil {
+		return CorrelationStats{}
+	}
+	return c.registry.stats()
+}
+
+// RecordLogForTrace appends a log entry to every active request of a trace.
 func (c *Clockwork) RecordLogForTrace(traceID, level, message string, fields map[string]interface{}) {
-	c.RecordLogForTraceWithTrace(traceID, level, message, fields, nil)
+	c.RecordLogForSpanWithTrace(traceID, "", level, message, fields, nil)
 }
 
-// RecordLogForTraceWithTrace appends a log entry for a traced active request with trace frames.
+// RecordLogForTraceWithTrace appends a log entry with trace frames to every active request of a trace.
 func (c *Clockwork) RecordLogForTraceWithTrace(traceID, level, message string, fields map[string]interface{}, trace []LogTraceFrame) {
-	if c == nil || traceID == "" {
-		return
-	}
-	collectorAny, ok := c.activeByTrace.Load(traceID)
-	if !ok {
-		return
-	}
-	collector, _ := collectorAny.(*Collector)
-	if collector == nil {
-		return
+	c.RecordLogForSpanWithTrace(traceID, "", level, message, fields, trace)
+}
+
+// RecordLogForSpan appends a log entry to the request registered for spanID, or when the
+// span is unknown (e.g. a child span), to every active request of traceID.
+// It reports whether any request received the entry.
+func (c *Clockwork) RecordLogForSpan(traceID, spanID, level, message string, fields map[string]interface{}) bool {
+	return c.RecordLogForSpanWithTrace(traceID, spanID, level, message, fields, nil)
+}
+
+// RecordLogForSpanWithTrace is RecordLogForSpan with trace frames.
+func (c *Clockwork) RecordLogForSpanWithTrace(traceID, spanID, level, message string, fields map[string]interface{}, trace []LogTraceFrame) bool {
+	if c == nil {
+		return false
 	}
-	collector.AddLogEntryWithTrace(level, message, fields, trace)
+	return c.registry.record(c.registry.lookup(traceID, spanID), level, message, fields, trace)
 }
 
-// RecordLogForSingleActive appends a log entry when exactly one traced request is active.
+// RecordLogForSingleActive appends a log entry when exactly one request is active.
 // This is a best-effort fallback for log lines that don't carry a trace_id field.
 func (c *Clockwork) RecordLogForSingleActive(level, message string, fields map[string]interface{}) bool {
 	return c.RecordLogForSingleActiveWithTrace(level, message, fields, nil)
@@ -197,21 +331,14 @@ func (c *Clockwork) RecordLogForSingleActive(level, message string, fields map[s
 
 // RecordLogForSingleActiveWithTrace appends a log entry with trace frames when exactly one request is active.
 func (c *Clockwork) RecordLogForSingleActiveWithTrace(level, message string, fields map[string]interface{}, trace []LogTraceFrame) bool {
-	if c == nil || c.activeCount.Load() != 1 {
+	if c == nil {
 		return false
 	}
-
-	var collector *Collector
-	c.activeByTrace.Range(func(_, value interface{}) bool {
-		collector, _ = value.(*Collector)
-		return false
-	})
-	if collector == nil {
-		return false
+	var collectors []*Collector
+	if collector := c.registry.single(); collector != nil {
+		collectors = []*Collector{collector}
 	}
-
-	collector.AddLogEntryWithTrace(level, message, fields, trace)
-	return true
+	return c.registry.record(collectors, level, message, fields, trace)
 }
 
 func (c *Clockwork) runCleanup(stop <-chan struct{}) {
@@ -3,7 +3,9 @@ package clockwork
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -11,9 +13,14 @@ import (
 	"github.com/google/uuid"
 )
 
+// MaxLogTraceFrames is the number of stack frames kept per log entry. Logging integrations
+// may stop collecting frames there.
+const MaxLogTraceFrames = 12
+
 const (
-	maxLogTraceFrames     = 12
 	maxLogTraceStackDepth = 32
+	// maxLogContextDepth bounds nested maps in log context; deeper maps are stringified.
+	maxLogContextDepth = 4
 )
 
 type collectorLimits struct {
@@ -21,8 +28,11 @@ type collectorLimits struct {
 	maxStringLen     int
 	maxDBQueries     int
 	maxCacheQueries  int
+	maxRedisCmds     int
+	maxHTTPRequests  int
 	maxLogs          int
 	maxTimelineEvent int
+	nPlusOne         int
 }
 
 func limitsFromConfig(cfg Config) collectorLimits {
@@ -31,8 +41,11 @@ func limitsFromConfig(cfg Config) collectorLimits {
 		maxStringLen:     cfg.MaxStringLength,
 		maxDBQueries:     cfg.MaxDatabaseQueries,
 		maxCacheQueries:  cfg.MaxCacheQueries,
+		maxRedisCmds:     cfg.MaxRedisCommands,
+		maxHTTPRequests:  cfg.MaxHTTPRequests,
 		maxLogs:          cfg.MaxLogEntries,
 		maxTimelineEvent: cfg.MaxTimelineEvents,
+		nPlusOne:         cfg.NPlusOneThreshold,
 	}
 }
 
@@ -50,9 +63,20 @@ type DataCollector interface {
 	SetURL(url string)
 	SetController(controller string)
 	SetTrace(traceID, spanID string)
+	SetGetData(data map[string]interface{})
+	SetPostData(data map[string]interface{})
+	SetCookies(cookies map[string]string)
+	SetSessionData(data map[string]interface{})
+	SetAuthenticatedUser(user AuthenticatedUser)
 	SetResponseData(status int, duration time.Duration)
 	AddDatabaseQuery(query string, duration time.Duration, connection string, slow bool)
+	AddDatabaseQueryDetailed(query string, duration time.Duration, connection string, slow bool, model, file string, line int, details ...QueryDetails)
+	AddModelAction(action ModelAction)
 	AddCacheQuery(cacheType, key string, duration time.Duration)
+	AddCacheQueryDetailed(cacheType, key string, duration time.Duration, details ...CacheDetails)
+	AddRedisCommand(command RedisCommand)
+	AddHTTPRequest(request HTTPRequest)
+	AddSubrequest(url, id, path string)
 	AddLogEntry(level, message string, fields map[string]interface{})
 	AddLogEntryWithTrace(level, message string, fields map[string]interface{}, trace []LogTraceFrame)
 	AddTimelineEvent(name, description string, start, end time.Time, color string)
@@ -69,8 +93,16 @@ type Collector struct {
 	url              string
 	controller       string
 	headers          map[string]string
+	getData          map[string]interface{}
+	postData         map[string]interface{}
+	requestData      interface{}
+	responseData     interface{}
+	cookies          map[string]string
+	sessionData      map[string]interface{}
+	user             *AuthenticatedUser
 	traceID          string
 	spanID           string
+	parent           string
 	responseStatus   int
 	responseTime     time.Time
 	responseDuration time.Duration
@@ -78,16 +110,37 @@ type Collector struct {
 	memoryUsageEnd   uint64
 
 	databaseQueries []DatabaseQuery
+	modelActions    []ModelAction
+	modelCounts     map[string]map[string]int // action -> model -> count
 	cacheQueries    []CacheQuery
+	cacheStats      cacheStats
+	redisCommands   []RedisCommand
+	httpRequests    []HTTPRequest
+	subrequests     []Subrequest
 	logEntries      []LogEntry
 	timelineEvents  []TimelineEvent
 	userData        map[string]interface{}
 	dropped         map[string]int
+	eventSeq        int
 	truncated       bool
 
 	limits    collectorLimits
 	usedBytes int
 
+	// request is the captured request, passed to the SessionResolver on completion.
+	request *http.Request
+
+	// profile is the pprof session started for the request; profiles lists the kinds it produced.
+	profile  *profileSession
+	profiles []string
+
+	// explains are query plans fetched after the handler returns, before metadata is saved.
+	explains []pendingExplain
+
+	// tailSampled marks a request collected speculatively; it is stored only if it
+	// qualifies for tail sampling once the response is known.
+	tailSampled bool
+
 	mu sync.RWMutex
 }
 
@@ -125,6 +178,35 @@ func (c *Collector) ID() string {
 	return c.id
 }
 
+// TailSampled reports whether the request is collected speculatively and is stored only
+// if it turns out slow or failed.
+func (c *Collector) TailSampled() bool {
+	if c == nil {
+		return false
+	}
+	return c.tailSampled
+}
+
+// TraceID returns the trace identifier set on the collector.
+func (c *Collector) TraceID() string {
+	if c == nil {
+		return ""
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.traceID
+}
+
+// Trace returns the trace and span identifiers set on the collector.
+func (c *Collector) Trace() (traceID, spanID string) {
+	if c == nil {
+		return "", ""
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.traceID, c.spanID
+}
+
 // SetResponseData sets response metadata.
 func (c *Collector) SetResponseData(status int, duration time.Duration) {
 	if c == nil {
@@ -177,6 +259,16 @@ func (c *Collector) SetController(controller string) {
 	c.controller = c.truncate(controller)
 }
 
+// SetParent sets the Clockwork ID of the upstream request that called this one.
+func (c *Collector) SetParent(id string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.parent = c.truncate(id)
+}
+
 // SetTrace sets trace and span identifiers.
 func (c *Collector) SetTrace(traceID, spanID string) {
 	if c == nil {
@@ -188,6 +280,61 @@ func (c *Collector) SetTrace(traceID, spanID string) {
 	c.spanID = c.truncate(spanID)
 }
 
+// SetGetData sets the query string parameters.
+func (c *Collector) SetGetData(data map[string]interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.getData = data
+}
+
+// SetPostData sets decoded form fields, replacing any recorded from the request body.
+func (c *Collector) SetPostData(data map[string]interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.postData = data
+}
+
+// SetCookies sets the request cookies.
+func (c *Collector) SetCookies(cookies map[string]string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cookies = cookies
+}
+
+// SetSessionData merges data into the recorded session data.
+func (c *Collector) SetSessionData(data map[string]interface{}) {
+	if c == nil || len(data) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sessionData == nil {
+		c.sessionData = make(map[string]interface{}, len(data))
+	}
+	for k, v := range data {
+		c.sessionData[k] = v
+	}
+}
+
+// SetAuthenticatedUser records the user the request was made by.
+func (c *Collector) SetAuthenticatedUser(user AuthenticatedUser) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.user = &user
+}
+
 // AddDatabaseQuery adds a database query event.
 // Model is auto-extracted from the SQL when not provided via AddDatabaseQueryDetailed.
 func (c *Collector) AddDatabaseQuery(query string, duration time.Duration, connection string, slow bool) {
@@ -197,60 +344,127 @@ func (c *Collector) AddDatabaseQuery(query string, duration time.Duration, conne
 // AddDatabaseQueryDetailed adds a database query with explicit model, file, and line info.
 // If model is empty, the table name is extracted from the SQL query.
 // If file is empty, it is captured from the caller's stack.
-func (c *Collector) AddDatabaseQueryDetailed(query string, duration time.Duration, connection string, slow bool, model, file string, line int) {
+// An optional QueryDetails records bindings, affected rows and the statement's error.
+func (c *Collector) AddDatabaseQueryDetailed(query string, duration time.Duration, connection string, slow bool, model, file string, line int, details ...QueryDetails) {
 	if c == nil {
 		return
 	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if !c.reserveLocked("database", c.limits.maxDBQueries, len(c.databaseQueries), len(query)+64) {
+	estimate := len(query) + 64
+	for _, d := range details {
+		if len(d.Bindings) > 0 {
+			estimate += bindingsEstimate(d.Bindings)
+			if d.Dialect != "" {
+				estimate += len(query)
+			}
+		}
+	}
+	if !c.reserveLocked("database", c.limits.maxDBQueries, len(c.databaseQueries), estimate) {
 		return
 	}
 
+	var dialect string
+	for _, d := range details {
+		dialect = d.Dialect
+	}
+	info := parseSQL(query, dialect)
 	if model == "" {
-		model = extractTableName(query)
+		model = primaryTableName(info.Tables)
 	}
 	if file == "" {
 		file, line = callerOutsidePackage(4)
 	}
 
-	durationMS := durationMs(duration)
+	durationMS := DurationMs(duration)
 	dq := DatabaseQuery{
 		Query:      c.truncate(query),
 		Duration:   durationMS,
 		Connection: c.truncate(connection),
 		Model:      c.truncate(model),
+		Operation:  info.Operation,
+		Tables:     info.Tables,
 		File:       c.truncate(file),
 		Line:       line,
 		Slow:       slow,
 		Timestamp:  unixTimestamp(),
+		dialect:    dialect,
+	}
+	color := colorForSlow(slow)
+	for _, d := range details {
+		dq.RowsAffected = d.RowsAffected
+		if d.Err != nil {
+			dq.Error = c.truncate(d.Err.Error())
+			color = "red"
+		}
+		if len(d.Bindings) > 0 {
+			dq.Bindings = make([]interface{}, len(d.Bindings))
+			for i, v := range d.Bindings {
+				dq.Bindings[i] = c.normalizeBinding(bindingValue(v))
+			}
+			if dialect != "" {
+				dq.Runnable = c.truncate(InterpolateQuery(query, d.Bindings, dialect))
+			}
+		}
 	}
 	c.databaseQueries = append(c.databaseQueries, dq)
-	c.appendTimelineLocked("db", dq.Query, dq.Timestamp-durationMS, dq.Timestamp, colorForSlow(slow))
+	for _, d := range details {
+		c.addExplainLocked(d.Explain)
+	}
+	c.appendTimelineLocked("db", dq.Query, dq.Timestamp-durationMS/1000, dq.Timestamp, color)
 }
 
 // AddCacheQuery adds a cache operation event.
 func (c *Collector) AddCacheQuery(cacheType, key string, duration time.Duration) {
+	c.AddCacheQueryDetailed(cacheType, key, duration)
+}
+
+// AddHTTPRequest adds an outgoing HTTP call. Duration is in milliseconds; a zero Timestamp
+// (unix seconds when the call started) is taken as now minus Duration.
+func (c *Collector) AddHTTPRequest(request HTTPRequest) {
 	if c == nil {
 		return
 	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if !c.reserveLocked("cache", c.limits.maxCacheQueries, len(c.cacheQueries), len(key)+48) {
+	estimate := len(request.Request.URL) + 16*(len(request.Request.Headers)+len(request.Response.Headers)) + 96
+	if !c.reserveLocked("http", c.limits.maxHTTPRequests, len(c.httpRequests), estimate) {
 		return
 	}
 
-	durationMS := durationMs(duration)
-	cq := CacheQuery{
-		Type:      c.truncate(cacheType),
-		Key:       c.truncate(key),
-		Duration:  durationMS,
-		Timestamp: unixTimestamp(),
+	request.Request.Method = c.truncate(request.Request.Method)
+	request.Request.URL = c.truncate(request.Request.URL)
+	request.Error = c.truncate(request.Error)
+	if request.Timestamp == 0 {
+		request.Timestamp = unixTimestamp() - request.Duration/1000
+	}
+	c.httpRequests = append(c.httpRequests, request)
+
+	color := "green"
+	if request.Error != "" || request.Response.Status >= 400 {
+		color = "red"
+	}
+	c.appendTimelineLocked("http", request.Request.Method+" "+request.Request.URL, request.Timestamp, request.Timestamp+request.Duration/1000, color)
+}
+
+// AddSubrequest links a downstream capture: url is the downstream request URL, id its
+// X-Clockwork-Id response header and path its metadata route prefix (DefaultPath when empty).
+func (c *Collector) AddSubrequest(url, id, path string) {
+	if c == nil || id == "" {
+		return
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.reserveLocked("subrequests", c.limits.maxHTTPRequests, len(c.subrequests), len(url)+len(id)+len(path)+32) {
+		return
 	}
-	c.cacheQueries = append(c.cacheQueries, cq)
-	c.appendTimelineLocked("cache", cq.Type+": "+cq.Key, cq.Timestamp-durationMS, cq.Timestamp, "purple")
+	c.subrequests = append(c.subrequests, Subrequest{URL: c.truncate(url), ID: c.truncate(id), Path: c.truncate(path)})
 }
 
 // AddLogEntry adds a log message.
@@ -258,7 +472,8 @@ func (c *Collector) AddLogEntry(level, message string, fields map[string]interfa
 	c.AddLogEntryWithTrace(level, message, fields, nil)
 }
 
-// AddLogEntryWithTrace adds a log message with stack trace frames.
+// AddLogEntryWithTrace adds a log message with stack trace frames. Frames in vendored or
+// module cache paths are marked IsVendor.
 func (c *Collector) AddLogEntryWithTrace(level, message string, fields map[string]interface{}, trace []LogTraceFrame) {
 	if c == nil {
 		return
@@ -279,7 +494,7 @@ func (c *Collector) AddLogEntryWithTrace(level, message string, fields map[strin
 	entry := LogEntry{
 		Level:     c.truncate(level),
 		Message:   c.truncate(message),
-		Context:   c.sanitizeContext(fields),
+		Context:   c.sanitizeContext(fields, 0),
 		Timestamp: unixTimestamp(),
 		Trace:     sanitizedTrace,
 	}
@@ -341,24 +556,55 @@ func (c *Collector) GetMetadata() *Metadata {
 		Time:                 unixFromTime(c.startTime),
 		ResponseTime:         unixFromTime(c.responseTime),
 		ResponseStatus:       c.responseStatus,
-		ResponseDuration:     durationMs(c.responseDuration),
+		ResponseDuration:     DurationMs(c.responseDuration),
 		Method:               c.method,
 		URI:                  c.uri,
 		URL:                  c.url,
 		Controller:           c.controller,
 		Headers:              c.headers,
+		GetData:              c.getData,
+		PostData:             c.postData,
+		RequestData:          c.requestData,
+		ResponseData:         c.responseData,
+		Cookies:              c.cookies,
+		AuthenticatedUser:    c.user,
 		TraceID:              c.traceID,
 		SpanID:               c.spanID,
+		Parent:               c.parent,
+		Profiles:             append([]string(nil), c.profiles...),
 		DatabaseQueries:      copyDB(c.databaseQueries),
 		DatabaseQueriesCount: len(c.databaseQueries),
 		DatabaseDuration:     totalDBDuration,
 		CacheQueries:         copyCache(c.cacheQueries),
+		CacheReads:           c.cacheStats.reads,
+		CacheHits:            c.cacheStats.hits,
+		CacheWrites:          c.cacheStats.writes,
+		CacheDeletes:         c.cacheStats.deletes,
+		CacheTime:            c.cacheStats.time,
+		RedisCommands:        copyRedis(c.redisCommands),
+		HTTPRequests:         copyHTTP(c.httpRequests),
+		Subrequests:          copySubrequests(c.subrequests),
 		LogEntries:           copyLogs(c.logEntries),
 		TimelineEvents:       copyTimeline(c.timelineEvents),
 		MemoryUsage:          memoryDelta,
 		Truncated:            c.truncated,
 	}
 
+	if len(c.modelActions) > 0 {
+		meta.ModelsActions = append([]ModelAction(nil), c.modelActions...)
+	}
+	meta.ModelsRetrieved = copyCounts(c.modelCounts[ModelRetrieved])
+	meta.ModelsCreated = copyCounts(c.modelCounts[ModelCreated])
+	meta.ModelsUpdated = copyCounts(c.modelCounts[ModelUpdated])
+	meta.ModelsDeleted = copyCounts(c.modelCounts[ModelDeleted])
+
+	if len(c.sessionData) > 0 {
+		meta.SessionData = make(map[string]interface{}, len(c.sessionData))
+		for k, v := range c.sessionData {
+			meta.SessionData[k] = v
+		}
+	}
+
 	if len(c.userData) > 0 {
 		meta.UserData = make(map[string]interface{}, len(c.userData))
 		for k, v := range c.userData {
@@ -373,9 +619,46 @@ func (c *Collector) GetMetadata() *Metadata {
 		}
 	}
 
+	if warnings := analyzeQueries(c.databaseQueries, c.limits.nPlusOne); len(warnings) > 0 {
+		meta.QueryWarnings = warnings
+		c.appendWarningLogsLocked(meta, warnings)
+	}
+
 	return meta
 }
 
+// appendWarningLogsLocked writes query warnings to meta's log entries under the same log
+// and payload limits as AddLogEntry. GetMetadata may run more than once, so the limits
+// are applied to meta without reserving anything on the collector.
+func (c *Collector) appendWarningLogsLocked(meta *Metadata, warnings []QueryWarning) {
+	timestamp := meta.ResponseTime
+	if timestamp == 0 {
+		timestamp = unixTimestamp()
+	}
+	used := c.usedBytes
+	for _, w := range warnings {
+		entry := queryWarningLogEntry(w, timestamp)
+		estimate := len(entry.Message) + 96 + len(entry.Trace)*64
+		bucket := ""
+		switch {
+		case c.limits.maxLogs > 0 && len(meta.LogEntries) >= c.limits.maxLogs:
+			bucket = "logs"
+		case c.limits.maxRequestBytes > 0 && used+estimate > c.limits.maxRequestBytes:
+			bucket = "payload"
+		}
+		if bucket != "" {
+			if meta.Dropped == nil {
+				meta.Dropped = make(map[string]int)
+			}
+			meta.Dropped[bucket]++
+			meta.Truncated = true
+			continue
+		}
+		used += estimate
+		meta.LogEntries = append(meta.LogEntries, entry)
+	}
+}
+
 func (c *Collector) reserveLocked(bucket string, max, current, estimate int) bool {
 	if max > 0 && current >= max {
 		c.dropped[bucket]++
@@ -398,29 +681,33 @@ func (c *Collector) reserveLocked(bucket string, max, current, estimate int) boo
 	return true
 }
 
-func (c *Collector) appendTimelineLocked(name, description string, startMs, endMs float64, color string) {
+// appendTimelineLocked adds an event spanning start to end (unix seconds); Duration is in milliseconds.
+func (c *Collector) appendTimelineLocked(name, description string, start, end float64, color string) {
+	c.appendEventLocked(TimelineEvent{Name: name, Description: description, Start: start, End: end, Color: color})
+}
+
+func (c *Collector) appendEventLocked(event TimelineEvent) {
 	if c.limits.maxTimelineEvent > 0 && len(c.timelineEvents) >= c.limits.maxTimelineEvent {
 		c.dropped["timeline"]++
 		c.truncated = true
 		return
 	}
 
-	event := TimelineEvent{
-		Name:        c.truncate(name),
-		Description: c.truncate(description),
-		Start:       startMs,
-		Color:       color,
-	}
-
-	if endMs > startMs {
-		event.End = endMs
-		event.Duration = endMs - startMs
+	event.Name = c.truncate(event.Name)
+	event.Description = c.truncate(event.Description)
+	if event.End > event.Start {
+		event.Duration = (event.End - event.Start) * 1000
+	} else {
+		event.End = 0
+		event.Duration = 0
 	}
 
 	c.timelineEvents = append(c.timelineEvents, event)
 }
 
-func (c *Collector) sanitizeContext(fields map[string]interface{}) map[string]interface{} {
+// sanitizeContext keeps at most 20 fields per map. Nested maps, such as slog groups, are
+// kept down to maxLogContextDepth.
+func (c *Collector) sanitizeContext(fields map[string]interface{}, depth int) map[string]interface{} {
 	if len(fields) == 0 {
 		return nil
 	}
@@ -439,6 +726,12 @@ func (c *Collector) sanitizeContext(fields map[string]interface{}) map[string]in
 			out[key] = c.truncate(tv)
 		case int, int32, int64, uint, uint32, uint64, float32, float64, bool:
 			out[key] = tv
+		case map[string]interface{}:
+			if depth+1 < maxLogContextDepth {
+				out[key] = c.sanitizeContext(tv, depth+1)
+			} else {
+				out[key] = c.truncate(toCompactString(tv))
+			}
 		default:
 			out[key] = c.truncate(toCompactString(tv))
 		}
@@ -452,17 +745,17 @@ func (c *Collector) sanitizeTrace(trace []LogTraceFrame) []LogTraceFrame {
 		return nil
 	}
 
-	maxFrames := maxLogTraceFrames
+	maxFrames := MaxLogTraceFrames
 	if len(trace) < maxFrames {
 		maxFrames = len(trace)
 	}
 	out := make([]LogTraceFrame, 0, maxFrames)
-	for i := 0; i < len(trace) && i < maxLogTraceFrames; i++ {
+	for i := 0; i < len(trace) && i < MaxLogTraceFrames; i++ {
 		out = append(out, LogTraceFrame{
 			Call:     c.truncate(trace[i].Call),
 			File:     c.truncate(trace[i].File),
 			Line:     trace[i].Line,
-			IsVendor: trace[i].IsVendor,
+			IsVendor: trace[i].IsVendor || isVendorPath(trace[i].File),
 		})
 	}
 	return out
@@ -475,8 +768,8 @@ func (c *Collector) captureCurrentStackTrace(skip int) []LogTraceFrame {
 		return nil
 	}
 	frames := runtime.CallersFrames(pcs[:n])
-	out := make([]LogTraceFrame, 0, maxLogTraceFrames)
-	for len(out) < maxLogTraceFrames {
+	out := make([]LogTraceFrame, 0, MaxLogTraceFrames)
+	for len(out) < MaxLogTraceFrames {
 		frame, more := frames.Next()
 		if frame.File == "" {
 			if !more {
@@ -506,6 +799,15 @@ func (c *Collector) truncate(v string) string {
 	return v[:c.limits.maxStringLen]
 }
 
+func copyRedis(in []RedisCommand) []RedisCommand {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]RedisCommand, len(in))
+	copy(out, in)
+	return out
+}
+
 func copyDB(in []DatabaseQuery) []DatabaseQuery {
 	out := make([]DatabaseQuery, len(in))
 	copy(out, in)
@@ -518,6 +820,24 @@ func copyCache(in []CacheQuery) []CacheQuery {
 	return out
 }
 
+func copyHTTP(in []HTTPRequest) []HTTPRequest {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]HTTPRequest, len(in))
+	copy(out, in)
+	return out
+}
+
+func copySubrequests(in []Subrequest) []Subrequest {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]Subrequest, len(in))
+	copy(out, in)
+	return out
+}
+
 func copyLogs(in []LogEntry) []LogEntry {
 	out := make([]LogEntry, len(in))
 	copy(out, in)
@@ -537,7 +857,8 @@ func colorForSlow(slow bool) string {
 	return "blue"
 }
 
-func durationMs(d time.Duration) float64 {
+// DurationMs converts d to the fractional milliseconds Clockwork records durations in.
+func DurationMs(d time.Duration) float64 {
 	return float64(d.Microseconds()) / 1000.0
 }
 
@@ -561,46 +882,25 @@ func isVendorPath(path string) bool {
 	return strings.Contains(p, "/vendor/") || strings.Contains(p, "/pkg/mod/")
 }
 
-// extractTableName parses a SQL query and returns the primary table name.
-func extractTableName(query string) string {
-	q := strings.TrimSpace(query)
-	if q == "" {
-		return ""
-	}
-	tokens := strings.Fields(q)
-	upper := make([]string, len(tokens))
-	for i, t := range tokens {
-		upper[i] = strings.ToUpper(t)
-	}
-
-	for i, tok := range upper {
-		switch tok {
-		case "FROM", "INTO", "UPDATE", "TABLE":
-			if i+1 < len(tokens) {
-				return cleanTableName(tokens[i+1])
-			}
-		case "JOIN":
-			if i+1 < len(tokens) {
-				return cleanTableName(tokens[i+1])
-			}
-		}
+// ParseFileLine splits a "file:line" location, as found in caller fields and formatted
+// stack traces, into its file and line.
+func ParseFileLine(in string) (string, int, bool) {
+	in = strings.TrimSpace(in)
+	idx := strings.LastIndex(in, ":")
+	if idx <= 0 || idx == len(in)-1 {
+		return "", 0, false
 	}
-	return ""
-}
-
-func cleanTableName(raw string) string {
-	name := strings.Trim(raw, "`\"'[]")
-	name = strings.TrimRight(name, ",;()")
-	if dot := strings.LastIndex(name, "."); dot >= 0 && dot < len(name)-1 {
-		name = name[dot+1:]
+	line, err := strconv.Atoi(in[idx+1:])
+	if err != nil {
+		return "", 0, false
 	}
-	return name
+	return in[:idx], line, true
 }
 
 // callerOutsidePackage walks the call stack starting at skip and returns the
 // first frame whose file path does not contain "go-clockwork".
-func callerOutsidePackage(skip int) (string, int) {
-	pcs := make([]uintptr, 16)
+func callerOutsidePackage(skip int, skipFunctions ...string) (string, int) {
+	pcs := make([]uintptr, 32)
 	n := runtime.Callers(skip, pcs)
 	if n == 0 {
 		return "", 0
@@ -608,7 +908,7 @@ func callerOutsidePackage(skip int) (string, int) {
 	frames := runtime.CallersFrames(pcs[:n])
 	for {
 		frame, more := frames.Next()
-		if frame.File == "" {
+		if frame.File == "" || hasAnyPrefix(frame.Function, skipFunctions) {
 			if !more {
 				break
 			}
@@ -624,6 +924,22 @@ func callerOutsidePackage(skip int) (string, int) {
 	return "", 0
 }
 
+// CallerOutside returns the file and line of the first caller outside go-clockwork whose
+// function name does not start with one of skipFunctions (e.g. "database/sql.").
+// Integrations use it to attribute queries to application code rather than driver internals.
+func CallerOutside(skipFunctions ...string) (string, int) {
+	return callerOutsidePackage(3, skipFunctions...)
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // contextKey is an unexported key type to prevent collisions.
 type contextKey string
 
@@ -1,6 +1,7 @@
 package clockwork
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -27,6 +28,51 @@ func TestCollector_RespectsLimitsAndMarksTruncated(t *testing.T) {
 	require.GreaterOrEqual(t, meta.Dropped["strings"], 1)
 }
 
+func TestCollector_KeepsNestedLogContext(t *testing.T) {
+	collector := NewCollector("GET", "/", collectorLimits{})
+	collector.AddLogEntry("info", "nested", map[string]interface{}{
+		"request": map[string]interface{}{"method": "GET", "status": 200},
+		"a":       map[string]interface{}{"b": map[string]interface{}{"c": map[string]interface{}{"d": map[string]interface{}{"e": 1}}}},
+	})
+
+	context := collector.GetMetadata().LogEntries[0].Context
+	require.Equal(t, map[string]interface{}{"method": "GET", "status": 200}, context["request"])
+	deep := context["a"].(map[string]interface{})["b"].(map[string]interface{})["c"].(map[string]interface{})["d"]
+	require.Equal(t, "map[e:1]", deep, "maps below the depth limit are stringified")
+}
+
+func TestCollector_MarksVendorLogFrames(t *testing.T) {
+	collector := NewCollector("GET", "/", collectorLimits{})
+	collector.AddLogEntryWithTrace("info", "traced", nil, []LogTraceFrame{
+		{File: "/app/handler.go", Line: 10},
+		{File: "/root/go/pkg/mod/github.com/lib/pq@v1.10.9/conn.go", Line: 20},
+		{File: "/app/vendor/github.com/lib/pq/conn.go", Line: 30},
+	})
+
+	trace := collector.GetMetadata().LogEntries[0].Trace
+	require.Len(t, trace, 3)
+	require.False(t, trace[0].IsVendor)
+	require.True(t, trace[1].IsVendor)
+	require.True(t, trace[2].IsVendor)
+}
+
+func TestParseFileLine(t *testing.T) {
+	file, line, ok := ParseFileLine("\t/app/handler.go:42 ")
+	require.True(t, ok)
+	require.Equal(t, "/app/handler.go", file)
+	require.Equal(t, 42, line)
+
+	file, line, ok = ParseFileLine(`C:\app\handler.go:7`)
+	require.True(t, ok)
+	require.Equal(t, `C:\app\handler.go`, file)
+	require.Equal(t, 7, line)
+
+	for _, in := range []string{"", "handler.go", ":12", "handler.go:", "handler.go:x"} {
+		_, _, ok := ParseFileLine(in)
+		require.False(t, ok, in)
+	}
+}
+
 func TestCollector_ComputesDatabaseDuration(t *testing.T) {
 	collector := NewCollector("GET", "/db", collectorLimits{})
 	require.NotNil(t, collector)
@@ -64,10 +110,14 @@ func TestExtractTableName(t *testing.T) {
 func TestCollector_AddDatabaseQueryExtractsModel(t *testing.T) {
 	collector := NewCollector("GET", "/test", collectorLimits{})
 	collector.AddDatabaseQuery("SELECT * FROM products WHERE id = 1", 5*time.Millisecond, "mysql", false)
+	collector.AddDatabaseQuery("WITH top AS (SELECT * FROM sales) SELECT * FROM shop.products JOIN top ON true", 5*time.Millisecond, "pg", false)
 
 	meta := collector.GetMetadata()
-	require.Len(t, meta.DatabaseQueries, 1)
+	require.Len(t, meta.DatabaseQueries, 2)
 	require.Equal(t, "products", meta.DatabaseQueries[0].Model)
+	require.Equal(t, "SELECT", meta.DatabaseQueries[0].Operation)
+	require.Equal(t, "products", meta.DatabaseQueries[1].Model)
+	require.Equal(t, []string{"shop.products", "sales"}, meta.DatabaseQueries[1].Tables)
 }
 
 func TestCollector_AddDatabaseQueryDetailedUsesExplicitModel(t *testing.T) {
@@ -83,3 +133,38 @@ func TestCollector_AddDatabaseQueryDetailedUsesExplicitModel(t *testing.T) {
 	require.Equal(t, "app/handlers/product.go", meta.DatabaseQueries[0].File)
 	require.Equal(t, 42, meta.DatabaseQueries[0].Line)
 }
+
+func TestCollector_AddHTTPRequest(t *testing.T) {
+	collector := NewCollector("GET", "/checkout", collectorLimits{maxHTTPRequests: 1})
+
+	collector.AddHTTPRequest(HTTPRequest{
+		Request:  HTTPRequestInfo{Method: "POST", URL: "https://payments.local/charge"},
+		Response: HTTPResponseInfo{Status: 502},
+		Duration: 12.5,
+	})
+	collector.AddHTTPRequest(HTTPRequest{Request: HTTPRequestInfo{Method: "GET", URL: "https://dropped.local"}})
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.HTTPRequests, 1)
+	require.Equal(t, 502, meta.HTTPRequests[0].Response.Status)
+	require.NotZero(t, meta.HTTPRequests[0].Timestamp)
+	require.Equal(t, 1, meta.Dropped["http"])
+	require.Len(t, meta.TimelineEvents, 1)
+	require.Equal(t, "POST https://payments.local/charge", meta.TimelineEvents[0].Description)
+	require.Equal(t, "red", meta.TimelineEvents[0].Color)
+}
+
+func TestCollector_AddDatabaseQueryDetailedRecordsResult(t *testing.T) {
+	collector := NewCollector("POST", "/orders", collectorLimits{})
+	collector.AddDatabaseQueryDetailed("UPDATE orders SET paid = true", time.Millisecond, "pg", false, "", "orders.go", 12,
+		QueryDetails{RowsAffected: 3})
+	collector.AddDatabaseQueryDetailed("INSERT INTO orders VALUES (1)", time.Millisecond, "pg", false, "", "orders.go", 20,
+		QueryDetails{Err: errors.New("duplicate key")})
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.DatabaseQueries, 2)
+	require.Equal(t, int64(3), meta.DatabaseQueries[0].RowsAffected)
+	require.Empty(t, meta.DatabaseQueries[0].Error)
+	require.Equal(t, "duplicate key", meta.DatabaseQueries[1].Error)
+	require.Equal(t, "red", meta.TimelineEvents[1].Color)
+}
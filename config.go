@@ -1,9 +1,20 @@
 package clockwork
 
 import (
+	"strings"
 	"time"
 )
 
+// Capture modes for Config.CaptureMode.
+const (
+	// CaptureModeHeader captures only requests carrying the Clockwork header.
+	CaptureModeHeader = "header"
+	// CaptureModeAlways captures every request.
+	CaptureModeAlways = "always"
+	// CaptureModeSample captures a random share of requests given by SampleRate or RouteSampleRates.
+	CaptureModeSample = "sample"
+)
+
 // Config holds Clockwork configuration.
 type Config struct {
 	Enabled    bool   `mapstructure:"enabled"`
@@ -16,6 +27,8 @@ type Config struct {
 
 	MaxDatabaseQueries int `mapstructure:"max_database_queries"`
 	MaxCacheQueries    int `mapstructure:"max_cache_queries"`
+	MaxRedisCommands   int `mapstructure:"max_redis_commands"`
+	MaxHTTPRequests    int `mapstructure:"max_http_requests"`
 	MaxLogEntries      int `mapstructure:"max_log_entries"`
 	MaxTimelineEvents  int `mapstructure:"max_timeline_events"`
 	MaxStringLength    int `mapstructure:"max_string_length"`
@@ -23,6 +36,44 @@ type Config struct {
 	SlowQueryThreshold   time.Duration `mapstructure:"slow_query_threshold"`
 	CleanupInterval      time.Duration `mapstructure:"cleanup_interval"`
 	RequestRetentionTime time.Duration `mapstructure:"request_retention_time"`
+
+	// NPlusOneThreshold is how many similar queries from one call site are reported as an N+1
+	// pattern; identical statements run more than once are reported as duplicates. A negative
+	// value turns query analysis off.
+	NPlusOneThreshold int `mapstructure:"n_plus_one_threshold"`
+
+	// CaptureMode selects which requests are captured; requests with the Clockwork
+	// header are always captured regardless of mode.
+	CaptureMode string `mapstructure:"capture_mode"`
+	// SampleRate is the share of requests (0..1) captured in sample mode.
+	SampleRate float64 `mapstructure:"sample_rate"`
+	// RouteSampleRates overrides SampleRate for paths matching a glob; the longest matching pattern wins.
+	RouteSampleRates map[string]float64 `mapstructure:"route_sample_rates"`
+
+	// TailSlowThreshold and TailServerErrors enable tail sampling: requests not selected
+	// otherwise are still collected and only stored when slower than the threshold or,
+	// with TailServerErrors, when they respond with a 5xx status.
+	TailSlowThreshold time.Duration `mapstructure:"tail_slow_threshold"`
+	TailServerErrors  bool          `mapstructure:"tail_server_errors"`
+
+	// CaptureRequestBody and CaptureResponseBody record bodies whose content type matches
+	// BodyContentTypes, bounded by MaxRequestPayloadBytes.
+	CaptureRequestBody  bool     `mapstructure:"capture_request_body"`
+	CaptureResponseBody bool     `mapstructure:"capture_response_body"`
+	BodyContentTypes    []string `mapstructure:"body_content_types"`
+
+	// ProfilingEnabled lets requests carrying ProfileHeader be captured with pprof profiles.
+	// The header value selects the kinds: "cpu" (default), "allocs" or "cpu,allocs".
+	// Profiles stop after ProfileMaxDuration even if the request is still running.
+	ProfilingEnabled   bool          `mapstructure:"profiling_enabled"`
+	ProfileHeader      string        `mapstructure:"profile_header"`
+	ProfileMaxDuration time.Duration `mapstructure:"profile_max_duration"`
+
+	// CapturePolicy configures the built-in capture policies evaluated before CaptureMode.
+	CapturePolicy CapturePolicyConfig `mapstructure:"capture_policy"`
+
+	// Redaction controls which headers are recorded and how sensitive values are masked before storage.
+	Redaction RedactionConfig `mapstructure:"redaction"`
 }
 
 // DefaultConfig returns baseline defaults for new deployments.
@@ -36,12 +87,26 @@ func DefaultConfig() Config {
 		MaxRequestPayloadBytes: 256 * 1024,
 		MaxDatabaseQueries:     100,
 		MaxCacheQueries:        200,
+		MaxRedisCommands:       200,
+		MaxHTTPRequests:        100,
 		MaxLogEntries:          150,
 		MaxTimelineEvents:      200,
 		MaxStringLength:        2048,
 		SlowQueryThreshold:     100 * time.Millisecond,
+		NPlusOneThreshold:      defaultNPlusOneThreshold,
 		CleanupInterval:        5 * time.Minute,
 		RequestRetentionTime:   time.Hour,
+		CaptureMode:            CaptureModeHeader,
+		ProfileHeader:          "X-Clockwork-Profile",
+		ProfileMaxDuration:     30 * time.Second,
+		CapturePolicy: CapturePolicyConfig{
+			ExcludePaths: []string{"*/favicon.ico"},
+		},
+		BodyContentTypes: []string{
+			"application/json", "application/*+json", "application/x-www-form-urlencoded",
+			"multipart/form-data", "application/xml", "text/*",
+		},
+		Redaction: DefaultRedactionConfig(),
 	}
 }
 
@@ -73,6 +138,12 @@ func (c *Config) Normalize() {
 	if c.MaxCacheQueries <= 0 {
 		c.MaxCacheQueries = d.MaxCacheQueries
 	}
+	if c.MaxRedisCommands <= 0 {
+		c.MaxRedisCommands = d.MaxRedisCommands
+	}
+	if c.MaxHTTPRequests <= 0 {
+		c.MaxHTTPRequests = d.MaxHTTPRequests
+	}
 	if c.MaxLogEntries <= 0 {
 		c.MaxLogEntries = d.MaxLogEntries
 	}
@@ -85,10 +156,52 @@ func (c *Config) Normalize() {
 	if c.SlowQueryThreshold <= 0 {
 		c.SlowQueryThreshold = d.SlowQueryThreshold
 	}
+	if c.NPlusOneThreshold == 0 {
+		c.NPlusOneThreshold = d.NPlusOneThreshold
+	}
 	if c.CleanupInterval <= 0 {
 		c.CleanupInterval = d.CleanupInterval
 	}
 	if c.RequestRetentionTime <= 0 {
 		c.RequestRetentionTime = d.RequestRetentionTime
 	}
+	c.CaptureMode = strings.ToLower(strings.TrimSpace(c.CaptureMode))
+	if c.CaptureMode != CaptureModeAlways && c.CaptureMode != CaptureModeSample {
+		c.CaptureMode = d.CaptureMode
+	}
+	c.SampleRate = clampRate(c.SampleRate)
+	if len(c.RouteSampleRates) > 0 {
+		rates := make(map[string]float64, len(c.RouteSampleRates))
+		for pattern, rate := range c.RouteSampleRates {
+			rates[pattern] = clampRate(rate)
+		}
+		c.RouteSampleRates = rates
+	}
+	if c.TailSlowThreshold < 0 {
+		c.TailSlowThreshold = 0
+	}
+	if c.CapturePolicy.ExcludePaths == nil {
+		c.CapturePolicy.ExcludePaths = d.CapturePolicy.ExcludePaths
+	}
+	if c.ProfileHeader == "" {
+		c.ProfileHeader = d.ProfileHeader
+	}
+	if c.ProfileMaxDuration <= 0 {
+		c.ProfileMaxDuration = d.ProfileMaxDuration
+	}
+	if c.BodyContentTypes == nil {
+		c.BodyContentTypes = d.BodyContentTypes
+	}
+	c.Redaction.normalize()
+}
+
+func clampRate(rate float64) float64 {
+	switch {
+	case rate < 0:
+		return 0
+	case rate > 1:
+		return 1
+	default:
+		return rate
+	}
 }
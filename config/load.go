@@ -75,6 +75,9 @@ func Load(opts LoadOptions) (clockwork.Config, error) {
 	applyEnvOverrides(&cfg, envPrefix)
 
 	cfg.Normalize()
+	if _, err := clockwork.NewRedactor(cfg.Redaction); err != nil {
+		return clockwork.Config{}, fmt.Errorf("invalid redaction config: %w", err)
+	}
 	return cfg, nil
 }
 
@@ -83,20 +86,34 @@ func bindClockworkEnv(v *viper.Viper, envPrefix string) {
 		return
 	}
 	keys := map[string]string{
-		"enabled":                   "ENABLED",
-		"header_name":               "HEADER_NAME",
-		"id_header_name":            "ID_HEADER_NAME",
-		"max_requests":              "MAX_REQUESTS",
-		"max_storage_bytes":         "MAX_STORAGE_BYTES",
-		"max_request_payload_bytes": "MAX_REQUEST_PAYLOAD_BYTES",
-		"max_database_queries":      "MAX_DATABASE_QUERIES",
-		"max_cache_queries":         "MAX_CACHE_QUERIES",
-		"max_log_entries":           "MAX_LOG_ENTRIES",
-		"max_timeline_events":       "MAX_TIMELINE_EVENTS",
-		"max_string_length":         "MAX_STRING_LENGTH",
-		"slow_query_threshold":      "SLOW_QUERY_THRESHOLD",
-		"cleanup_interval":          "CLEANUP_INTERVAL",
-		"request_retention_time":    "REQUEST_RETENTION_TIME",
+		"enabled":                       "ENABLED",
+		"header_name":                   "HEADER_NAME",
+		"id_header_name":                "ID_HEADER_NAME",
+		"max_requests":                  "MAX_REQUESTS",
+		"max_storage_bytes":             "MAX_STORAGE_BYTES",
+		"max_request_payload_bytes":     "MAX_REQUEST_PAYLOAD_BYTES",
+		"max_database_queries":          "MAX_DATABASE_QUERIES",
+		"max_cache_queries":             "MAX_CACHE_QUERIES",
+		"max_redis_commands":            "MAX_REDIS_COMMANDS",
+		"max_http_requests":             "MAX_HTTP_REQUESTS",
+		"max_log_entries":               "MAX_LOG_ENTRIES",
+		"max_timeline_events":           "MAX_TIMELINE_EVENTS",
+		"max_string_length":             "MAX_STRING_LENGTH",
+		"slow_query_threshold":          "SLOW_QUERY_THRESHOLD",
+		"n_plus_one_threshold":          "N_PLUS_ONE_THRESHOLD",
+		"cleanup_interval":              "CLEANUP_INTERVAL",
+		"request_retention_time":        "REQUEST_RETENTION_TIME",
+		"capture_mode":                  "CAPTURE_MODE",
+		"sample_rate":                   "SAMPLE_RATE",
+		"tail_slow_threshold":           "TAIL_SLOW_THRESHOLD",
+		"tail_server_errors":            "TAIL_SERVER_ERRORS",
+		"capture_request_body":          "CAPTURE_REQUEST_BODY",
+		"capture_response_body":         "CAPTURE_RESPONSE_BODY",
+		"profiling_enabled":             "PROFILING_ENABLED",
+		"profile_header":                "PROFILE_HEADER",
+		"profile_max_duration":          "PROFILE_MAX_DURATION",
+		"redaction.capture_all_headers": "REDACT_CAPTURE_ALL_HEADERS",
+		"redaction.mask":                "REDACT_MASK",
 	}
 
 	for key, suffix := range keys {
@@ -148,6 +165,16 @@ func applyEnvOverrides(cfg *clockwork.Config, envPrefix string) {
 			cfg.MaxCacheQueries = parsed
 		}
 	}
+	if value, ok := lookupEnv(key("MAX_REDIS_COMMANDS")); ok {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.MaxRedisCommands = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("MAX_HTTP_REQUESTS")); ok {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.MaxHTTPRequests = parsed
+		}
+	}
 	if value, ok := lookupEnv(key("MAX_LOG_ENTRIES")); ok {
 		if parsed, err := strconv.Atoi(value); err == nil {
 			cfg.MaxLogEntries = parsed
@@ -168,6 +195,11 @@ func applyEnvOverrides(cfg *clockwork.Config, envPrefix string) {
 			cfg.SlowQueryThreshold = parsed
 		}
 	}
+	if value, ok := lookupEnv(key("N_PLUS_ONE_THRESHOLD")); ok {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			cfg.NPlusOneThreshold = parsed
+		}
+	}
 	if value, ok := lookupEnv(key("CLEANUP_INTERVAL")); ok {
 		if parsed, err := time.ParseDuration(value); err == nil {
 			cfg.CleanupInterval = parsed
@@ -178,6 +210,129 @@ func applyEnvOverrides(cfg *clockwork.Config, envPrefix string) {
 			cfg.RequestRetentionTime = parsed
 		}
 	}
+	if value, ok := lookupEnv(key("CAPTURE_MODE")); ok {
+		cfg.CaptureMode = value
+	}
+	if value, ok := lookupEnv(key("SAMPLE_RATE")); ok {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			cfg.SampleRate = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("ROUTE_SAMPLE_RATES")); ok {
+		if parsed := parseRouteSampleRates(value); len(parsed) > 0 {
+			cfg.RouteSampleRates = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("TAIL_SLOW_THRESHOLD")); ok {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			cfg.TailSlowThreshold = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("TAIL_SERVER_ERRORS")); ok {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.TailServerErrors = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("CAPTURE_REQUEST_BODY")); ok {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.CaptureRequestBody = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("CAPTURE_RESPONSE_BODY")); ok {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.CaptureResponseBody = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("PROFILING_ENABLED")); ok {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.ProfilingEnabled = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("PROFILE_MAX_DURATION")); ok {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			cfg.ProfileMaxDuration = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("BODY_CONTENT_TYPES")); ok {
+		cfg.BodyContentTypes = parseList(value)
+	}
+	if value, ok := lookupEnv(key("CAPTURE_INCLUDE_PATHS")); ok {
+		cfg.CapturePolicy.IncludePaths = parseList(value)
+	}
+	if value, ok := lookupEnv(key("CAPTURE_EXCLUDE_PATHS")); ok {
+		cfg.CapturePolicy.ExcludePaths = parseList(value)
+	}
+	if value, ok := lookupEnv(key("CAPTURE_MATCH_HEADERS")); ok {
+		cfg.CapturePolicy.MatchHeaders = parsePairs(value)
+	}
+	if value, ok := lookupEnv(key("CAPTURE_MATCH_COOKIES")); ok {
+		cfg.CapturePolicy.MatchCookies = parsePairs(value)
+	}
+	if value, ok := lookupEnv(key("CAPTURE_ALLOW_IPS")); ok {
+		cfg.CapturePolicy.AllowIPs = parseList(value)
+	}
+	if value, ok := lookupEnv(key("CAPTURE_EXCLUDE_USER_AGENTS")); ok {
+		cfg.CapturePolicy.ExcludeUserAgents = parseList(value)
+	}
+	if value, ok := lookupEnv(key("REDACT_CAPTURE_ALL_HEADERS")); ok {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			cfg.Redaction.CaptureAllHeaders = parsed
+		}
+	}
+	if value, ok := lookupEnv(key("REDACT_ALLOW_HEADERS")); ok {
+		cfg.Redaction.AllowHeaders = parseList(value)
+	}
+	if value, ok := lookupEnv(key("REDACT_DENY_HEADERS")); ok {
+		cfg.Redaction.DenyHeaders = parseList(value)
+	}
+	if value, ok := lookupEnv(key("REDACT_MASK_HEADERS")); ok {
+		cfg.Redaction.MaskHeaders = parseList(value)
+	}
+	if value, ok := lookupEnv(key("REDACT_MASK_KEYS")); ok {
+		cfg.Redaction.MaskKeys = parseList(value)
+	}
+	if value, ok := lookupEnv(key("REDACT_MASK")); ok {
+		cfg.Redaction.Mask = value
+	}
+}
+
+// parseList splits a comma-separated value, dropping empty items.
+func parseList(value string) []string {
+	out := make([]string, 0, 4)
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
+
+// parsePairs parses "name=value" pairs separated by commas; a bare name maps to "".
+func parsePairs(value string) map[string]string {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, v, _ := strings.Cut(pair, "=")
+		if name = strings.TrimSpace(name); name != "" {
+			out[name] = strings.TrimSpace(v)
+		}
+	}
+	return out
+}
+
+// parseRouteSampleRates parses "pattern=rate" pairs separated by commas, e.g. "/api/*=0.1,/health=0".
+func parseRouteSampleRates(value string) map[string]float64 {
+	out := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		pattern, rawRate, ok := strings.Cut(pair, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			continue
+		}
+		if rate, err := strconv.ParseFloat(strings.TrimSpace(rawRate), 64); err == nil {
+			out[pattern] = rate
+		}
+	}
+	return out
 }
 
 func lookupEnv(key string) (string, bool) {
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -53,3 +54,91 @@ max_requests: 50
 	require.Equal(t, "X-Clockwork", cfg.HeaderName)
 	require.Equal(t, 50, cfg.MaxRequests)
 }
+
+func TestLoad_CapturePolicy(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "clockwork.yml")
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`clockwork:
+  enabled: true
+  capture_mode: sample
+  sample_rate: 0.05
+  route_sample_rates:
+    /checkout/*: 0.5
+  tail_server_errors: true
+`), 0o600))
+	t.Setenv("CLOCKWORK_C_TAIL_SLOW_THRESHOLD", "750ms")
+	t.Setenv("CLOCKWORK_C_ROUTE_SAMPLE_RATES", "/health=0,/api/*=0.25")
+
+	cfg, err := Load(LoadOptions{
+		ConfigPath: dir,
+		ConfigName: "clockwork",
+		ConfigType: "yml",
+		EnvPrefix:  "CLOCKWORK_C",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "sample", cfg.CaptureMode)
+	require.InDelta(t, 0.05, cfg.SampleRate, 1e-9)
+	require.True(t, cfg.TailServerErrors)
+	require.Equal(t, 750*time.Millisecond, cfg.TailSlowThreshold)
+	require.Equal(t, map[string]float64{"/health": 0, "/api/*": 0.25}, cfg.RouteSampleRates)
+}
+
+func TestLoad_CapturePolicyRules(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "clockwork.yml")
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`clockwork:
+  capture_policy:
+    exclude_paths: ["/health*", "*/favicon.ico"]
+    match_cookies:
+      staff: "1"
+    allow_ips: ["10.0.0.0/8"]
+`), 0o600))
+	t.Setenv("CLOCKWORK_D_CAPTURE_MATCH_HEADERS", "X-Tenant=acme-*,X-Debug")
+
+	cfg, err := Load(LoadOptions{
+		ConfigPath: dir,
+		ConfigName: "clockwork",
+		ConfigType: "yml",
+		EnvPrefix:  "CLOCKWORK_D",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"/health*", "*/favicon.ico"}, cfg.CapturePolicy.ExcludePaths)
+	require.Equal(t, map[string]string{"staff": "1"}, cfg.CapturePolicy.MatchCookies)
+	require.Equal(t, []string{"10.0.0.0/8"}, cfg.CapturePolicy.AllowIPs)
+	require.Equal(t, map[string]string{"X-Tenant": "acme-*", "X-Debug": ""}, cfg.CapturePolicy.MatchHeaders)
+}
+
+func TestLoad_Redaction(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "clockwork.yml")
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`clockwork:
+  redaction:
+    capture_all_headers: true
+    deny_headers: ["X-Internal-*"]
+    patterns: ["email", "acct-[0-9]+"]
+`), 0o600))
+	t.Setenv("CLOCKWORK_E_REDACT_MASK", "***")
+
+	cfg, err := Load(LoadOptions{
+		ConfigPath: dir,
+		ConfigName: "clockwork",
+		ConfigType: "yml",
+		EnvPrefix:  "CLOCKWORK_E",
+	})
+	require.NoError(t, err)
+	require.True(t, cfg.Redaction.CaptureAllHeaders)
+	require.Equal(t, []string{"X-Internal-*"}, cfg.Redaction.DenyHeaders)
+	require.Equal(t, []string{"email", "acct-[0-9]+"}, cfg.Redaction.Patterns)
+	require.Equal(t, "***", cfg.Redaction.Mask)
+	require.NotEmpty(t, cfg.Redaction.MaskHeaders)
+
+	require.NoError(t, os.WriteFile(configPath, []byte(`clockwork:
+  redaction:
+    patterns: ["acct-[0-9"]
+`), 0o600))
+	_, err = Load(LoadOptions{ConfigPath: dir, ConfigName: "clockwork", ConfigType: "yml", EnvPrefix: "CLOCKWORK_E"})
+	require.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package clockwork
+
+import (
+	"context"
+)
+
+// maxQueryExplains bounds the query plans fetched per request.
+const maxQueryExplains = 10
+
+// ExplainFunc fetches the execution plan of a recorded query. It runs after the handler
+// has returned, with a context that carries no collector so its own queries are not recorded.
+type ExplainFunc func(ctx context.Context) (string, error)
+
+// pendingExplain is a plan to fetch for databaseQueries[index].
+type pendingExplain struct {
+	index int
+	fn    ExplainFunc
+}
+
+// addExplainLocked queues fn for the query just appended to databaseQueries.
+func (c *Collector) addExplainLocked(fn ExplainFunc) {
+	if fn == nil {
+		return
+	}
+	if len(c.explains) >= maxQueryExplains {
+		c.dropped["explains"]++
+		return
+	}
+	c.explains = append(c.explains, pendingExplain{index: len(c.databaseQueries) - 1, fn: fn})
+}
+
+// hasPendingExplains reports whether query plans are waiting to be fetched.
+func (c *Collector) hasPendingExplains() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.explains) > 0
+}
+
+// runExplains fetches the queued plans one at a time and stores them on their queries.
+// Failures are stored as the plan text so they show up next to the query.
+func (c *Collector) runExplains(ctx context.Context) {
+	c.mu.Lock()
+	pending := c.explains
+	c.explains = nil
+	c.mu.Unlock()
+
+	ctx = ContextWithCollector(ctx, nil)
+	for _, p := range pending {
+		plan, err := p.fn(ctx)
+		if err != nil {
+			plan = "explain failed: " + err.Error()
+		}
+		c.mu.Lock()
+		if p.index < len(c.databaseQueries) {
+			c.databaseQueries[p.index].Explain = c.truncate(plan)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Wait blocks until request completions deferred for query plans have been saved.
+// Call it before shutdown so those requests are not lost.
+func (c *Clockwork) Wait() {
+	if c == nil {
+		return
+	}
+	c.deferred.Wait()
+}
@@ -0,0 +1,39 @@
+package clockwork
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteRequest_AttachesExplainPlans(t *testing.T) {
+	cw := NewClockwork(DefaultConfig(), NewInMemoryStorage(10, 1024*1024))
+	ctx := context.Background()
+
+	collector := cw.NewCollector(http.MethodGet, "/users")
+	require.NotNil(t, collector)
+
+	var sawCollector bool
+	collector.AddDatabaseQueryDetailed("SELECT * FROM users", 300*time.Millisecond, "pg", true, "", "users.go", 3,
+		QueryDetails{Explain: func(ctx context.Context) (string, error) {
+			sawCollector = CollectorFromContext(ctx) != nil
+			return "Seq Scan on users", nil
+		}})
+	collector.AddDatabaseQueryDetailed("SELECT * FROM posts", 300*time.Millisecond, "pg", true, "", "posts.go", 8,
+		QueryDetails{Explain: func(context.Context) (string, error) {
+			return "", errors.New("permission denied")
+		}})
+
+	require.NoError(t, cw.CompleteRequest(ctx, collector, http.StatusOK, 700*time.Millisecond))
+	cw.Wait()
+
+	meta, err := cw.GetMetadata(ctx, collector.ID())
+	require.NoError(t, err)
+	require.Equal(t, "Seq Scan on users", meta.DatabaseQueries[0].Explain)
+	require.Equal(t, "explain failed: permission denied", meta.DatabaseQueries[1].Explain)
+	require.False(t, sawCollector, "explain queries are not recorded")
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/RezaKargar/go-clockwork"
@@ -14,17 +15,40 @@ type Cache interface {
 	Delete(ctx context.Context, key string) error
 }
 
+// MultiCache is a Cache that reads and writes several keys in one round trip. GetMulti
+// returns the values of the keys that were found.
+type MultiCache interface {
+	Cache
+	GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error)
+	SetMulti(ctx context.Context, items map[string]interface{}, ttl time.Duration) error
+}
+
+// Sizer is implemented by cached values that know their size in bytes. Sizes of string
+// and []byte values are recorded without it.
+type Sizer interface {
+	Size() int
+}
+
 // Wrapper wraps Cache to emit cache telemetry to active Clockwork collectors.
 type Wrapper struct {
 	underlying Cache
+	name       string
 }
 
-// Wrap wraps cache operations with Clockwork instrumentation.
+var _ MultiCache = (*Wrapper)(nil)
+
+// Wrap wraps cache operations with Clockwork instrumentation. The returned Cache is a
+// *Wrapper, which also implements MultiCache.
 func Wrap(underlying Cache) Cache {
+	return WrapNamed(underlying, "")
+}
+
+// WrapNamed is like Wrap and records name as the cache store of each operation.
+func WrapNamed(underlying Cache, name string) Cache {
 	if underlying == nil {
 		return nil
 	}
-	return &Wrapper{underlying: underlying}
+	return &Wrapper{underlying: underlying, name: name}
 }
 
 // Get retrieves a value from cache and records hit/miss.
@@ -35,11 +59,7 @@ func (c *Wrapper) Get(ctx context.Context, key string) (interface{}, bool) {
 	duration := time.Since(startTime)
 
 	if collector != nil {
-		cacheType := "miss"
-		if found {
-			cacheType = "hit"
-		}
-		collector.AddCacheQuery(cacheType, key, duration)
+		c.record(collector, lookupType(found), key, duration, value, 0, nil)
 	}
 
 	return value, found
@@ -52,8 +72,8 @@ func (c *Wrapper) Set(ctx context.Context, key string, value interface{}, ttl ti
 	err := c.underlying.Set(ctx, key, value, ttl)
 	duration := time.Since(startTime)
 
-	if collector != nil && err == nil {
-		collector.AddCacheQuery("write", key, duration)
+	if collector != nil {
+		c.record(collector, clockwork.CacheWrite, key, duration, value, ttl, err)
 	}
 
 	return err
@@ -66,9 +86,104 @@ func (c *Wrapper) Delete(ctx context.Context, key string) error {
 	err := c.underlying.Delete(ctx, key)
 	duration := time.Since(startTime)
 
-	if collector != nil && err == nil {
-		collector.AddCacheQuery("delete", key, duration)
+	if collector != nil {
+		c.record(collector, clockwork.CacheDelete, key, duration, nil, 0, err)
+	}
+
+	return err
+}
+
+// GetMulti retrieves several values and records a hit or miss per key. It uses the
+// underlying GetMulti when the cache is a MultiCache and calls Get per key otherwise.
+// The round trip is split evenly across the keys.
+func (c *Wrapper) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	multi, ok := c.underlying.(MultiCache)
+	if !ok {
+		values := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			if value, found := c.Get(ctx, key); found {
+				values[key] = value
+			}
+		}
+		return values, nil
+	}
+
+	collector := clockwork.CollectorFromContext(ctx)
+	startTime := time.Now()
+	values, err := multi.GetMulti(ctx, keys)
+	duration := time.Since(startTime)
+
+	if collector != nil && len(keys) > 0 {
+		each := duration / time.Duration(len(keys))
+		for _, key := range keys {
+			value, found := values[key]
+			c.record(collector, lookupType(found), key, each, value, 0, err)
+		}
+	}
+
+	return values, err
+}
+
+// SetMulti stores several values and records a write per key, in key order. It uses the
+// underlying SetMulti when the cache is a MultiCache and calls Set per key otherwise,
+// stopping at the first error.
+func (c *Wrapper) SetMulti(ctx context.Context, items map[string]interface{}, ttl time.Duration) error {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	multi, ok := c.underlying.(MultiCache)
+	if !ok {
+		for _, key := range keys {
+			if err := c.Set(ctx, key, items[key], ttl); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	collector := clockwork.CollectorFromContext(ctx)
+	startTime := time.Now()
+	err := multi.SetMulti(ctx, items, ttl)
+	duration := time.Since(startTime)
+
+	if collector != nil && len(keys) > 0 {
+		each := duration / time.Duration(len(keys))
+		for _, key := range keys {
+			c.record(collector, clockwork.CacheWrite, key, each, items[key], ttl, err)
+		}
 	}
 
 	return err
 }
+
+func (c *Wrapper) record(collector *clockwork.Collector, cacheType, key string, duration time.Duration, value interface{}, ttl time.Duration, err error) {
+	collector.AddCacheQueryDetailed(cacheType, key, duration, clockwork.CacheDetails{
+		Connection: c.name,
+		Size:       valueSize(value),
+		Expiration: ttl,
+		Err:        err,
+	})
+}
+
+// valueSize returns the size of value in bytes, or 0 when it is unknown.
+func valueSize(value interface{}) int {
+	switch v := value.(type) {
+	case []byte:
+		return len(v)
+	case string:
+		return len(v)
+	case Sizer:
+		return v.Size()
+	}
+	return 0
+}
+
+func lookupType(found bool) string {
+	if found {
+		return clockwork.CacheHit
+	}
+	return clockwork.CacheMiss
+}
@@ -0,0 +1,22 @@
+module github.com/RezaKargar/go-clockwork/integrations/goredis
+
+go 1.26
+
+require (
+	github.com/RezaKargar/go-clockwork v0.2.0
+	github.com/redis/go-redis/v9 v9.8.0
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel v1.39.0 // indirect
+	go.opentelemetry.io/otel/trace v1.39.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/RezaKargar/go-clockwork => ../..
@@ -0,0 +1,204 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/redis/go-redis/v9"
+)
+
+// callerSkip attributes commands to application code rather than go-redis internals.
+var callerSkip = []string{"github.com/redis/go-redis/", "github.com/RezaKargar/go-clockwork/"}
+
+// Hook is a go-redis v9 hook that records commands, pipelines and dials on the Clockwork
+// collector of the command context. GET-family commands are also recorded as cache hits
+// and misses.
+type Hook struct {
+	cw         *clockwork.Clockwork
+	connection string
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// NewHook creates a hook; connection names the client in the Redis tab (default "redis").
+// Returns nil if Clockwork is disabled; a nil *Hook records nothing.
+//
+//	rdb.AddHook(goredis.NewHook(cw, "sessions"))
+func NewHook(cw *clockwork.Clockwork, connection string) *Hook {
+	if cw == nil || !cw.IsEnabled() {
+		return nil
+	}
+	if connection == "" {
+		connection = "redis"
+	}
+	return &Hook{cw: cw, connection: connection}
+}
+
+// DialHook implements redis.Hook by adding a timeline event for new connections.
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	if h == nil {
+		return next
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		collector := clockwork.CollectorFromContext(ctx)
+		if collector == nil {
+			return next(ctx, network, addr)
+		}
+		start := time.Now()
+		conn, err := next(ctx, network, addr)
+		description, color := addr, ""
+		if err != nil {
+			description, color = addr+": "+err.Error(), "red"
+		}
+		collector.AddTimelineEvent("redis dial", description, start, time.Now(), color)
+		return conn, err
+	}
+}
+
+// ProcessHook implements redis.Hook by recording each command.
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	if h == nil {
+		return next
+	}
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		collector := clockwork.CollectorFromContext(ctx)
+		if collector == nil {
+			return next(ctx, cmd)
+		}
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.record(collector, []redis.Cmder{cmd}, err, start, time.Since(start))
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook by recording each pipelined command. The
+// pipeline's round trip is split evenly across its commands.
+func (h *Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	if h == nil {
+		return next
+	}
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		collector := clockwork.CollectorFromContext(ctx)
+		if collector == nil || len(cmds) == 0 {
+			return next(ctx, cmds)
+		}
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.record(collector, cmds, unassignedErr(cmds, err), start, time.Since(start))
+		return err
+	}
+}
+
+// unassignedErr returns the pipeline error when no command carries one, as when the
+// connection failed before any reply was read; otherwise each command keeps its own error.
+func unassignedErr(cmds []redis.Cmder, err error) error {
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// record adds cmds to collector. err is used for commands without an error of their own:
+// go-redis sets a single command's error only after the hooks have run.
+func (h *Hook) record(collector *clockwork.Collector, cmds []redis.Cmder, err error, start time.Time, total time.Duration) {
+	file, line := clockwork.CallerOutside(callerSkip...)
+	duration := total / time.Duration(len(cmds))
+	timestamp := float64(start.UnixNano()) / 1e9
+	for i, cmd := range cmds {
+		command := clockwork.RedisCommand{
+			Command:    cmd.Name(),
+			Parameters: parameters(cmd),
+			Duration:   clockwork.DurationMs(duration),
+			Connection: h.connection,
+			File:       file,
+			Line:       line,
+			Timestamp:  timestamp + float64(time.Duration(i)*duration)/1e9,
+		}
+		cmdErr := cmd.Err()
+		if cmdErr == nil {
+			cmdErr = err
+		}
+		if cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+			command.Error = cmdErr.Error()
+		}
+		collector.AddRedisCommand(command)
+		h.recordCacheLookup(collector, cmd, cmdErr, duration)
+	}
+}
+
+// parameters returns the command arguments after the name, hiding credentials.
+func parameters(cmd redis.Cmder) []interface{} {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return nil
+	}
+	switch cmd.Name() {
+	case "auth", "hello":
+		return []interface{}{"[REDACTED]"}
+	}
+	return args[1:]
+}
+
+// recordCacheLookup records GET-family commands as cache hits and misses: a redis.Nil reply
+// (or a nil element of MGET/HMGET) is a miss. Commands that failed otherwise are skipped.
+// Hits record the size of string replies. The lookups stay off the timeline, which already
+// has the command's redis event.
+func (h *Hook) recordCacheLookup(collector *clockwork.Collector, cmd redis.Cmder, err error, duration time.Duration) {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return
+	}
+	args := cmd.Args()
+	details := clockwork.CacheDetails{Connection: h.connection, SkipTimeline: true}
+	if str, ok := cmd.(*redis.StringCmd); ok && err == nil {
+		details.Size = len(str.Val())
+	}
+	switch cmd.Name() {
+	case "get", "getex", "getdel":
+		if len(args) > 1 {
+			collector.AddCacheQueryDetailed(lookupType(err == nil), fmt.Sprint(args[1]), duration, details)
+		}
+	case "hget":
+		if len(args) > 2 {
+			collector.AddCacheQueryDetailed(lookupType(err == nil), fmt.Sprint(args[1], " ", args[2]), duration, details)
+		}
+	case "mget", "hmget":
+		slice, ok := cmd.(*redis.SliceCmd)
+		if !ok {
+			return
+		}
+		keys, prefix := args[1:], ""
+		if cmd.Name() == "hmget" && len(args) > 1 {
+			keys, prefix = args[2:], fmt.Sprint(args[1], " ")
+		}
+		values := slice.Val()
+		if len(keys) == 0 {
+			return
+		}
+		each := duration / time.Duration(len(keys))
+		for i, key := range keys {
+			var value interface{}
+			if i < len(values) {
+				value = values[i]
+			}
+			details.Size = 0
+			if s, ok := value.(string); ok {
+				details.Size = len(s)
+			}
+			collector.AddCacheQueryDetailed(lookupType(value != nil), prefix+fmt.Sprint(key), each, details)
+		}
+	}
+}
+
+func lookupType(hit bool) string {
+	if hit {
+		return clockwork.CacheHit
+	}
+	return clockwork.CacheMiss
+}
@@ -0,0 +1,165 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+var errReadOnly = errors.New("READONLY You can't write against a read only replica")
+
+func newTestRequest(t *testing.T) (*Hook, *clockwork.Collector, context.Context) {
+	t.Helper()
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+	hook := NewHook(cw, "")
+	require.NotNil(t, hook)
+	collector := cw.NewCollector("GET", "/cart")
+	return hook, collector, clockwork.ContextWithCollector(context.Background(), collector)
+}
+
+func TestHook_RecordsCommandOnceOnTimeline(t *testing.T) {
+	hook, collector, ctx := newTestRequest(t)
+	process := hook.ProcessHook(func(_ context.Context, cmd redis.Cmder) error {
+		cmd.(*redis.StringCmd).SetVal("blue")
+		return nil
+	})
+
+	require.NoError(t, process(ctx, redis.NewStringCmd(ctx, "get", "cart:1")))
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.RedisCommands, 1)
+	require.Equal(t, "get", meta.RedisCommands[0].Command)
+	require.Equal(t, []interface{}{"cart:1"}, meta.RedisCommands[0].Parameters)
+	require.Equal(t, "redis", meta.RedisCommands[0].Connection)
+	require.Len(t, meta.CacheQueries, 1)
+	require.Equal(t, clockwork.CacheHit, meta.CacheQueries[0].Type)
+	require.Equal(t, 4, meta.CacheQueries[0].Size)
+
+	var names []string
+	for _, event := range meta.TimelineEvents {
+		names = append(names, event.Name)
+	}
+	require.Equal(t, []string{"redis"}, names)
+}
+
+func TestHook_RecordsCommandError(t *testing.T) {
+	hook, collector, ctx := newTestRequest(t)
+	process := hook.ProcessHook(func(context.Context, redis.Cmder) error { return errReadOnly })
+
+	err := process(ctx, redis.NewStringCmd(ctx, "get", "cart:1"))
+	require.ErrorIs(t, err, errReadOnly)
+
+	meta := collector.GetMetadata()
+	require.Equal(t, errReadOnly.Error(), meta.RedisCommands[0].Error)
+	require.Empty(t, meta.CacheQueries, "failed lookups are neither hits nor misses")
+}
+
+func TestHook_RedactsCredentials(t *testing.T) {
+	hook, collector, ctx := newTestRequest(t)
+	process := hook.ProcessHook(func(context.Context, redis.Cmder) error { return nil })
+
+	require.NoError(t, process(ctx, redis.NewStatusCmd(ctx, "auth", "app", "s3cret")))
+	require.NoError(t, process(ctx, redis.NewMapStringInterfaceCmd(ctx, "hello", 3, "auth", "app", "s3cret")))
+
+	commands := collector.GetMetadata().RedisCommands
+	require.Len(t, commands, 2)
+	for _, command := range commands {
+		require.Equal(t, []interface{}{"[REDACTED]"}, command.Parameters, command.Command)
+	}
+}
+
+func TestHook_SplitsMultiGetHitsAndMisses(t *testing.T) {
+	hook, collector, ctx := newTestRequest(t)
+	process := hook.ProcessHook(func(_ context.Context, cmd redis.Cmder) error {
+		switch cmd.Name() {
+		case "mget":
+			cmd.(*redis.SliceCmd).SetVal([]interface{}{"ann", nil, "bo"})
+		case "hmget":
+			cmd.(*redis.SliceCmd).SetVal([]interface{}{nil, "42"})
+		}
+		return nil
+	})
+
+	require.NoError(t, process(ctx, redis.NewSliceCmd(ctx, "mget", "user:1", "user:2", "user:3")))
+	require.NoError(t, process(ctx, redis.NewSliceCmd(ctx, "hmget", "user:1", "name", "age")))
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.RedisCommands, 2)
+	lookups := make(map[string]string, len(meta.CacheQueries))
+	for _, query := range meta.CacheQueries {
+		lookups[query.Key] = query.Type
+	}
+	require.Equal(t, map[string]string{
+		"user:1":      clockwork.CacheHit,
+		"user:2":      clockwork.CacheMiss,
+		"user:3":      clockwork.CacheHit,
+		"user:1 name": clockwork.CacheMiss,
+		"user:1 age":  clockwork.CacheHit,
+	}, lookups)
+	require.Equal(t, 5, meta.CacheReads)
+	require.Equal(t, 3, meta.CacheHits)
+}
+
+func TestHook_PipelineErrorsStayOnTheirCommands(t *testing.T) {
+	hook, collector, ctx := newTestRequest(t)
+	process := hook.ProcessPipelineHook(func(_ context.Context, cmds []redis.Cmder) error {
+		cmds[0].(*redis.StatusCmd).SetVal("OK")
+		cmds[1].SetErr(errReadOnly)
+		cmds[2].SetErr(redis.Nil)
+		return errReadOnly
+	})
+
+	cmds := []redis.Cmder{
+		redis.NewStatusCmd(ctx, "set", "cart:1", "blue"),
+		redis.NewIntCmd(ctx, "incr", "cart:count"),
+		redis.NewStringCmd(ctx, "get", "cart:2"),
+	}
+	require.ErrorIs(t, process(ctx, cmds), errReadOnly)
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.RedisCommands, 3)
+	require.Empty(t, meta.RedisCommands[0].Error)
+	require.Equal(t, errReadOnly.Error(), meta.RedisCommands[1].Error)
+	require.Empty(t, meta.RedisCommands[2].Error, "redis.Nil is not an error")
+	require.Less(t, meta.RedisCommands[0].Timestamp, meta.RedisCommands[2].Timestamp)
+	require.Len(t, meta.CacheQueries, 1)
+	require.Equal(t, clockwork.CacheMiss, meta.CacheQueries[0].Type)
+}
+
+func TestHook_PipelineErrorWithoutCommandErrors(t *testing.T) {
+	hook, collector, ctx := newTestRequest(t)
+	errDial := errors.New("dial tcp: connection refused")
+	process := hook.ProcessPipelineHook(func(context.Context, []redis.Cmder) error { return errDial })
+
+	cmds := []redis.Cmder{redis.NewStringCmd(ctx, "get", "cart:1"), redis.NewIntCmd(ctx, "incr", "cart:count")}
+	require.ErrorIs(t, process(ctx, cmds), errDial)
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.RedisCommands, 2)
+	for _, command := range meta.RedisCommands {
+		require.Equal(t, errDial.Error(), command.Error)
+	}
+	require.Empty(t, meta.CacheQueries)
+}
+
+func TestHook_SkipsCommandsWithoutCollector(t *testing.T) {
+	hook, collector, _ := newTestRequest(t)
+	called := false
+	process := hook.ProcessHook(func(context.Context, redis.Cmder) error {
+		called = true
+		return nil
+	})
+
+	require.NoError(t, process(context.Background(), redis.NewStringCmd(context.Background(), "get", "cart:1")))
+	require.True(t, called)
+	require.Empty(t, collector.GetMetadata().RedisCommands)
+
+	var nilHook *Hook
+	require.NotNil(t, nilHook.ProcessHook(func(context.Context, redis.Cmder) error { return nil }))
+}
@@ -0,0 +1,168 @@
+package gorm
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+	gormio "gorm.io/gorm"
+)
+
+const startedAtKey = "clockwork:started_at"
+
+// callerSkip attributes queries to application code rather than GORM internals.
+var callerSkip = []string{"gorm.io/", "database/sql.", "github.com/RezaKargar/go-clockwork/"}
+
+// Plugin is a gorm.Plugin that records GORM statements and model actions on the
+// Clockwork collector carried by the statement context (db.WithContext(ctx)).
+type Plugin struct {
+	cw                 *clockwork.Clockwork
+	slowQueryThreshold time.Duration
+}
+
+// New creates the plugin. A zero slowQueryThreshold uses cw.Config().SlowQueryThreshold.
+//
+//	db.Use(cwgorm.New(cw, 0))
+func New(cw *clockwork.Clockwork, slowQueryThreshold time.Duration) *Plugin {
+	if cw != nil && slowQueryThreshold <= 0 {
+		slowQueryThreshold = cw.Config().SlowQueryThreshold
+	}
+	return &Plugin{cw: cw, slowQueryThreshold: slowQueryThreshold}
+}
+
+// Name implements gorm.Plugin.
+func (p *Plugin) Name() string {
+	return "clockwork"
+}
+
+// Initialize implements gorm.Plugin by registering callbacks around GORM's create,
+// query, update, delete, row and raw processors. It registers nothing when Clockwork is disabled.
+func (p *Plugin) Initialize(db *gormio.DB) error {
+	if p == nil || p.cw == nil || !p.cw.IsEnabled() {
+		return nil
+	}
+	cb := db.Callback()
+	type register func(name string, fn func(*gormio.DB)) error
+	processors := []struct {
+		name          string
+		action        string
+		before, after register
+	}{
+		{"create", clockwork.ModelCreated, cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", clockwork.ModelRetrieved, cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", clockwork.ModelUpdated, cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", clockwork.ModelDeleted, cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", "", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", "", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, proc := range processors {
+		if err := proc.before("clockwork:before_"+proc.name, before); err != nil {
+			return err
+		}
+		if err := proc.after("clockwork:after_"+proc.name, p.after(proc.action)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(db *gormio.DB) {
+	if db.Statement == nil || clockwork.CollectorFromContext(db.Statement.Context) == nil {
+		return
+	}
+	db.InstanceSet(startedAtKey, time.Now())
+}
+
+// after records the statement and, for model processors, the model action.
+func (p *Plugin) after(action string) func(*gormio.DB) {
+	return func(db *gormio.DB) {
+		if db.Statement == nil || db.DryRun {
+			return
+		}
+		collector := clockwork.CollectorFromContext(db.Statement.Context)
+		if collector == nil {
+			return
+		}
+		value, ok := db.InstanceGet(startedAtKey)
+		started, _ := value.(time.Time)
+		query := db.Statement.SQL.String()
+		if !ok || query == "" {
+			return
+		}
+		duration := time.Since(started)
+
+		err := db.Error
+		if errors.Is(err, gormio.ErrRecordNotFound) {
+			err = nil
+		}
+		connection := db.Dialector.Name()
+		model := db.Statement.Table
+		if db.Statement.Schema != nil {
+			model = db.Statement.Schema.Name
+		}
+		file, line := clockwork.CallerOutside(callerSkip...)
+		rows := db.RowsAffected
+		if rows < 0 {
+			rows = 0 // Row and Raw report -1
+		}
+
+		collector.AddDatabaseQueryDetailed(
+			query, duration, connection, duration > p.slowQueryThreshold,
+			model, file, line,
+			clockwork.QueryDetails{
+				RowsAffected: rows,
+				Err:          err,
+				Bindings:     db.Statement.Vars,
+				Dialect:      dialect(connection),
+			},
+		)
+
+		if action == "" || err != nil || rows == 0 || db.Statement.Schema == nil {
+			return
+		}
+		collector.AddModelAction(clockwork.ModelAction{
+			Model:      db.Statement.Schema.Name,
+			Action:     action,
+			Key:        primaryKey(db),
+			Count:      int(rows),
+			Query:      query,
+			Duration:   clockwork.DurationMs(duration),
+			Connection: connection,
+			File:       file,
+			Line:       line,
+		})
+	}
+}
+
+// primaryKey returns the primary key of the single row a statement touched, or nil.
+func primaryKey(db *gormio.DB) interface{} {
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil || db.RowsAffected != 1 {
+		return nil
+	}
+	rv := db.Statement.ReflectValue
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		if rv.Len() != 1 {
+			return nil
+		}
+		rv = reflect.Indirect(rv.Index(0))
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	value, zero := field.ValueOf(db.Statement.Context, rv)
+	if zero {
+		return nil
+	}
+	return value
+}
+
+// dialect maps a GORM dialector name to a clockwork dialect, or "" when unknown.
+func dialect(name string) string {
+	switch name {
+	case clockwork.DialectPostgres, clockwork.DialectMySQL, clockwork.DialectSQLite, clockwork.DialectSQLServer:
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	gormio "gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type Product struct {
+	ID    uint
+	Name  string
+	Price int
+}
+
+func openDB(t *testing.T, plugin *Plugin) *gormio.DB {
+	t.Helper()
+	db, err := gormio.Open(sqlite.Open("file::memory:"), &gormio.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&Product{}))
+	require.NoError(t, db.Use(plugin))
+	return db
+}
+
+func newTestRequest(t *testing.T, slowQueryThreshold time.Duration) (*gormio.DB, *clockwork.Collector, context.Context) {
+	t.Helper()
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+	db := openDB(t, New(cw, slowQueryThreshold))
+	collector := cw.NewCollector("GET", "/products")
+	return db, collector, clockwork.ContextWithCollector(context.Background(), collector)
+}
+
+func TestPlugin_RecordsModelCallbacks(t *testing.T) {
+	db, collector, ctx := newTestRequest(t, 0)
+	tx := db.WithContext(ctx)
+
+	product := Product{Name: "lamp", Price: 30}
+	require.NoError(t, tx.Create(&product).Error)
+	var found Product
+	require.NoError(t, tx.First(&found, product.ID).Error)
+	require.NoError(t, tx.Model(&found).Update("price", 35).Error)
+	require.ErrorIs(t, tx.First(&Product{}, 999).Error, gormio.ErrRecordNotFound)
+	require.NoError(t, tx.Delete(&found).Error)
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.DatabaseQueries, 5)
+	for _, q := range meta.DatabaseQueries {
+		require.Equal(t, "sqlite", q.Connection)
+		require.Equal(t, "Product", q.Model)
+		require.Empty(t, q.Error, "record not found is not an error")
+		require.NotEmpty(t, q.File)
+	}
+	insert := meta.DatabaseQueries[0]
+	require.Contains(t, insert.Query, "INSERT INTO `products`")
+	require.Equal(t, []interface{}{"lamp", 30}, insert.Bindings)
+	require.Equal(t, "INSERT INTO `products` (`name`,`price`) VALUES ('lamp',30) RETURNING `id`", insert.Runnable)
+	require.EqualValues(t, 1, insert.RowsAffected)
+	require.Contains(t, meta.DatabaseQueries[2].Query, "UPDATE `products`")
+	require.Zero(t, meta.DatabaseQueries[3].RowsAffected)
+
+	actions := meta.ModelsActions
+	require.Len(t, actions, 4, "no action for the lookup that found nothing")
+	require.Equal(t, []string{clockwork.ModelCreated, clockwork.ModelRetrieved, clockwork.ModelUpdated, clockwork.ModelDeleted},
+		[]string{actions[0].Action, actions[1].Action, actions[2].Action, actions[3].Action})
+	for _, action := range actions {
+		require.Equal(t, "Product", action.Model)
+		require.EqualValues(t, product.ID, action.Key)
+		require.Equal(t, 1, action.Count)
+		require.Equal(t, "sqlite", action.Connection)
+	}
+}
+
+func TestPlugin_RecordsRowAndRaw(t *testing.T) {
+	db, collector, ctx := newTestRequest(t, 0)
+	tx := db.WithContext(ctx)
+
+	require.NoError(t, tx.Create(&[]Product{{Name: "a", Price: 1}, {Name: "b", Price: 2}}).Error)
+	var count int64
+	require.NoError(t, tx.Table("products").Select("count(*)").Where("price > ?", 0).Row().Scan(&count))
+	require.EqualValues(t, 2, count)
+	require.NoError(t, tx.Exec("UPDATE products SET price = price + ?", 1).Error)
+	require.Error(t, tx.Exec("UPDATE missing SET x = 1").Error)
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.DatabaseQueries, 4)
+	require.Len(t, meta.ModelsActions, 1)
+	require.Equal(t, 2, meta.ModelsActions[0].Count)
+	require.Nil(t, meta.ModelsActions[0].Key, "no key for several rows")
+
+	row := meta.DatabaseQueries[1]
+	require.Contains(t, row.Query, "SELECT count(*) FROM `products`")
+	require.Equal(t, []interface{}{0}, row.Bindings)
+	require.Zero(t, row.RowsAffected, "Row reports -1 rows")
+	raw := meta.DatabaseQueries[2]
+	require.Equal(t, "UPDATE products SET price = price + 1", raw.Runnable)
+	require.EqualValues(t, 2, raw.RowsAffected)
+	require.NotEmpty(t, meta.DatabaseQueries[3].Error)
+}
+
+func TestPlugin_SlowQueriesAndContext(t *testing.T) {
+	db, collector, ctx := newTestRequest(t, time.Nanosecond)
+
+	require.NoError(t, db.Create(&Product{Name: "untracked"}).Error)
+	require.NoError(t, db.Session(&gormio.Session{DryRun: true}).WithContext(ctx).Create(&Product{Name: "dry"}).Error)
+	require.NoError(t, db.WithContext(ctx).Create(&Product{Name: "tracked"}).Error)
+
+	queries := collector.GetMetadata().DatabaseQueries
+	require.Len(t, queries, 1, "only statements run with the request context are recorded")
+	require.True(t, queries[0].Slow)
+}
+
+func TestPlugin_Disabled(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.Enabled = false
+	cw := clockwork.NewClockwork(cfg, nil)
+	db := openDB(t, New(cw, 0))
+
+	require.Nil(t, db.Callback().Create().Get("clockwork:after_create"))
+	require.NoError(t, db.Create(&Product{Name: "plain"}).Error)
+}
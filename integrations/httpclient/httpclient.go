@@ -0,0 +1,165 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+)
+
+// Transport is an http.RoundTripper that records outgoing requests on the Clockwork
+// collector found in the request context. Requests without a collector pass through untouched.
+type Transport struct {
+	// PropagateHosts lists hosts, as path.Match patterns (e.g. "*.internal"), that receive
+	// the Clockwork trigger header and the parent request ID so downstream services using
+	// Clockwork capture the call; their X-Clockwork-Id response header is then recorded as
+	// a subrequest. Empty disables propagation, so request IDs never reach third parties.
+	// Tail-sampled requests are not propagated since they may never be stored.
+	PropagateHosts []string
+
+	cw   *clockwork.Clockwork
+	base http.RoundTripper
+}
+
+// NewTransport wraps base (http.DefaultTransport when nil) with Clockwork instrumentation.
+func NewTransport(cw *clockwork.Clockwork, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{cw: cw, base: base}
+}
+
+// WrapClient returns a copy of client whose transport records outgoing requests.
+// A nil client wraps http.DefaultClient.
+func WrapClient(cw *clockwork.Clockwork, client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	wrapped := *client
+	wrapped.Transport = NewTransport(cw, client.Transport)
+	return &wrapped
+}
+
+// RoundTrip executes req and records method, URL, status, sizes, headers and duration.
+// The entry is recorded once the response body is read to EOF or closed.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	collector := clockwork.CollectorFromContext(req.Context())
+	if collector == nil || !t.cw.IsEnabled() {
+		return t.base.RoundTrip(req)
+	}
+
+	cfg := t.cw.Config()
+	propagate := !collector.TailSampled() && t.propagateTo(req.URL.Hostname())
+	if propagate {
+		// RoundTrippers must not modify the caller's request.
+		req = req.Clone(req.Context())
+		req.Header.Set(cfg.HeaderName, "1")
+		req.Header.Set(clockwork.ParentIDHeader, collector.ID())
+	}
+
+	started := time.Now()
+	entry := clockwork.HTTPRequest{
+		Request: clockwork.HTTPRequestInfo{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: flattenHeaders(req.Header),
+			Size:    max(req.ContentLength, 0),
+		},
+		Timestamp: float64(started.UnixNano()) / 1e9,
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Duration = clockwork.DurationMs(time.Since(started))
+		collector.AddHTTPRequest(entry)
+		return resp, err
+	}
+
+	entry.Response.Status = resp.StatusCode
+	if propagate {
+		if id := resp.Header.Get(cfg.IDHeader); id != "" {
+			collector.AddSubrequest(entry.Request.URL, id, resp.Header.Get(clockwork.PathHeader))
+		}
+	}
+	entry.Response.Headers = flattenHeaders(resp.Header)
+	if resp.Body == nil || resp.Body == http.NoBody {
+		entry.Response.Size = max(resp.ContentLength, 0)
+		entry.Duration = clockwork.DurationMs(time.Since(started))
+		collector.AddHTTPRequest(entry)
+		return resp, nil
+	}
+
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		record: func(size int64) {
+			entry.Response.Size = size
+			entry.Duration = clockwork.DurationMs(time.Since(started))
+			collector.AddHTTPRequest(entry)
+		},
+	}
+	return resp, nil
+}
+
+func (t *Transport) propagateTo(host string) bool {
+	for _, pattern := range t.PropagateHosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingBody counts response bytes and calls record once on EOF, error or Close.
+// Read and Close may race, as when a timeout closes the body during a read.
+type recordingBody struct {
+	io.ReadCloser
+	record func(size int64)
+
+	mu   sync.Mutex
+	size int64
+	done bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.size += int64(n)
+	if err != nil {
+		b.finishLocked()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finishLocked()
+	return err
+}
+
+func (b *recordingBody) finishLocked() {
+	if !b.done {
+		b.done = true
+		b.record(b.size)
+	}
+}
+
+// flattenHeaders keeps the first value per header; redaction rules apply when the request is stored.
+func flattenHeaders(headers http.Header) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if len(values) > 0 {
+			out[name] = values[0]
+		}
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func newClockwork(t *testing.T, cfg clockwork.Config) *clockwork.Clockwork {
+	t.Helper()
+	cfg.Normalize()
+	return clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+}
+
+// captured returns the context of a request captured because it carries the Clockwork header.
+func captured(t *testing.T, cw *clockwork.Clockwork) (*clockwork.Collector, context.Context) {
+	t.Helper()
+	collector, ok := clockwork.NewRequestCapture(cw, "GET", "/checkout", "/checkout", http.Header{"X-Clockwork": {"1"}})
+	require.True(t, ok)
+	return collector, clockwork.ContextWithCollector(context.Background(), collector)
+}
+
+// downstream answers like a service running Clockwork and keeps the headers it received.
+type downstream struct {
+	mu      sync.Mutex
+	headers []http.Header
+}
+
+func (d *downstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	d.headers = append(d.headers, r.Header.Clone())
+	d.mu.Unlock()
+	w.Header().Set("X-Clockwork-Id", "child-1")
+	w.Header().Set(clockwork.PathHeader, "/debug/")
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = io.WriteString(w, "hello")
+}
+
+func (d *downstream) received(i int) http.Header {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.headers[i]
+}
+
+func get(t *testing.T, client *http.Client, ctx context.Context, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestTransport_RecordsResponse(t *testing.T) {
+	server := httptest.NewServer(&downstream{})
+	defer server.Close()
+	cw := newClockwork(t, clockwork.DefaultConfig())
+	collector, ctx := captured(t, cw)
+	client := WrapClient(cw, nil)
+
+	resp := get(t, client, ctx, server.URL+"/users?page=2")
+	require.Empty(t, collector.GetMetadata().HTTPRequests, "recorded once the body is consumed")
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+	require.NoError(t, resp.Body.Close())
+
+	requests := collector.GetMetadata().HTTPRequests
+	require.Len(t, requests, 1)
+	require.Equal(t, http.MethodGet, requests[0].Request.Method)
+	require.Equal(t, server.URL+"/users?page=2", requests[0].Request.URL)
+	require.Equal(t, http.StatusOK, requests[0].Response.Status)
+	require.EqualValues(t, 5, requests[0].Response.Size)
+	require.Equal(t, "text/plain", requests[0].Response.Headers["Content-Type"])
+	require.Empty(t, requests[0].Error)
+}
+
+func TestTransport_RecordsTransportError(t *testing.T) {
+	server := httptest.NewServer(&downstream{})
+	url := server.URL
+	server.Close()
+	cw := newClockwork(t, clockwork.DefaultConfig())
+	collector, ctx := captured(t, cw)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/orders", strings.NewReader("{}"))
+	require.NoError(t, err)
+	_, err = WrapClient(cw, nil).Do(req)
+	require.Error(t, err)
+
+	requests := collector.GetMetadata().HTTPRequests
+	require.Len(t, requests, 1)
+	require.Equal(t, http.MethodPost, requests[0].Request.Method)
+	require.EqualValues(t, 2, requests[0].Request.Size)
+	require.NotEmpty(t, requests[0].Error)
+	require.Zero(t, requests[0].Response.Status)
+}
+
+func TestTransport_RecordsOnceOnEOFOrClose(t *testing.T) {
+	server := httptest.NewServer(&downstream{})
+	defer server.Close()
+	cw := newClockwork(t, clockwork.DefaultConfig())
+	collector, ctx := captured(t, cw)
+	client := WrapClient(cw, nil)
+
+	resp := get(t, client, ctx, server.URL)
+	_, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_, err = resp.Body.Read(make([]byte, 8))
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, resp.Body.Close())
+	require.Len(t, collector.GetMetadata().HTTPRequests, 1)
+
+	resp = get(t, client, ctx, server.URL)
+	require.NoError(t, resp.Body.Close())
+	require.NoError(t, resp.Body.Close())
+
+	requests := collector.GetMetadata().HTTPRequests
+	require.Len(t, requests, 2)
+	require.EqualValues(t, 5, requests[0].Response.Size)
+	require.Zero(t, requests[1].Response.Size, "closed unread")
+}
+
+func TestTransport_CloseDuringRead(t *testing.T) {
+	server := httptest.NewServer(&downstream{})
+	defer server.Close()
+	cw := newClockwork(t, clockwork.DefaultConfig())
+	collector, ctx := captured(t, cw)
+
+	resp := get(t, WrapClient(cw, nil), ctx, server.URL)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(io.Discard, resp.Body)
+	}()
+	_ = resp.Body.Close()
+	wg.Wait()
+
+	require.Len(t, collector.GetMetadata().HTTPRequests, 1)
+}
+
+func TestTransport_PropagatesOnlyToListedHosts(t *testing.T) {
+	handler := &downstream{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	cw := newClockwork(t, clockwork.DefaultConfig())
+	collector, ctx := captured(t, cw)
+
+	transport := NewTransport(cw, nil)
+	client := &http.Client{Transport: transport}
+	for _, hosts := range [][]string{nil, {"*.internal"}, {"127.0.0.1"}} {
+		transport.PropagateHosts = hosts
+		resp := get(t, client, ctx, server.URL)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	for i := range 2 {
+		require.Empty(t, handler.received(i).Get("X-Clockwork"), i)
+		require.Empty(t, handler.received(i).Get(clockwork.ParentIDHeader), i)
+	}
+	require.Equal(t, "1", handler.received(2).Get("X-Clockwork"))
+	require.Equal(t, collector.ID(), handler.received(2).Get(clockwork.ParentIDHeader))
+
+	subrequests := collector.GetMetadata().Subrequests
+	require.Len(t, subrequests, 1, "only propagated calls are linked")
+	require.Equal(t, server.URL, subrequests[0].URL)
+	require.Equal(t, "child-1", subrequests[0].ID)
+	require.Equal(t, "/debug/", subrequests[0].Path)
+}
+
+func TestTransport_SkipsPropagationWhenTailSampled(t *testing.T) {
+	handler := &downstream{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	cfg := clockwork.DefaultConfig()
+	cfg.TailServerErrors = true
+	cw := newClockwork(t, cfg)
+	collector, ok := clockwork.NewRequestCapture(cw, "GET", "/checkout", "/checkout", nil)
+	require.True(t, ok)
+	require.True(t, collector.TailSampled())
+
+	transport := NewTransport(cw, nil)
+	transport.PropagateHosts = []string{"127.0.0.1"}
+	resp := get(t, &http.Client{Transport: transport}, clockwork.ContextWithCollector(context.Background(), collector), server.URL)
+	require.NoError(t, resp.Body.Close())
+
+	require.Empty(t, handler.received(0).Get("X-Clockwork"))
+	require.Empty(t, handler.received(0).Get(clockwork.ParentIDHeader))
+	meta := collector.GetMetadata()
+	require.Len(t, meta.HTTPRequests, 1, "the call itself is still recorded")
+	require.Empty(t, meta.Subrequests)
+}
+
+func TestTransport_PassesThroughWithoutCollector(t *testing.T) {
+	handler := &downstream{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	cw := newClockwork(t, clockwork.DefaultConfig())
+
+	transport := NewTransport(cw, nil)
+	transport.PropagateHosts = []string{"*"}
+	resp := get(t, &http.Client{Transport: transport}, context.Background(), server.URL)
+	require.NoError(t, resp.Body.Close())
+	require.Empty(t, handler.received(0).Get("X-Clockwork"))
+}
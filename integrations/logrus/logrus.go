@@ -0,0 +1,132 @@
+package logrus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+	lr "github.com/sirupsen/logrus"
+)
+
+const maxContextFields = 20
+
+// Hook is a logrus.Hook that mirrors entries into Clockwork collectors. Entries logged
+// with a request context (logger.WithContext(r.Context())) go to its collector; others
+// are associated by their span_id and trace_id fields, or attached to the single active
+// request when exactly one is active.
+type Hook struct {
+	cw *clockwork.Clockwork
+}
+
+var _ lr.Hook = (*Hook)(nil)
+
+// NewHook creates a hook. Returns nil if Clockwork is disabled; a nil *Hook records nothing.
+//
+//	logger.AddHook(cwlogrus.NewHook(cw))
+func NewHook(cw *clockwork.Clockwork) *Hook {
+	if cw == nil || !cw.IsEnabled() {
+		return nil
+	}
+	return &Hook{cw: cw}
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []lr.Level {
+	if h == nil {
+		return nil
+	}
+	return lr.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (h *Hook) Fire(entry *lr.Entry) error {
+	if h == nil {
+		return nil
+	}
+	var collector *clockwork.Collector
+	if entry.Context != nil {
+		collector = clockwork.CollectorFromContext(entry.Context)
+	}
+	traceID, _ := entry.Data["trace_id"].(string)
+	spanID, _ := entry.Data["span_id"].(string)
+	if collector == nil && !h.cw.HasActiveTraces() {
+		return nil
+	}
+
+	level := levelName(entry.Level)
+	fields := contextFields(entry.Data)
+	trace := buildLogTrace(entry)
+
+	switch {
+	case collector != nil:
+		collector.AddLogEntryWithTrace(level, entry.Message, fields, trace)
+	case traceID != "" || spanID != "":
+		h.cw.RecordLogForSpanWithTrace(traceID, spanID, level, entry.Message, fields, trace)
+	default:
+		h.cw.RecordLogForSingleActiveWithTrace(level, entry.Message, fields, trace)
+	}
+	return nil
+}
+
+// contextFields keeps the first 20 entry fields other than trace_id in key order, as
+// logrus formatters print them. Errors are recorded as their message.
+func contextFields(data lr.Fields) map[string]interface{} {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if len(fields) >= maxContextFields {
+			break
+		}
+		if key == "trace_id" {
+			continue
+		}
+		switch v := data[key].(type) {
+		case error:
+			fields[key] = v.Error()
+		case time.Duration:
+			fields[key] = v.String()
+		case time.Time:
+			fields[key] = v.UTC().Format(time.RFC3339Nano)
+		case fmt.Stringer:
+			fields[key] = v.String()
+		default:
+			fields[key] = v
+		}
+	}
+	return fields
+}
+
+// levelName maps logrus levels to Clockwork log levels.
+func levelName(level lr.Level) string {
+	switch level {
+	case lr.TraceLevel, lr.DebugLevel:
+		return "debug"
+	case lr.WarnLevel:
+		return "warning"
+	case lr.ErrorLevel:
+		return "error"
+	case lr.FatalLevel:
+		return "critical"
+	case lr.PanicLevel:
+		return "emergency"
+	}
+	return "info"
+}
+
+// buildLogTrace returns the entry's caller, recorded when the logger has ReportCaller set.
+func buildLogTrace(entry *lr.Entry) []clockwork.LogTraceFrame {
+	if !entry.HasCaller() {
+		return nil
+	}
+	return []clockwork.LogTraceFrame{{
+		Call: entry.Caller.Function,
+		File: entry.Caller.File,
+		Line: entry.Caller.Line,
+	}}
+}
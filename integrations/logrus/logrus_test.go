@@ -0,0 +1,113 @@
+package logrus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+	lr "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogger(t *testing.T) (*clockwork.Clockwork, *lr.Logger) {
+	t.Helper()
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+	logger := lr.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(lr.TraceLevel)
+	logger.AddHook(NewHook(cw))
+	return cw, logger
+}
+
+func activeCollector(cw *clockwork.Clockwork, traceID, spanID string) *clockwork.Collector {
+	collector := cw.NewCollector("GET", "/orders")
+	collector.SetTrace(traceID, spanID)
+	cw.RegisterCollector(collector)
+	return collector
+}
+
+func TestHook_RecordsEntriesWithRequestContext(t *testing.T) {
+	cw, logger := newLogger(t)
+	first := activeCollector(cw, "trace-1", "span-1")
+	second := activeCollector(cw, "trace-2", "span-2")
+
+	ctx := clockwork.ContextWithCollector(context.Background(), second)
+	logger.WithContext(ctx).WithFields(lr.Fields{
+		"error":   errors.New("out of stock"),
+		"elapsed": 1500 * time.Millisecond,
+		"sku":     "A-1",
+	}).Warn("order rejected")
+
+	require.Empty(t, first.GetMetadata().LogEntries)
+	entries := second.GetMetadata().LogEntries
+	require.Len(t, entries, 1)
+	require.Equal(t, "warning", entries[0].Level)
+	require.Equal(t, "order rejected", entries[0].Message)
+	require.Equal(t, map[string]interface{}{"error": "out of stock", "elapsed": "1.5s", "sku": "A-1"}, entries[0].Context)
+}
+
+func TestHook_RoutesByTraceFields(t *testing.T) {
+	cw, logger := newLogger(t)
+	first := activeCollector(cw, "trace-1", "span-1")
+	second := activeCollector(cw, "trace-2", "span-2")
+
+	logger.WithFields(lr.Fields{"trace_id": "trace-1", "span_id": "span-1"}).Info("to first")
+	logger.WithField("trace_id", "trace-2").Debug("to second")
+	logger.Info("ambiguous")
+
+	require.Len(t, first.GetMetadata().LogEntries, 1)
+	require.Equal(t, "to first", first.GetMetadata().LogEntries[0].Message)
+	require.NotContains(t, first.GetMetadata().LogEntries[0].Context, "trace_id")
+	require.Len(t, second.GetMetadata().LogEntries, 1)
+	require.Equal(t, "debug", second.GetMetadata().LogEntries[0].Level)
+	require.EqualValues(t, 1, cw.CorrelationStats().UnattributedLogs)
+}
+
+func TestHook_KeepsFirstFieldsInKeyOrder(t *testing.T) {
+	cw, logger := newLogger(t)
+	collector := activeCollector(cw, "", "")
+
+	data := make(lr.Fields, 25)
+	for i := 1; i <= 25; i++ {
+		data[fmt.Sprintf("f%02d", i)] = i
+	}
+	for range 5 {
+		logger.WithFields(data).Info("many fields")
+	}
+
+	for _, entry := range collector.GetMetadata().LogEntries {
+		require.Len(t, entry.Context, maxContextFields)
+		require.Contains(t, entry.Context, "f01")
+		require.Contains(t, entry.Context, "f20")
+		require.NotContains(t, entry.Context, "f21")
+	}
+}
+
+func TestHook_RecordsCaller(t *testing.T) {
+	cw, logger := newLogger(t)
+	collector := activeCollector(cw, "", "")
+	logger.SetReportCaller(true)
+
+	logger.Error("with caller")
+
+	entry := collector.GetMetadata().LogEntries[0]
+	require.Equal(t, "error", entry.Level)
+	require.Len(t, entry.Trace, 1)
+	require.Contains(t, entry.Trace[0].File, "logrus_test.go")
+	require.Contains(t, entry.Trace[0].Call, "TestHook_RecordsCaller")
+}
+
+func TestNewHook_Disabled(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.Enabled = false
+	hook := NewHook(clockwork.NewClockwork(cfg, nil))
+	require.Nil(t, hook)
+	require.Empty(t, hook.Levels())
+	require.NoError(t, hook.Fire(lr.NewEntry(lr.New())))
+}
@@ -0,0 +1,232 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+	pgxv5 "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// callerSkip attributes queries to application code rather than pgx internals.
+var callerSkip = []string{"github.com/jackc/pgx/", "github.com/jackc/puddle/", "github.com/RezaKargar/go-clockwork/"}
+
+// Tracer records pgx queries, batches, COPY operations, connects and pool acquires on the
+// Clockwork collector of the query context. Set it as pgx.ConnConfig.Tracer (or
+// pgxpool.Config.ConnConfig.Tracer); acquire tracing applies to pools only.
+type Tracer struct {
+	cw                 *clockwork.Clockwork
+	slowQueryThreshold time.Duration
+}
+
+var (
+	_ pgxv5.QueryTracer     = (*Tracer)(nil)
+	_ pgxv5.BatchTracer     = (*Tracer)(nil)
+	_ pgxv5.CopyFromTracer  = (*Tracer)(nil)
+	_ pgxv5.ConnectTracer   = (*Tracer)(nil)
+	_ pgxpool.AcquireTracer = (*Tracer)(nil)
+)
+
+// NewTracer creates a pgx tracer. A zero slowQueryThreshold uses
+// cw.Config().SlowQueryThreshold. Returns nil if Clockwork is disabled; a nil *Tracer
+// records nothing.
+func NewTracer(cw *clockwork.Clockwork, slowQueryThreshold time.Duration) *Tracer {
+	if cw == nil || !cw.IsEnabled() {
+		return nil
+	}
+	if slowQueryThreshold <= 0 {
+		slowQueryThreshold = cw.Config().SlowQueryThreshold
+	}
+	return &Tracer{cw: cw, slowQueryThreshold: slowQueryThreshold}
+}
+
+type (
+	queryKey   struct{}
+	batchKey   struct{}
+	copyKey    struct{}
+	connectKey struct{}
+	acquireKey struct{}
+)
+
+type queryStart struct {
+	sql   string
+	args  []interface{}
+	start time.Time
+}
+
+// batchState times batch members: each is measured from the end of the previous one.
+type batchState struct {
+	start time.Time
+	last  time.Time
+	count int
+}
+
+type copyStart struct {
+	sql   string
+	start time.Time
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *Tracer) TraceQueryStart(ctx context.Context, _ *pgxv5.Conn, data pgxv5.TraceQueryStartData) context.Context {
+	if t == nil || clockwork.CollectorFromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, queryKey{}, &queryStart{sql: data.SQL, args: data.Args, start: time.Now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (t *Tracer) TraceQueryEnd(ctx context.Context, conn *pgxv5.Conn, data pgxv5.TraceQueryEndData) {
+	q, ok := ctx.Value(queryKey{}).(*queryStart)
+	if t == nil || !ok {
+		return
+	}
+	t.record(ctx, conn, q.sql, q.args, time.Since(q.start), data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceBatchStart implements pgx.BatchTracer.
+func (t *Tracer) TraceBatchStart(ctx context.Context, _ *pgxv5.Conn, _ pgxv5.TraceBatchStartData) context.Context {
+	if t == nil || clockwork.CollectorFromContext(ctx) == nil {
+		return ctx
+	}
+	now := time.Now()
+	return context.WithValue(ctx, batchKey{}, &batchState{start: now, last: now})
+}
+
+// TraceBatchQuery implements pgx.BatchTracer. Each batch member is recorded as a query.
+func (t *Tracer) TraceBatchQuery(ctx context.Context, conn *pgxv5.Conn, data pgxv5.TraceBatchQueryData) {
+	b, ok := ctx.Value(batchKey{}).(*batchState)
+	if t == nil || !ok {
+		return
+	}
+	now := time.Now()
+	b.count++
+	t.record(ctx, conn, data.SQL, data.Args, now.Sub(b.last), data.CommandTag.RowsAffected(), data.Err)
+	b.last = now
+}
+
+// TraceBatchEnd implements pgx.BatchTracer by adding a timeline event spanning the batch.
+func (t *Tracer) TraceBatchEnd(ctx context.Context, _ *pgxv5.Conn, data pgxv5.TraceBatchEndData) {
+	b, ok := ctx.Value(batchKey{}).(*batchState)
+	collector := clockwork.CollectorFromContext(ctx)
+	if t == nil || !ok || collector == nil {
+		return
+	}
+	description := fmt.Sprintf("%d queries", b.count)
+	if data.Err != nil {
+		description += ": " + data.Err.Error()
+	}
+	collector.AddTimelineEvent("pgx batch", description, b.start, time.Now(), colorFor(data.Err))
+}
+
+// TraceCopyFromStart implements pgx.CopyFromTracer.
+func (t *Tracer) TraceCopyFromStart(ctx context.Context, _ *pgxv5.Conn, data pgxv5.TraceCopyFromStartData) context.Context {
+	if t == nil || clockwork.CollectorFromContext(ctx) == nil {
+		return ctx
+	}
+	columns := make([]string, len(data.ColumnNames))
+	for i, name := range data.ColumnNames {
+		columns[i] = pgxv5.Identifier{name}.Sanitize()
+	}
+	sql := "COPY " + data.TableName.Sanitize() + " (" + strings.Join(columns, ", ") + ") FROM STDIN"
+	return context.WithValue(ctx, copyKey{}, &copyStart{sql: sql, start: time.Now()})
+}
+
+// TraceCopyFromEnd implements pgx.CopyFromTracer. The COPY is recorded as a query with
+// the number of rows copied.
+func (t *Tracer) TraceCopyFromEnd(ctx context.Context, conn *pgxv5.Conn, data pgxv5.TraceCopyFromEndData) {
+	c, ok := ctx.Value(copyKey{}).(*copyStart)
+	if t == nil || !ok {
+		return
+	}
+	t.record(ctx, conn, c.sql, nil, time.Since(c.start), data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceConnectStart implements pgx.ConnectTracer.
+func (t *Tracer) TraceConnectStart(ctx context.Context, _ pgxv5.TraceConnectStartData) context.Context {
+	if t == nil || clockwork.CollectorFromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, connectKey{}, time.Now())
+}
+
+// TraceConnectEnd implements pgx.ConnectTracer by adding a timeline event for the connect.
+func (t *Tracer) TraceConnectEnd(ctx context.Context, data pgxv5.TraceConnectEndData) {
+	t.timeline(ctx, connectKey{}, "pgx connect", data.Conn, data.Err)
+}
+
+// TraceAcquireStart implements pgxpool.AcquireTracer.
+func (t *Tracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	if t == nil || clockwork.CollectorFromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, acquireKey{}, time.Now())
+}
+
+// TraceAcquireEnd implements pgxpool.AcquireTracer by adding a timeline event for the time
+// spent waiting for a pool connection.
+func (t *Tracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	t.timeline(ctx, acquireKey{}, "pgx acquire", data.Conn, data.Err)
+}
+
+func (t *Tracer) timeline(ctx context.Context, key interface{}, name string, conn *pgxv5.Conn, err error) {
+	start, ok := ctx.Value(key).(time.Time)
+	collector := clockwork.CollectorFromContext(ctx)
+	if t == nil || !ok || collector == nil {
+		return
+	}
+	description := connectionName(conn)
+	if err != nil {
+		description = err.Error()
+	}
+	collector.AddTimelineEvent(name, description, start, time.Now(), colorFor(err))
+}
+
+func (t *Tracer) record(ctx context.Context, conn *pgxv5.Conn, sql string, args []interface{}, duration time.Duration, rows int64, err error) {
+	collector := clockwork.CollectorFromContext(ctx)
+	if collector == nil {
+		return
+	}
+	file, line := clockwork.CallerOutside(callerSkip...)
+	details := clockwork.QueryDetails{RowsAffected: rows, Err: err}
+	if bindings, ok := queryArgs(args); ok {
+		details.Bindings = bindings
+		details.Dialect = clockwork.DialectPostgres
+	}
+	collector.AddDatabaseQueryDetailed(sql, duration, connectionName(conn), duration > t.slowQueryThreshold, "", file, line, details)
+}
+
+// queryArgs strips the leading query options pgx accepts (pgx.QueryExecMode,
+// pgx.QueryResultFormats and pgx.QueryResultFormatsByOID) from args. It reports false when
+// the arguments are rewritten by pgx (e.g. pgx.NamedArgs) and so do not match the SQL's
+// placeholders.
+func queryArgs(args []interface{}) ([]interface{}, bool) {
+	for len(args) > 0 {
+		switch args[0].(type) {
+		case pgxv5.QueryExecMode, pgxv5.QueryResultFormats, pgxv5.QueryResultFormatsByOID:
+			args = args[1:]
+		case pgxv5.QueryRewriter:
+			return nil, false
+		default:
+			return args, true
+		}
+	}
+	return args, true
+}
+
+// connectionName is the database name of conn, or "pgx" when it is unknown.
+func connectionName(conn *pgxv5.Conn) string {
+	if conn != nil && conn.Config() != nil && conn.Config().Database != "" {
+		return conn.Config().Database
+	}
+	return "pgx"
+}
+
+func colorFor(err error) string {
+	if err != nil {
+		return "red"
+	}
+	return ""
+}
@@ -0,0 +1,197 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+	pgxv5 "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+var errUniqueViolation = errors.New("duplicate key value violates unique constraint")
+
+func newTestRequest(t *testing.T, slowQueryThreshold time.Duration) (*Tracer, *clockwork.Collector, context.Context) {
+	t.Helper()
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+	tracer := NewTracer(cw, slowQueryThreshold)
+	require.NotNil(t, tracer)
+	collector := cw.NewCollector("GET", "/accounts")
+	return tracer, collector, clockwork.ContextWithCollector(context.Background(), collector)
+}
+
+func timelineEvents(meta *clockwork.Metadata, name string) []clockwork.TimelineEvent {
+	var events []clockwork.TimelineEvent
+	for _, event := range meta.TimelineEvents {
+		if event.Name == name {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestTracer_RecordsQuery(t *testing.T) {
+	tracer, collector, ctx := newTestRequest(t, time.Hour)
+
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgxv5.TraceQueryStartData{
+		SQL:  "UPDATE accounts SET name = $1 WHERE id = $2",
+		Args: []interface{}{pgxv5.QueryExecModeSimpleProtocol, "o'neil", 7},
+	})
+	tracer.TraceQueryEnd(queryCtx, nil, pgxv5.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 1")})
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.DatabaseQueries, 1)
+	q := meta.DatabaseQueries[0]
+	require.Equal(t, "pgx", q.Connection)
+	require.Equal(t, "accounts", q.Model)
+	require.Equal(t, []interface{}{"o'neil", 7}, q.Bindings)
+	require.Equal(t, "UPDATE accounts SET name = 'o''neil' WHERE id = 7", q.Runnable)
+	require.EqualValues(t, 1, q.RowsAffected)
+	require.False(t, q.Slow)
+	require.Empty(t, q.Error)
+	events := timelineEvents(meta, "db")
+	require.Len(t, events, 1)
+	require.Equal(t, "blue", events[0].Color)
+}
+
+func TestTracer_RecordsSlowAndFailedQueries(t *testing.T) {
+	tracer, collector, ctx := newTestRequest(t, time.Nanosecond)
+
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgxv5.TraceQueryStartData{SQL: "SELECT pg_sleep(1)"})
+	time.Sleep(time.Millisecond)
+	tracer.TraceQueryEnd(queryCtx, nil, pgxv5.TraceQueryEndData{})
+	queryCtx = tracer.TraceQueryStart(ctx, nil, pgxv5.TraceQueryStartData{SQL: "INSERT INTO accounts (id) VALUES (1)"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgxv5.TraceQueryEndData{Err: errUniqueViolation})
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.DatabaseQueries, 2)
+	require.True(t, meta.DatabaseQueries[0].Slow)
+	require.Empty(t, meta.DatabaseQueries[0].Error)
+	require.Equal(t, errUniqueViolation.Error(), meta.DatabaseQueries[1].Error)
+	for _, event := range timelineEvents(meta, "db") {
+		require.Equal(t, "red", event.Color)
+	}
+}
+
+func TestTracer_RecordsBatchMembers(t *testing.T) {
+	tracer, collector, ctx := newTestRequest(t, time.Hour)
+
+	batchCtx := tracer.TraceBatchStart(ctx, nil, pgxv5.TraceBatchStartData{})
+	tracer.TraceBatchQuery(batchCtx, nil, pgxv5.TraceBatchQueryData{
+		SQL:        "INSERT INTO accounts (name) VALUES ($1)",
+		Args:       []interface{}{"ann"},
+		CommandTag: pgconn.NewCommandTag("INSERT 0 1"),
+	})
+	tracer.TraceBatchQuery(batchCtx, nil, pgxv5.TraceBatchQueryData{
+		SQL:  "INSERT INTO accounts (name) VALUES ($1)",
+		Args: []interface{}{"bo"},
+		Err:  errUniqueViolation,
+	})
+	tracer.TraceBatchEnd(batchCtx, nil, pgxv5.TraceBatchEndData{Err: errUniqueViolation})
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.DatabaseQueries, 2)
+	require.Equal(t, "INSERT INTO accounts (name) VALUES ('ann')", meta.DatabaseQueries[0].Runnable)
+	require.EqualValues(t, 1, meta.DatabaseQueries[0].RowsAffected)
+	require.Equal(t, errUniqueViolation.Error(), meta.DatabaseQueries[1].Error)
+	batches := timelineEvents(meta, "pgx batch")
+	require.Len(t, batches, 1)
+	require.Equal(t, "2 queries: "+errUniqueViolation.Error(), batches[0].Description)
+	require.Equal(t, "red", batches[0].Color)
+}
+
+func TestTracer_RecordsCopyFrom(t *testing.T) {
+	tracer, collector, ctx := newTestRequest(t, time.Hour)
+
+	copyCtx := tracer.TraceCopyFromStart(ctx, nil, pgxv5.TraceCopyFromStartData{
+		TableName:   pgxv5.Identifier{"public", "accounts"},
+		ColumnNames: []string{"id", "name"},
+	})
+	tracer.TraceCopyFromEnd(copyCtx, nil, pgxv5.TraceCopyFromEndData{CommandTag: pgconn.NewCommandTag("COPY 3")})
+
+	queries := collector.GetMetadata().DatabaseQueries
+	require.Len(t, queries, 1)
+	require.Equal(t, `COPY "public"."accounts" ("id", "name") FROM STDIN`, queries[0].Query)
+	require.EqualValues(t, 3, queries[0].RowsAffected)
+	require.Empty(t, queries[0].Bindings)
+}
+
+func TestTracer_RecordsConnectAndAcquire(t *testing.T) {
+	tracer, collector, ctx := newTestRequest(t, time.Hour)
+	errRefused := errors.New("dial tcp: connection refused")
+
+	connectCtx := tracer.TraceConnectStart(ctx, pgxv5.TraceConnectStartData{})
+	tracer.TraceConnectEnd(connectCtx, pgxv5.TraceConnectEndData{})
+	connectCtx = tracer.TraceConnectStart(ctx, pgxv5.TraceConnectStartData{})
+	tracer.TraceConnectEnd(connectCtx, pgxv5.TraceConnectEndData{Err: errRefused})
+	acquireCtx := tracer.TraceAcquireStart(ctx, nil, pgxpool.TraceAcquireStartData{})
+	tracer.TraceAcquireEnd(acquireCtx, nil, pgxpool.TraceAcquireEndData{})
+
+	meta := collector.GetMetadata()
+	connects := timelineEvents(meta, "pgx connect")
+	require.Len(t, connects, 2)
+	require.Equal(t, "pgx", connects[0].Description)
+	require.Empty(t, connects[0].Color)
+	require.Equal(t, errRefused.Error(), connects[1].Description)
+	require.Equal(t, "red", connects[1].Color)
+	acquires := timelineEvents(meta, "pgx acquire")
+	require.Len(t, acquires, 1)
+	require.Equal(t, "pgx", acquires[0].Description)
+}
+
+func TestTracer_SkipsWithoutCollector(t *testing.T) {
+	tracer, collector, _ := newTestRequest(t, time.Hour)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgxv5.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgxv5.TraceQueryEndData{})
+	require.Empty(t, collector.GetMetadata().DatabaseQueries)
+
+	var nilTracer *Tracer
+	ctx = nilTracer.TraceQueryStart(context.Background(), nil, pgxv5.TraceQueryStartData{SQL: "SELECT 1"})
+	nilTracer.TraceQueryEnd(ctx, nil, pgxv5.TraceQueryEndData{})
+}
+
+func TestQueryArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []interface{}
+		expected []interface{}
+		bound    bool
+	}{
+		{"plain", []interface{}{1, "a"}, []interface{}{1, "a"}, true},
+		{"exec mode", []interface{}{pgxv5.QueryExecModeExec, 1}, []interface{}{1}, true},
+		{"result formats", []interface{}{pgxv5.QueryResultFormats{1}, pgxv5.QueryExecModeExec, 1}, []interface{}{1}, true},
+		{"result formats by oid", []interface{}{pgxv5.QueryResultFormatsByOID{25: 0}, 1}, []interface{}{1}, true},
+		{"options only", []interface{}{pgxv5.QueryExecModeSimpleProtocol}, []interface{}{}, true},
+		{"named args", []interface{}{pgxv5.NamedArgs{"id": 1}}, nil, false},
+		{"rewriter after options", []interface{}{pgxv5.QueryExecModeExec, pgxv5.StrictNamedArgs{"id": 1}}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, bound := queryArgs(tt.args)
+			require.Equal(t, tt.bound, bound)
+			require.Equal(t, tt.expected, args)
+		})
+	}
+}
+
+func TestTracer_SkipsBindingsForRewrittenQueries(t *testing.T) {
+	tracer, collector, ctx := newTestRequest(t, time.Hour)
+
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgxv5.TraceQueryStartData{
+		SQL:  "SELECT * FROM accounts WHERE id = @id",
+		Args: []interface{}{pgxv5.NamedArgs{"id": 1}},
+	})
+	tracer.TraceQueryEnd(queryCtx, nil, pgxv5.TraceQueryEndData{})
+
+	queries := collector.GetMetadata().DatabaseQueries
+	require.Len(t, queries, 1)
+	require.Empty(t, queries[0].Bindings)
+	require.Empty(t, queries[0].Runnable)
+}
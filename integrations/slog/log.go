@@ -0,0 +1,54 @@
+package slog
+
+import (
+	"context"
+	"log"
+	stdslog "log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// NewLogLogger returns a standard library *log.Logger whose output is handled by handler
+// at level with ctx, so code written against the log package reaches the request collector:
+//
+//	legacy.Run(cwslog.NewLogLogger(r.Context(), handler, slog.LevelInfo))
+//
+// Each Print call becomes one record, without the trailing newline; the logger has no
+// prefix or flags since handler adds its own time and source.
+func NewLogLogger(ctx context.Context, handler stdslog.Handler, level stdslog.Level) *log.Logger {
+	return log.New(&logWriter{ctx: ctx, handler: handler, level: level}, "", 0)
+}
+
+// logWriter turns writes from a log.Logger into slog records.
+type logWriter struct {
+	ctx     context.Context
+	handler stdslog.Handler
+	level   stdslog.Level
+}
+
+// Write implements io.Writer. It never fails so the log package does not report errors.
+func (w *logWriter) Write(p []byte) (int, error) {
+	if !w.handler.Enabled(w.ctx, w.level) {
+		return len(p), nil
+	}
+	message := strings.TrimSuffix(string(p), "\n")
+	record := stdslog.NewRecord(time.Now(), w.level, message, callerPC())
+	_ = w.handler.Handle(w.ctx, record)
+	return len(p), nil
+}
+
+// callerPC returns the program counter of the first caller outside the log package and
+// this writer.
+func callerPC() uintptr {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	for _, pc := range pcs[:n] {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if strings.HasPrefix(frame.Function, "log.") {
+			continue
+		}
+		return pc
+	}
+	return 0
+}
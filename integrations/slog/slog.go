@@ -0,0 +1,208 @@
+package slog
+
+import (
+	"context"
+	stdslog "log/slog"
+	"runtime"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+)
+
+// Handler wraps a slog.Handler and mirrors records into the Clockwork collector of the
+// context passed to Handle (logger.InfoContext(r.Context(), ...)). Groups and attributes
+// become nested log context.
+type Handler struct {
+	underlying stdslog.Handler
+	cw         *clockwork.Clockwork
+	// goas holds the groups and attributes added by WithGroup and WithAttrs, in order.
+	goas []groupOrAttrs
+}
+
+// groupOrAttrs is either a group opened by WithGroup or attributes added by WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []stdslog.Attr
+}
+
+var _ stdslog.Handler = (*Handler)(nil)
+
+// WrapHandler wraps handler for Clockwork log collection. It returns handler unchanged
+// when Clockwork is disabled.
+//
+//	logger := slog.New(cwslog.WrapHandler(slog.NewJSONHandler(os.Stdout, nil), cw))
+func WrapHandler(handler stdslog.Handler, cw *clockwork.Clockwork) stdslog.Handler {
+	if handler == nil || cw == nil || !cw.IsEnabled() {
+		return handler
+	}
+	return &Handler{underlying: handler, cw: cw}
+}
+
+// Enabled implements slog.Handler; records below the underlying handler's level are not collected.
+func (h *Handler) Enabled(ctx context.Context, level stdslog.Level) bool {
+	return h.underlying.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler by passing the record on and adding it to the
+// collector of ctx, if any.
+func (h *Handler) Handle(ctx context.Context, record stdslog.Record) error {
+	err := h.underlying.Handle(ctx, record)
+
+	collector := clockwork.CollectorFromContext(ctx)
+	if collector == nil {
+		return err
+	}
+	collector.AddLogEntryWithTrace(levelName(record.Level), record.Message, h.contextFields(record), buildLogTrace(record.PC))
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []stdslog.Attr) stdslog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(h.underlying.WithAttrs(attrs), groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) stdslog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(h.underlying.WithGroup(name), groupOrAttrs{group: name})
+}
+
+func (h *Handler) with(underlying stdslog.Handler, goa groupOrAttrs) *Handler {
+	goas := make([]groupOrAttrs, 0, len(h.goas)+1)
+	goas = append(append(goas, h.goas...), goa)
+	return &Handler{underlying: underlying, cw: h.cw, goas: goas}
+}
+
+// contextFields nests the handler's attributes and the record's under their groups.
+// Groups left without attributes are omitted, as slog handlers do.
+func (h *Handler) contextFields(record stdslog.Record) map[string]interface{} {
+	root := make(map[string]interface{}, record.NumAttrs())
+	current := root
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			next := make(map[string]interface{})
+			current[goa.group] = next
+			current = next
+			continue
+		}
+		for _, attr := range goa.attrs {
+			addAttr(current, attr)
+		}
+	}
+	record.Attrs(func(attr stdslog.Attr) bool {
+		addAttr(current, attr)
+		return true
+	})
+	pruneEmpty(root)
+	return root
+}
+
+func addAttr(dst map[string]interface{}, attr stdslog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(stdslog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() != stdslog.KindGroup {
+		dst[attr.Key] = attrValue(attr.Value)
+		return
+	}
+	attrs := attr.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+	group := dst
+	if attr.Key != "" {
+		group = make(map[string]interface{}, len(attrs))
+		dst[attr.Key] = group
+	}
+	for _, a := range attrs {
+		addAttr(group, a)
+	}
+}
+
+func attrValue(value stdslog.Value) interface{} {
+	switch value.Kind() {
+	case stdslog.KindString:
+		return value.String()
+	case stdslog.KindInt64:
+		return value.Int64()
+	case stdslog.KindUint64:
+		return value.Uint64()
+	case stdslog.KindFloat64:
+		return value.Float64()
+	case stdslog.KindBool:
+		return value.Bool()
+	case stdslog.KindDuration:
+		return value.Duration().String()
+	case stdslog.KindTime:
+		return value.Time().UTC().Format(time.RFC3339Nano)
+	}
+	if err, ok := value.Any().(error); ok {
+		return err.Error()
+	}
+	return value.Any()
+}
+
+// pruneEmpty removes nested groups that ended up without attributes.
+func pruneEmpty(fields map[string]interface{}) bool {
+	for key, value := range fields {
+		if group, ok := value.(map[string]interface{}); ok && pruneEmpty(group) {
+			delete(fields, key)
+		}
+	}
+	return len(fields) == 0
+}
+
+// levelName maps slog levels, including custom ones in between, to Clockwork log levels.
+func levelName(level stdslog.Level) string {
+	switch {
+	case level < stdslog.LevelInfo:
+		return "debug"
+	case level < stdslog.LevelWarn:
+		return "info"
+	case level < stdslog.LevelError:
+		return "warning"
+	}
+	return "error"
+}
+
+// buildLogTrace returns the frame at pc followed by its callers when Handle runs on the
+// logging goroutine, or only that frame otherwise. It returns nil when pc is unknown, so
+// the collector captures the current stack instead.
+func buildLogTrace(pc uintptr) []clockwork.LogTraceFrame {
+	if pc == 0 {
+		return nil
+	}
+	source, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if source.File == "" {
+		return nil
+	}
+	frames := make([]clockwork.LogTraceFrame, 0, clockwork.MaxLogTraceFrames)
+	frames = append(frames, traceFrame(source))
+
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	callers := runtime.CallersFrames(pcs[:n])
+	found := false
+	for len(frames) < clockwork.MaxLogTraceFrames {
+		frame, more := callers.Next()
+		if found && frame.File != "" {
+			frames = append(frames, traceFrame(frame))
+		} else if frame.Function == source.Function && frame.File == source.File && frame.Line == source.Line {
+			found = true
+		}
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+func traceFrame(frame runtime.Frame) clockwork.LogTraceFrame {
+	return clockwork.LogTraceFrame{Call: frame.Function, File: frame.File, Line: frame.Line}
+}
@@ -0,0 +1,119 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	stdslog "log/slog"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(t *testing.T, level stdslog.Level) (*stdslog.Logger, *bytes.Buffer, *clockwork.Collector, context.Context) {
+	t.Helper()
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+	collector := cw.NewCollector("GET", "/orders")
+
+	var out bytes.Buffer
+	handler := WrapHandler(stdslog.NewJSONHandler(&out, &stdslog.HandlerOptions{Level: level}), cw)
+	return stdslog.New(handler), &out, collector, clockwork.ContextWithCollector(context.Background(), collector)
+}
+
+func TestHandler_RecordsLevelsAndAttrs(t *testing.T) {
+	logger, out, collector, ctx := newTestLogger(t, stdslog.LevelInfo)
+
+	logger.DebugContext(ctx, "below level")
+	logger.InfoContext(ctx, "order placed", "order_id", "o-1", "items", 3, "elapsed", 1500*time.Millisecond)
+	logger.Log(ctx, stdslog.LevelWarn+2, "slow order", "error", errors.New("timeout"))
+	logger.ErrorContext(ctx, "order failed")
+	logger.Info("without request context")
+
+	require.Contains(t, out.String(), `"msg":"without request context"`)
+	entries := collector.GetMetadata().LogEntries
+	require.Len(t, entries, 3)
+	require.Equal(t, "info", entries[0].Level)
+	require.Equal(t, map[string]interface{}{"order_id": "o-1", "items": int64(3), "elapsed": "1.5s"}, entries[0].Context)
+	require.Equal(t, "warning", entries[1].Level)
+	require.Equal(t, "timeout", entries[1].Context["error"])
+	require.Equal(t, "error", entries[2].Level)
+	require.Nil(t, entries[2].Context)
+}
+
+func TestHandler_NestsGroups(t *testing.T) {
+	logger, _, collector, ctx := newTestLogger(t, stdslog.LevelInfo)
+
+	logger.With("service", "orders").
+		WithGroup("request").With("method", "GET").
+		WithGroup("db").
+		InfoContext(ctx, "query", "table", "orders", stdslog.Group("timing", "rows", 2))
+	logger.InfoContext(ctx, "inline", stdslog.Group("", "flat", true))
+
+	entries := collector.GetMetadata().LogEntries
+	require.Len(t, entries, 2)
+	require.Equal(t, map[string]interface{}{
+		"service": "orders",
+		"request": map[string]interface{}{
+			"method": "GET",
+			"db": map[string]interface{}{
+				"table":  "orders",
+				"timing": map[string]interface{}{"rows": int64(2)},
+			},
+		},
+	}, entries[0].Context)
+	require.Equal(t, map[string]interface{}{"flat": true}, entries[1].Context)
+}
+
+func TestHandler_PrunesEmptyGroups(t *testing.T) {
+	logger, _, collector, ctx := newTestLogger(t, stdslog.LevelInfo)
+
+	logger.With("service", "orders").WithGroup("request").WithGroup("db").
+		InfoContext(ctx, "no attrs", stdslog.Group("empty"), stdslog.Attr{})
+
+	entries := collector.GetMetadata().LogEntries
+	require.Len(t, entries, 1)
+	require.Equal(t, map[string]interface{}{"service": "orders"}, entries[0].Context)
+}
+
+func TestHandler_TracesCallerAndItsCallers(t *testing.T) {
+	logger, _, collector, ctx := newTestLogger(t, stdslog.LevelInfo)
+
+	logger.InfoContext(ctx, "traced")
+
+	trace := collector.GetMetadata().LogEntries[0].Trace
+	require.GreaterOrEqual(t, len(trace), 2)
+	require.Contains(t, trace[0].File, "slog_test.go")
+	require.Contains(t, trace[0].Call, "TestHandler_TracesCallerAndItsCallers")
+	require.Equal(t, "testing.tRunner", trace[1].Call)
+}
+
+func TestHandler_TracesOnlyCallerOffItsStack(t *testing.T) {
+	logger, _, collector, ctx := newTestLogger(t, stdslog.LevelInfo)
+
+	record := recordFromHelper()
+	require.NoError(t, logger.Handler().Handle(ctx, record))
+
+	trace := collector.GetMetadata().LogEntries[0].Trace
+	require.Len(t, trace, 1)
+	require.Contains(t, trace[0].Call, "recordFromHelper")
+}
+
+// recordFromHelper returns a record whose PC points into a function that has returned
+// by the time the record is handled, as with records handled on another goroutine.
+func recordFromHelper() stdslog.Record {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	return stdslog.NewRecord(time.Now(), stdslog.LevelInfo, "queued", pcs[0])
+}
+
+func TestWrapHandler_Disabled(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.Enabled = false
+	handler := stdslog.NewTextHandler(&bytes.Buffer{}, nil)
+	require.Same(t, handler, WrapHandler(handler, clockwork.NewClockwork(cfg, nil)))
+}
@@ -0,0 +1,460 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+)
+
+// callerSkip lists function prefixes skipped when attributing a statement to application code.
+var callerSkip = []string{"database/sql.", "github.com/RezaKargar/go-clockwork/"}
+
+// Open opens a database like sql.Open with every statement recorded through observer.
+// The connection name shown in Clockwork is the database name from dsn, or driverName.
+func Open(driverName, dsn string, observer *Observer) (*stdsql.DB, error) {
+	db, err := stdsql.Open(driverName, dsn)
+	if err != nil || observer == nil {
+		return db, err
+	}
+	d := db.Driver()
+	_ = db.Close()
+
+	name := ConnectionName(driverName, dsn)
+	dialect := DialectForDriver(driverName)
+	if dc, ok := d.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return stdsql.OpenDB(&wrappedConnector{parent: connector, observer: observer, name: name, dialect: dialect}), nil
+	}
+	return stdsql.OpenDB(dsnConnector{dsn: dsn, driver: &wrappedDriver{parent: d, observer: observer, name: name, dialect: dialect}}), nil
+}
+
+// WrapDriver returns a driver.Driver whose connections record statements through observer
+// under the given connection name. A nil observer returns d unchanged.
+func WrapDriver(d driver.Driver, observer *Observer, name string) driver.Driver {
+	if d == nil || observer == nil {
+		return d
+	}
+	if name == "" {
+		name = "sql"
+	}
+	return &wrappedDriver{parent: d, observer: observer, name: name, dialect: dialectForType(d)}
+}
+
+// WrapConnector returns a driver.Connector for sql.OpenDB whose connections record
+// statements through observer under the given connection name. A nil observer returns c unchanged.
+func WrapConnector(c driver.Connector, observer *Observer, name string) driver.Connector {
+	if c == nil || observer == nil {
+		return c
+	}
+	if name == "" {
+		name = "sql"
+	}
+	return &wrappedConnector{parent: c, observer: observer, name: name, dialect: dialectForType(c.Driver())}
+}
+
+// DialectForDriver maps a database/sql driver name (or driver package path) to the
+// clockwork dialect used to render runnable queries; unknown drivers return "".
+func DialectForDriver(driverName string) string {
+	name := strings.ToLower(driverName)
+	switch {
+	case strings.Contains(name, "pgx"), strings.Contains(name, "postgres"), strings.Contains(name, "lib/pq"), name == "pq":
+		return clockwork.DialectPostgres
+	case strings.Contains(name, "mysql"):
+		return clockwork.DialectMySQL
+	case strings.Contains(name, "sqlite"):
+		return clockwork.DialectSQLite
+	case strings.Contains(name, "sqlserver"), strings.Contains(name, "mssql"):
+		return clockwork.DialectSQLServer
+	}
+	return ""
+}
+
+// dialectForType guesses the dialect from the package implementing d.
+func dialectForType(d driver.Driver) string {
+	t := reflect.TypeOf(d)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return DialectForDriver(t.PkgPath())
+}
+
+// ConnectionName derives a connection name from a DSN: the database name for URL
+// ("postgres://host/db"), key-value ("dbname=db") and MySQL ("user@tcp(host)/db") forms,
+// the file name for SQLite paths, otherwise driverName.
+func ConnectionName(driverName, dsn string) string {
+	dsn = strings.TrimSpace(dsn)
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.Host != "" {
+		if db := strings.Trim(u.Path, "/"); db != "" {
+			return db
+		}
+		if db := u.Query().Get("database"); db != "" {
+			return db
+		}
+	}
+	for _, field := range strings.Fields(dsn) {
+		if key, value, ok := strings.Cut(field, "="); ok && (key == "dbname" || key == "database") {
+			return strings.Trim(value, `'"`)
+		}
+	}
+	for _, field := range strings.Split(dsn, ";") {
+		if key, value, ok := strings.Cut(field, "="); ok && strings.EqualFold(strings.TrimSpace(key), "database") {
+			return strings.TrimSpace(value)
+		}
+	}
+	if at := strings.LastIndex(dsn, ")/"); at >= 0 {
+		db, _, _ := strings.Cut(dsn[at+2:], "?")
+		if db != "" {
+			return db
+		}
+	}
+	if path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?"); path != "" && !strings.ContainsAny(path, "=@ ") {
+		if base := filepath.Base(path); base != "." && base != string(filepath.Separator) {
+			return base
+		}
+	}
+	if driverName != "" {
+		return driverName
+	}
+	return "sql"
+}
+
+type wrappedDriver struct {
+	parent   driver.Driver
+	observer *Observer
+	name     string
+	dialect  string
+}
+
+func (d *wrappedDriver) Open(dsn string) (driver.Conn, error) {
+	c, err := d.parent.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{parent: c, observer: d.observer, name: d.name, dialect: d.dialect}, nil
+}
+
+func (d *wrappedDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	if dc, ok := d.parent.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &wrappedConnector{parent: c, observer: d.observer, name: d.name, dialect: d.dialect}, nil
+	}
+	return dsnConnector{dsn: dsn, driver: d}, nil
+}
+
+type wrappedConnector struct {
+	parent   driver.Connector
+	observer *Observer
+	name     string
+	dialect  string
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.parent.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{parent: conn, observer: c.observer, name: c.name, dialect: c.dialect}, nil
+}
+
+func (c *wrappedConnector) Driver() driver.Driver {
+	return &wrappedDriver{parent: c.parent.Driver(), observer: c.observer, name: c.name, dialect: c.dialect}
+}
+
+// dsnConnector adapts a driver without DriverContext to sql.OpenDB.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+
+func (c dsnConnector) Driver() driver.Driver { return c.driver }
+
+type wrappedConn struct {
+	parent   driver.Conn
+	observer *Observer
+	name     string
+	dialect  string
+}
+
+// record reports a statement to the request collector in ctx, if any.
+func (c *wrappedConn) record(ctx context.Context, query string, args []driver.NamedValue, start time.Time, rows int64, err error) {
+	if errors.Is(err, driver.ErrSkip) || clockwork.CollectorFromContext(ctx) == nil {
+		return
+	}
+	duration := time.Since(start)
+	file, line := clockwork.CallerOutside(callerSkip...)
+	c.observer.OnQuery(ctx, Observation{
+		Query:        query,
+		Duration:     duration,
+		Connection:   c.name,
+		File:         file,
+		Line:         line,
+		RowsAffected: rows,
+		Err:          err,
+		Args:         argValues(args),
+		Dialect:      c.dialect,
+	})
+}
+
+func (c *wrappedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *wrappedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if pc, ok := c.parent.(driver.ConnPrepareContext); ok {
+		stmt, err = pc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.parent.Prepare(query)
+	}
+	if err != nil {
+		c.record(ctx, query, nil, start, 0, err)
+		return nil, err
+	}
+	return wrapStmt(&wrappedStmt{parent: stmt, conn: c, query: query}), nil
+}
+
+func (c *wrappedConn) Close() error {
+	return c.parent.Close()
+}
+
+func (c *wrappedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if bc, ok := c.parent.(driver.ConnBeginTx); ok {
+		tx, err = bc.BeginTx(ctx, opts)
+	} else if opts.Isolation != driver.IsolationLevel(stdsql.LevelDefault) || opts.ReadOnly {
+		err = errors.New("sql: driver does not support non-default isolation level or read-only transactions")
+	} else {
+		tx, err = c.parent.Begin()
+	}
+	c.record(ctx, "BEGIN", nil, start, 0, err)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedTx{parent: tx, conn: c, ctx: ctx}, nil
+}
+
+func (c *wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.parent.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.record(ctx, query, args, start, rowsAffected(result, err), err)
+	return result, err
+}
+
+func (c *wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.parent.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.record(ctx, query, args, start, 0, err)
+	return rows, err
+}
+
+func (c *wrappedConn) Ping(ctx context.Context) error {
+	if p, ok := c.parent.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.parent.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) IsValid() bool {
+	if v, ok := c.parent.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *wrappedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.parent.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type wrappedTx struct {
+	parent driver.Tx
+	conn   *wrappedConn
+	ctx    context.Context
+}
+
+func (t *wrappedTx) Commit() error {
+	start := time.Now()
+	err := t.parent.Commit()
+	t.conn.record(t.ctx, "COMMIT", nil, start, 0, err)
+	return err
+}
+
+func (t *wrappedTx) Rollback() error {
+	start := time.Now()
+	err := t.parent.Rollback()
+	t.conn.record(t.ctx, "ROLLBACK", nil, start, 0, err)
+	return err
+}
+
+type wrappedStmt struct {
+	parent driver.Stmt
+	conn   *wrappedConn
+	query  string
+}
+
+// converterStmt is a wrappedStmt whose parent implements driver.ColumnConverter.
+// database/sql falls back to the converter when CheckNamedValue returns driver.ErrSkip.
+type converterStmt struct {
+	*wrappedStmt
+}
+
+func (s converterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return s.parent.(driver.ColumnConverter).ColumnConverter(idx)
+}
+
+// wrapStmt exposes driver.ColumnConverter only when the parent statement implements it.
+func wrapStmt(s *wrappedStmt) driver.Stmt {
+	if _, ok := s.parent.(driver.ColumnConverter); ok {
+		return converterStmt{s}
+	}
+	return s
+}
+
+func (s *wrappedStmt) Close() error {
+	return s.parent.Close()
+}
+
+func (s *wrappedStmt) NumInput() int {
+	return s.parent.NumInput()
+}
+
+func (s *wrappedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *wrappedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamed(args))
+}
+
+func (s *wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var (
+		result driver.Result
+		err    error
+	)
+	if ec, ok := s.parent.(driver.StmtExecContext); ok {
+		result, err = ec.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedToValues(args); err == nil {
+			result, err = s.parent.Exec(values)
+		}
+	}
+	s.conn.record(ctx, s.query, args, start, rowsAffected(result, err), err)
+	return result, err
+}
+
+func (s *wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if qc, ok := s.parent.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedToValues(args); err == nil {
+			rows, err = s.parent.Query(values)
+		}
+	}
+	s.conn.record(ctx, s.query, args, start, 0, err)
+	return rows, err
+}
+
+func (s *wrappedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.parent.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// argValues returns the bound values in ordinal order.
+func argValues(args []driver.NamedValue) []interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+func rowsAffected(result driver.Result, err error) int64 {
+	if err != nil || result == nil {
+		return 0
+	}
+	n, rerr := result.RowsAffected()
+	if rerr != nil {
+		return 0
+	}
+	return n
+}
+
+func valuesToNamed(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+func namedToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
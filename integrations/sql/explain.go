@@ -0,0 +1,145 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"strings"
+	"time"
+
+	"github.com/RezaKargar/go-clockwork"
+)
+
+// defaultExplainTimeout bounds each EXPLAIN when Explainer.Timeout is zero.
+const defaultExplainTimeout = 5 * time.Second
+
+// Explainer fetches query plans for slow SELECT statements. Plans are fetched after the
+// handler returns and attached to the query before the request is saved.
+type Explainer struct {
+	// DB runs the EXPLAIN statements. It may be the instrumented database; plan queries are not recorded.
+	DB *stdsql.DB
+	// Dialect is used when the observation carries none (clockwork.DialectPostgres, DialectMySQL or DialectSQLite).
+	Dialect string
+	// Analyze runs EXPLAIN ANALYZE, which executes the query again. Postgres and MySQL only.
+	// Side effects of the query, such as volatile functions that write, run a second time;
+	// the plan is fetched in a transaction that is always rolled back, which does not undo
+	// non-transactional effects (sequences, MyISAM tables, external calls).
+	Analyze bool
+	// JSON requests the plan as JSON. Postgres and MySQL only.
+	JSON bool
+	// Timeout bounds each EXPLAIN; defaults to 5s.
+	Timeout time.Duration
+}
+
+// SetExplainer enables plan capture for slow SELECT queries. Pass nil to disable it.
+func (o *Observer) SetExplainer(e *Explainer) {
+	if o == nil {
+		return
+	}
+	o.explainer = e
+}
+
+// explainFunc returns the plan fetcher for query, or nil when it should not be explained.
+func (e *Explainer) explainFunc(query string, args []interface{}, dialect string) clockwork.ExplainFunc {
+	if e == nil || e.DB == nil {
+		return nil
+	}
+	if dialect == "" {
+		dialect = e.Dialect
+	}
+	if !clockwork.ParseSQLDialect(query, dialect).ReadOnly {
+		return nil
+	}
+	statement := e.statement(query, dialect)
+	if statement == "" {
+		return nil
+	}
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = defaultExplainTimeout
+	}
+	return func(ctx context.Context) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if !e.Analyze {
+			return queryPlan(ctx, e.DB, statement, args)
+		}
+		tx, err := e.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = tx.Rollback() }()
+		return queryPlan(ctx, tx, statement, args)
+	}
+}
+
+// statement builds the EXPLAIN statement for dialect, or "" when the dialect is not supported.
+func (e *Explainer) statement(query, dialect string) string {
+	switch dialect {
+	case clockwork.DialectPostgres:
+		var options []string
+		if e.Analyze {
+			options = append(options, "ANALYZE")
+		}
+		if e.JSON {
+			options = append(options, "FORMAT JSON")
+		}
+		if len(options) == 0 {
+			return "EXPLAIN " + query
+		}
+		return "EXPLAIN (" + strings.Join(options, ", ") + ") " + query
+	case clockwork.DialectMySQL:
+		switch {
+		case e.Analyze:
+			return "EXPLAIN ANALYZE " + query
+		case e.JSON:
+			return "EXPLAIN FORMAT=JSON " + query
+		}
+		return "EXPLAIN " + query
+	case clockwork.DialectSQLite:
+		return "EXPLAIN QUERY PLAN " + query
+	}
+	return ""
+}
+
+// queryer is a *sql.DB or *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*stdsql.Rows, error)
+}
+
+// queryPlan runs statement and formats its rows as text. Single-column plans are returned
+// line by line; wider results get a header row and tab-separated columns.
+func queryPlan(ctx context.Context, db queryer, statement string, args []interface{}) (string, error) {
+	rows, err := db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	if len(columns) > 1 {
+		lines = append(lines, strings.Join(columns, "\t"))
+	}
+	values := make([]stdsql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	cells := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		for i, v := range values {
+			cells[i] = v.String
+		}
+		lines = append(lines, strings.Join(cells, "\t"))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,51 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainer_SkipsStatementsWithSideEffects(t *testing.T) {
+	explainer := &Explainer{DB: &stdsql.DB{}, Dialect: clockwork.DialectPostgres, Analyze: true}
+
+	for _, query := range []string{
+		"UPDATE users SET active = true",
+		"SELECT * FROM users WHERE id = $1 FOR UPDATE",
+		"SELECT * FROM users FOR KEY SHARE",
+		"SELECT * INTO users_copy FROM users",
+		"WITH d AS (DELETE FROM users RETURNING id) SELECT * FROM d",
+	} {
+		require.Nil(t, explainer.explainFunc(query, nil, ""), query)
+	}
+	require.Nil(t, explainer.explainFunc(`SELECT * FROM users WHERE name = 'o\'neil' LOCK IN SHARE MODE`, nil, clockwork.DialectMySQL))
+	require.NotNil(t, explainer.explainFunc("SELECT * FROM users WHERE id = $1", nil, ""))
+	require.Nil(t, explainer.explainFunc("SELECT 1", nil, "mssql"), "unsupported dialect")
+}
+
+func TestExplainer_AnalyzeRollsBack(t *testing.T) {
+	d := &fakeDriver{contextConn: true}
+	db := stdsql.OpenDB(fakeConnector{driver: d})
+	defer func() { _ = db.Close() }()
+	query := "SELECT * FROM users WHERE id = $1"
+
+	explainer := &Explainer{DB: db, Dialect: clockwork.DialectPostgres}
+	plan, err := explainer.explainFunc(query, []interface{}{7}, "")(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "1", plan)
+	require.Zero(t, d.rollbacks)
+
+	explainer.Analyze = true
+	_, err = explainer.explainFunc(query, []interface{}{7}, "")(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, d.rollbacks)
+
+	require.Equal(t, []fakeCall{
+		{query: "EXPLAIN " + query, args: []driver.Value{int64(7)}},
+		{query: "EXPLAIN (ANALYZE) " + query, args: []driver.Value{int64(7)}},
+	}, d.executed)
+}
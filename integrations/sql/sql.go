@@ -16,12 +16,18 @@ type Observation struct {
 	Model      string // table or model name; auto-extracted from SQL if empty
 	File       string // caller file path
 	Line       int    // caller line number
+
+	RowsAffected int64         // rows affected by an Exec, if known
+	Err          error         // error returned by the statement
+	Args         []interface{} // bound arguments, in placeholder order
+	Dialect      string        // clockwork.DialectPostgres etc.; enables the runnable query
 }
 
 // Observer forwards SQL observations to Clockwork collectors.
 type Observer struct {
 	cw                 *clockwork.Clockwork
 	slowQueryThreshold time.Duration
+	explainer          *Explainer
 }
 
 // NewObserver creates a SQL observer that records queries to the active request collector.
@@ -56,8 +62,19 @@ func (o *Observer) OnQuery(ctx context.Context, observation Observation) {
 	}
 
 	slow := observation.Duration > o.slowQueryThreshold
+	var explain clockwork.ExplainFunc
+	if slow && observation.Err == nil {
+		explain = o.explainer.explainFunc(query, observation.Args, observation.Dialect)
+	}
 	collector.AddDatabaseQueryDetailed(
 		query, observation.Duration, conn, slow,
 		observation.Model, observation.File, observation.Line,
+		clockwork.QueryDetails{
+			RowsAffected: observation.RowsAffected,
+			Err:          observation.Err,
+			Bindings:     observation.Args,
+			Dialect:      observation.Dialect,
+			Explain:      explain,
+		},
 	)
 }
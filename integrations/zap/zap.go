@@ -1,34 +1,69 @@
 package zap
 
 import (
+	"context"
 	"fmt"
 	"math"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/RezaKargar/go-clockwork"
+	"go.opentelemetry.io/otel/trace"
+	uberzap "go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-const maxLogTraceFrames = 12
+// requestKey names the field added by Request. It is a skip field, so encoders leave it out.
+const requestKey = "clockwork"
 
 // Core wraps a zap core and mirrors correlated logs into Clockwork collectors.
 type Core struct {
 	underlying zapcore.Core
 	cw         *clockwork.Clockwork
+	minLevel   zapcore.LevelEnabler
 	fields     []zapcore.Field
 }
 
 // WrapCore wraps a zap core for Clockwork log collection.
 func WrapCore(core zapcore.Core, cw *clockwork.Clockwork) zapcore.Core {
+	return WrapCoreLevel(core, cw, nil)
+}
+
+// WrapCoreLevel is like WrapCore but only mirrors entries enabled by minLevel (e.g.
+// zapcore.WarnLevel) into Clockwork; the underlying core still receives every entry.
+// A nil minLevel mirrors all entries.
+func WrapCoreLevel(core zapcore.Core, cw *clockwork.Clockwork, minLevel zapcore.LevelEnabler) zapcore.Core {
 	if core == nil || cw == nil || !cw.IsEnabled() {
 		return core
 	}
 	return &Core{
 		underlying: core,
 		cw:         cw,
+		minLevel:   minLevel,
+	}
+}
+
+// Request returns a field that ties entries to the collector of ctx, so they are
+// recorded on that request even while others are active. The field is not encoded.
+// It is a no-op when ctx carries no collector.
+//
+//	logger.Info("order placed", cwzap.Request(r.Context()), zap.String("order_id", id))
+func Request(ctx context.Context) zapcore.Field {
+	collector := clockwork.CollectorFromContext(ctx)
+	if collector == nil {
+		return uberzap.Skip()
 	}
+	return zapcore.Field{Key: requestKey, Type: zapcore.SkipType, Interface: collector}
+}
+
+// ContextLogger returns logger with Request(ctx) attached, for passing down a request's
+// call chain. It returns logger unchanged when ctx carries no collector.
+func ContextLogger(ctx context.Context, logger *uberzap.Logger) *uberzap.Logger {
+	collector := clockwork.CollectorFromContext(ctx)
+	if logger == nil || collector == nil {
+		return logger
+	}
+	return logger.With(zapcore.Field{Key: requestKey, Type: zapcore.SkipType, Interface: collector})
 }
 
 func (c *Core) Enabled(level zapcore.Level) bool {
@@ -39,6 +74,7 @@ func (c *Core) With(fields []zapcore.Field) zapcore.Core {
 	next := &Core{
 		underlying: c.underlying.With(fields),
 		cw:         c.cw,
+		minLevel:   c.minLevel,
 		fields:     append(append(make([]zapcore.Field, 0, len(c.fields)+len(fields)), c.fields...), fields...),
 	}
 	return next
@@ -51,16 +87,28 @@ func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcor
 	return checked.AddCore(entry, c)
 }
 
+// Write passes the entry on and records it on the request it belongs to: the collector
+// from a Request field, else the request registered for its span or trace ID (trace_id
+// and span_id string fields or OpenTelemetry trace.TraceID, trace.SpanID and
+// trace.SpanContext values), else the single active request.
 func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 	err := c.underlying.Write(entry, fields)
 
-	if c.cw == nil || !c.cw.HasActiveTraces() {
+	if c.cw == nil || (c.minLevel != nil && !c.minLevel.Enabled(entry.Level)) {
+		return err
+	}
+
+	collector := findCollector(fields)
+	if collector == nil {
+		collector = findCollector(c.fields)
+	}
+	if collector == nil && !c.cw.HasActiveTraces() {
 		return err
 	}
 
-	traceID := findTraceID(c.fields)
-	if traceID == "" {
-		traceID = findTraceID(fields)
+	traceID, spanID := findTrace(fields)
+	if traceID == "" && spanID == "" {
+		traceID, spanID = findTrace(c.fields)
 	}
 
 	contextFields := make(map[string]interface{}, 8)
@@ -75,12 +123,16 @@ func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 
 	traceFrames := buildLogTrace(entry)
 
-	if traceID == "" {
+	if collector != nil {
+		collector.AddLogEntryWithTrace(entry.Level.String(), entry.Message, contextFields, traceFrames)
+		return err
+	}
+	if traceID == "" && spanID == "" {
 		c.cw.RecordLogForSingleActiveWithTrace(entry.Level.String(), entry.Message, contextFields, traceFrames)
 		return err
 	}
 
-	c.cw.RecordLogForTraceWithTrace(traceID, entry.Level.String(), entry.Message, contextFields, traceFrames)
+	c.cw.RecordLogForSpanWithTrace(traceID, spanID, entry.Level.String(), entry.Message, contextFields, traceFrames)
 	return err
 }
 
@@ -88,13 +140,48 @@ func (c *Core) Sync() error {
 	return c.underlying.Sync()
 }
 
-func findTraceID(fields []zapcore.Field) string {
+func findCollector(fields []zapcore.Field) *clockwork.Collector {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Type != zapcore.SkipType {
+			continue
+		}
+		if collector, ok := fields[i].Interface.(*clockwork.Collector); ok && collector != nil {
+			return collector
+		}
+	}
+	return nil
+}
+
+// findTrace returns the trace and span IDs carried by fields, if any.
+func findTrace(fields []zapcore.Field) (traceID, spanID string) {
 	for i := range fields {
-		if fields[i].Key == "trace_id" && fields[i].Type == zapcore.StringType {
-			return fields[i].String
+		switch v := fields[i].Interface.(type) {
+		case trace.TraceID:
+			if v.IsValid() {
+				traceID = v.String()
+			}
+		case trace.SpanID:
+			if v.IsValid() {
+				spanID = v.String()
+			}
+		case trace.SpanContext:
+			if v.HasTraceID() {
+				traceID = v.TraceID().String()
+			}
+			if v.HasSpanID() {
+				spanID = v.SpanID().String()
+			}
+		}
+		if fields[i].Type == zapcore.StringType {
+			switch fields[i].Key {
+			case "trace_id":
+				traceID = fields[i].String
+			case "span_id":
+				spanID = fields[i].String
+			}
 		}
 	}
-	return ""
+	return traceID, spanID
 }
 
 func appendFields(dst map[string]interface{}, fields []zapcore.Field) {
@@ -106,7 +193,7 @@ func appendFields(dst map[string]interface{}, fields []zapcore.Field) {
 			return
 		}
 		field := fields[i]
-		if field.Key == "trace_id" {
+		if field.Key == "trace_id" || field.Type == zapcore.SkipType {
 			continue
 		}
 		if value, ok := fieldValue(field); ok {
@@ -179,13 +266,12 @@ func toCompactString(v interface{}) string {
 }
 
 func buildLogTrace(entry zapcore.Entry) []clockwork.LogTraceFrame {
-	frames := make([]clockwork.LogTraceFrame, 0, maxLogTraceFrames)
+	frames := make([]clockwork.LogTraceFrame, 0, clockwork.MaxLogTraceFrames)
 
 	if entry.Caller.Defined && entry.Caller.File != "" {
 		frames = append(frames, clockwork.LogTraceFrame{
-			File:     entry.Caller.File,
-			Line:     entry.Caller.Line,
-			IsVendor: isVendorPath(entry.Caller.File),
+			File: entry.Caller.File,
+			Line: entry.Caller.Line,
 		})
 	}
 
@@ -195,7 +281,7 @@ func buildLogTrace(entry zapcore.Entry) []clockwork.LogTraceFrame {
 	}
 
 	for _, frame := range parsed {
-		if len(frames) >= maxLogTraceFrames {
+		if len(frames) >= clockwork.MaxLogTraceFrames {
 			break
 		}
 		if isSameFrame(frame, frames) {
@@ -214,49 +300,28 @@ func parseZapStack(stack string) []clockwork.LogTraceFrame {
 	}
 
 	lines := strings.Split(stack, "\n")
-	frames := make([]clockwork.LogTraceFrame, 0, maxLogTraceFrames)
-	for i := 0; i < len(lines)-1 && len(frames) < maxLogTraceFrames; i++ {
+	frames := make([]clockwork.LogTraceFrame, 0, clockwork.MaxLogTraceFrames)
+	for i := 0; i < len(lines)-1 && len(frames) < clockwork.MaxLogTraceFrames; i++ {
 		call := strings.TrimSpace(lines[i])
 		if call == "" {
 			continue
 		}
 
 		fileLine := strings.TrimSpace(lines[i+1])
-		file, line, ok := parseFileLine(fileLine)
+		file, line, ok := clockwork.ParseFileLine(fileLine)
 		if !ok {
 			continue
 		}
 		frames = append(frames, clockwork.LogTraceFrame{
-			Call:     call,
-			File:     file,
-			Line:     line,
-			IsVendor: isVendorPath(file),
+			Call: call,
+			File: file,
+			Line: line,
 		})
 		i++
 	}
 	return frames
 }
 
-func parseFileLine(in string) (string, int, bool) {
-	if in == "" {
-		return "", 0, false
-	}
-
-	in = strings.TrimSuffix(in, ")")
-	idx := strings.LastIndex(in, ":")
-	if idx == -1 || idx == len(in)-1 {
-		return "", 0, false
-	}
-
-	file := in[:idx]
-	lineStr := in[idx+1:]
-	line, err := strconv.Atoi(lineStr)
-	if err != nil {
-		return "", 0, false
-	}
-	return file, line, true
-}
-
 func isSameFrame(candidate clockwork.LogTraceFrame, existing []clockwork.LogTraceFrame) bool {
 	for _, frame := range existing {
 		if frame.File == candidate.File && frame.Line == candidate.Line && frame.Call == candidate.Call {
@@ -265,11 +330,3 @@ func isSameFrame(candidate clockwork.LogTraceFrame, existing []clockwork.LogTrac
 	}
 	return false
 }
-
-func isVendorPath(path string) bool {
-	if path == "" {
-		return false
-	}
-	p := strings.ToLower(path)
-	return strings.Contains(p, "/vendor/") || strings.Contains(p, "/pkg/mod/")
-}
@@ -0,0 +1,136 @@
+package zap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	traceA = trace.TraceID{0xa1, 0x01}
+	spanA  = trace.SpanID{0xa1, 0x02}
+	traceB = trace.TraceID{0xb2, 0x01}
+	spanB  = trace.SpanID{0xb2, 0x02}
+)
+
+func newClockwork(t *testing.T) *clockwork.Clockwork {
+	t.Helper()
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	return clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+}
+
+func activeCollector(cw *clockwork.Clockwork, traceID trace.TraceID, spanID trace.SpanID) *clockwork.Collector {
+	collector := cw.NewCollector("GET", "/orders")
+	collector.SetTrace(traceID.String(), spanID.String())
+	cw.RegisterCollector(collector)
+	return collector
+}
+
+func newLogger(cw *clockwork.Clockwork, out *bytes.Buffer, minLevel zapcore.LevelEnabler) *uberzap.Logger {
+	encoder := zapcore.NewJSONEncoder(uberzap.NewProductionEncoderConfig())
+	core := zapcore.NewCore(encoder, zapcore.AddSync(out), zapcore.DebugLevel)
+	return uberzap.New(WrapCoreLevel(core, cw, minLevel))
+}
+
+func TestCore_RequestFieldPicksCollector(t *testing.T) {
+	cw := newClockwork(t)
+	first := activeCollector(cw, traceA, spanA)
+	second := activeCollector(cw, traceB, spanB)
+
+	var out bytes.Buffer
+	logger := newLogger(cw, &out, nil)
+	ctx := clockwork.ContextWithCollector(context.Background(), second)
+
+	logger.Info("order placed", Request(ctx), uberzap.String("order_id", "o-1"), uberzap.Int("items", 3))
+	ContextLogger(ctx, logger).Named("orders").Warn("order delayed", uberzap.Error(errors.New("carrier down")))
+	logger.Info("no collector", Request(context.Background()))
+
+	require.NotContains(t, out.String(), requestKey)
+	require.Contains(t, out.String(), `"msg":"no collector"`)
+	require.Empty(t, first.GetMetadata().LogEntries)
+	entries := second.GetMetadata().LogEntries
+	require.Len(t, entries, 2)
+	require.Equal(t, "info", entries[0].Level)
+	require.Equal(t, "o-1", entries[0].Context["order_id"])
+	require.Equal(t, int64(3), entries[0].Context["items"])
+	require.NotContains(t, entries[0].Context, requestKey)
+	require.Equal(t, "warn", entries[1].Level)
+	require.Equal(t, "carrier down", entries[1].Context["error"])
+	require.Equal(t, "orders", entries[1].Context["logger"])
+	require.EqualValues(t, 1, cw.CorrelationStats().UnattributedLogs)
+}
+
+func TestCore_RoutesByOTelFields(t *testing.T) {
+	cw := newClockwork(t)
+	first := activeCollector(cw, traceA, spanA)
+	second := activeCollector(cw, traceB, spanB)
+
+	logger := newLogger(cw, &bytes.Buffer{}, nil)
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceB, SpanID: spanB})
+
+	logger.Info("by ids", uberzap.Any("trace", traceA), uberzap.Any("span", spanA))
+	logger.With(uberzap.Any("span_context", spanContext)).Info("by span context")
+	logger.Info("by trace only", uberzap.Any("trace", traceA), uberzap.Any("span", trace.SpanID{0xff}))
+	logger.Info("by string fields", uberzap.String("trace_id", traceB.String()), uberzap.String("span_id", spanB.String()))
+	logger.Info("ambiguous")
+
+	firstEntries := first.GetMetadata().LogEntries
+	require.Len(t, firstEntries, 2)
+	require.Equal(t, "by ids", firstEntries[0].Message)
+	require.Equal(t, "by trace only", firstEntries[1].Message)
+	secondEntries := second.GetMetadata().LogEntries
+	require.Len(t, secondEntries, 2)
+	require.Equal(t, "by span context", secondEntries[0].Message)
+	require.Equal(t, "by string fields", secondEntries[1].Message)
+	require.NotContains(t, secondEntries[1].Context, "trace_id")
+	require.EqualValues(t, 1, cw.CorrelationStats().UnattributedLogs)
+}
+
+func TestWrapCoreLevel_FiltersMirroredEntries(t *testing.T) {
+	cw := newClockwork(t)
+	collector := activeCollector(cw, traceA, spanA)
+
+	var out bytes.Buffer
+	logger := newLogger(cw, &out, zapcore.WarnLevel)
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+
+	require.Contains(t, out.String(), `"msg":"debug"`)
+	require.Contains(t, out.String(), `"msg":"info"`)
+	entries := collector.GetMetadata().LogEntries
+	require.Len(t, entries, 2)
+	require.Equal(t, "warn", entries[0].Message)
+	require.Equal(t, "error", entries[1].Message)
+}
+
+func TestCore_RecordsCallerAndStack(t *testing.T) {
+	cw := newClockwork(t)
+	collector := activeCollector(cw, traceA, spanA)
+
+	logger := newLogger(cw, &bytes.Buffer{}, nil).WithOptions(uberzap.AddCaller(), uberzap.AddStacktrace(zapcore.ErrorLevel))
+	logger.Error("failed")
+
+	trace := collector.GetMetadata().LogEntries[0].Trace
+	require.GreaterOrEqual(t, len(trace), 2)
+	require.Contains(t, trace[0].File, "zap_test.go")
+	require.Empty(t, trace[0].Call)
+	require.Contains(t, trace[1].Call, "TestCore_RecordsCallerAndStack")
+	require.Equal(t, trace[0].Line, trace[1].Line)
+}
+
+func TestWrapCore_Disabled(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.Enabled = false
+	core := zapcore.NewNopCore()
+	require.Equal(t, core, WrapCore(core, clockwork.NewClockwork(cfg, nil)))
+}
@@ -0,0 +1,238 @@
+package zerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/RezaKargar/go-clockwork"
+	zl "github.com/rs/zerolog"
+)
+
+const (
+	maxContextFields = 20
+
+	// idField carries the collector ID from the hook to the writer, which looks it up with
+	// Clockwork.ActiveCollector. It is left in the output, linking log lines to their
+	// Clockwork request.
+	idField = "clockwork_id"
+)
+
+// Writer mirrors zerolog events into Clockwork collectors and writes them to an
+// underlying writer. Use it both as the logger's output and as its hook:
+//
+//	w := cwzerolog.NewWriter(os.Stdout, cw)
+//	logger := zerolog.New(w).Hook(w)
+//
+// Events whose context carries a collector (Event.Ctx or Logger.With().Ctx) go to that
+// collector. Other events are associated by their span_id and trace_id fields, or
+// attached to the single active request when exactly one is active.
+type Writer struct {
+	underlying io.Writer
+	cw         *clockwork.Clockwork
+}
+
+var (
+	_ zl.LevelWriter = (*Writer)(nil)
+	_ zl.Hook        = (*Writer)(nil)
+)
+
+// NewWriter wraps w. When Clockwork is disabled the writer only passes events on.
+func NewWriter(w io.Writer, cw *clockwork.Clockwork) *Writer {
+	if cw != nil && !cw.IsEnabled() {
+		cw = nil
+	}
+	return &Writer{underlying: w, cw: cw}
+}
+
+// Run implements zerolog.Hook by tagging events logged with a request context with the
+// collector ID, so Write can find the collector.
+func (w *Writer) Run(e *zl.Event, _ zl.Level, _ string) {
+	if w.cw == nil || !e.Enabled() {
+		return
+	}
+	if collector := clockwork.CollectorFromContext(e.GetCtx()); collector != nil {
+		e.Str(idField, collector.ID())
+	}
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.WriteLevel(zl.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter.
+func (w *Writer) WriteLevel(level zl.Level, p []byte) (int, error) {
+	var (
+		n   int
+		err error
+	)
+	if lw, ok := w.underlying.(zl.LevelWriter); ok {
+		n, err = lw.WriteLevel(level, p)
+	} else {
+		n, err = w.underlying.Write(p)
+	}
+
+	if w.cw != nil && w.cw.HasActiveTraces() {
+		w.record(level, p)
+	}
+	return n, err
+}
+
+func (w *Writer) record(level zl.Level, p []byte) {
+	event, keys, ok := decodeEvent(p)
+	if !ok {
+		return
+	}
+
+	var collector *clockwork.Collector
+	if id, ok := event[idField].(string); ok {
+		// An event of a request that has already completed is not attributed elsewhere.
+		if collector = w.cw.ActiveCollector(id); collector == nil {
+			return
+		}
+	}
+	traceID, _ := event["trace_id"].(string)
+	spanID, _ := event["span_id"].(string)
+
+	if level == zl.NoLevel {
+		if value, ok := event[zl.LevelFieldName].(string); ok {
+			if parsed, err := zl.ParseLevel(value); err == nil {
+				level = parsed
+			}
+		}
+	}
+	message, _ := event[zl.MessageFieldName].(string)
+	fields := contextFields(event, keys)
+	trace := buildLogTrace(event)
+
+	switch {
+	case collector != nil:
+		collector.AddLogEntryWithTrace(levelName(level), message, fields, trace)
+	case traceID != "" || spanID != "":
+		w.cw.RecordLogForSpanWithTrace(traceID, spanID, levelName(level), message, fields, trace)
+	default:
+		w.cw.RecordLogForSingleActiveWithTrace(levelName(level), message, fields, trace)
+	}
+}
+
+// decodeEvent decodes a JSON event and lists its keys in the order the logger wrote them.
+func decodeEvent(p []byte) (map[string]interface{}, []string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, false
+	}
+	event := make(map[string]interface{}, 8)
+	keys := make([]string, 0, 8)
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, nil, false
+		}
+		key, _ := tok.(string)
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, false
+		}
+		if _, dup := event[key]; !dup {
+			keys = append(keys, key)
+		}
+		event[key] = value
+	}
+	return event, keys, true
+}
+
+// contextFields keeps the first 20 event fields in logger order, leaving out those
+// Clockwork records separately.
+func contextFields(event map[string]interface{}, keys []string) map[string]interface{} {
+	fields := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if len(fields) >= maxContextFields {
+			break
+		}
+		switch key {
+		case idField, "trace_id", zl.LevelFieldName, zl.MessageFieldName, zl.CallerFieldName, zl.ErrorStackFieldName:
+			continue
+		}
+		fields[key] = fieldValue(event[key])
+	}
+	return fields
+}
+
+func fieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = fieldValue(item)
+		}
+		return out
+	case []interface{}:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+	return value
+}
+
+// levelName maps zerolog levels to Clockwork log levels.
+func levelName(level zl.Level) string {
+	switch level {
+	case zl.TraceLevel, zl.DebugLevel:
+		return "debug"
+	case zl.WarnLevel:
+		return "warning"
+	case zl.ErrorLevel:
+		return "error"
+	case zl.FatalLevel:
+		return "critical"
+	case zl.PanicLevel:
+		return "emergency"
+	}
+	return "info"
+}
+
+// buildLogTrace returns the caller field (file:line) followed by the frames of an error
+// stack field, as written by zerolog's pkgerrors marshaler.
+func buildLogTrace(event map[string]interface{}) []clockwork.LogTraceFrame {
+	frames := make([]clockwork.LogTraceFrame, 0, clockwork.MaxLogTraceFrames)
+	if caller, ok := event[zl.CallerFieldName].(string); ok {
+		if file, line, ok := clockwork.ParseFileLine(caller); ok {
+			frames = append(frames, clockwork.LogTraceFrame{File: file, Line: line})
+		}
+	}
+	stack, _ := event[zl.ErrorStackFieldName].([]interface{})
+	for _, item := range stack {
+		if len(frames) >= clockwork.MaxLogTraceFrames {
+			break
+		}
+		frame, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		call, _ := frame["func"].(string)
+		file, _ := frame["source"].(string)
+		line, _ := strconv.Atoi(stringValue(frame["line"]))
+		frames = append(frames, clockwork.LogTraceFrame{Call: call, File: file, Line: line})
+	}
+	return frames
+}
+
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
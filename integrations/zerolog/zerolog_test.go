@@ -0,0 +1,144 @@
+package zerolog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/RezaKargar/go-clockwork"
+	zl "github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func newClockwork(t *testing.T) *clockwork.Clockwork {
+	t.Helper()
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	return clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+}
+
+func activeCollector(cw *clockwork.Clockwork, traceID, spanID string) *clockwork.Collector {
+	collector := cw.NewCollector("GET", "/orders")
+	collector.SetTrace(traceID, spanID)
+	cw.RegisterCollector(collector)
+	return collector
+}
+
+func TestWriter_RecordsEventsWithRequestContext(t *testing.T) {
+	cw := newClockwork(t)
+	first := activeCollector(cw, "trace-1", "span-1")
+	second := activeCollector(cw, "trace-2", "span-2")
+
+	var out bytes.Buffer
+	w := NewWriter(&out, cw)
+	logger := zl.New(w).Hook(w)
+
+	ctx := clockwork.ContextWithCollector(context.Background(), second)
+	logger.Warn().Ctx(ctx).Str("order_id", "o-1").Int("items", 3).Msg("order placed")
+
+	require.Contains(t, out.String(), `"clockwork_id":"`+second.ID()+`"`)
+	require.Empty(t, first.GetMetadata().LogEntries)
+	entries := second.GetMetadata().LogEntries
+	require.Len(t, entries, 1)
+	require.Equal(t, "warning", entries[0].Level)
+	require.Equal(t, "order placed", entries[0].Message)
+	require.Equal(t, "o-1", entries[0].Context["order_id"])
+	require.Equal(t, int64(3), entries[0].Context["items"])
+	require.NotContains(t, entries[0].Context, idField)
+}
+
+func TestWriter_RoutesByTraceFields(t *testing.T) {
+	cw := newClockwork(t)
+	first := activeCollector(cw, "trace-1", "span-1")
+	second := activeCollector(cw, "trace-2", "span-2")
+
+	w := NewWriter(&bytes.Buffer{}, cw)
+	logger := zl.New(w).Hook(w)
+
+	logger.Info().Str("trace_id", "trace-1").Str("span_id", "span-1").Msg("to first")
+	logger.Info().Str("trace_id", "trace-2").Str("span_id", "child").Msg("to second by trace")
+	logger.Info().Msg("ambiguous")
+
+	require.Len(t, first.GetMetadata().LogEntries, 1)
+	require.Equal(t, "to first", first.GetMetadata().LogEntries[0].Message)
+	require.Len(t, second.GetMetadata().LogEntries, 1)
+	require.Equal(t, "to second by trace", second.GetMetadata().LogEntries[0].Message)
+	require.EqualValues(t, 1, cw.CorrelationStats().UnattributedLogs)
+}
+
+func TestWriter_DropsEventsOfCompletedRequests(t *testing.T) {
+	cw := newClockwork(t)
+	active := activeCollector(cw, "", "")
+	completed := cw.NewCollector("GET", "/done")
+
+	w := NewWriter(&bytes.Buffer{}, cw)
+	logger := zl.New(w).Hook(w)
+	logger.Info().Ctx(clockwork.ContextWithCollector(context.Background(), completed)).Msg("late")
+
+	require.Empty(t, active.GetMetadata().LogEntries, "not attributed to the single active request")
+	require.Empty(t, completed.GetMetadata().LogEntries)
+}
+
+func TestWriter_ParsesLevelOfNoLevelEvents(t *testing.T) {
+	cw := newClockwork(t)
+	collector := activeCollector(cw, "", "")
+
+	w := NewWriter(&bytes.Buffer{}, cw)
+	logger := zl.New(w).Hook(w)
+	logger.Log().Str(zl.LevelFieldName, "error").Msg("from Log")
+	_, err := w.Write([]byte(`{"level":"debug","message":"raw write"}` + "\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("not json\n"))
+	require.NoError(t, err)
+
+	entries := collector.GetMetadata().LogEntries
+	require.Len(t, entries, 2)
+	require.Equal(t, "error", entries[0].Level)
+	require.Equal(t, "from Log", entries[0].Message)
+	require.Equal(t, "debug", entries[1].Level)
+	require.Equal(t, "raw write", entries[1].Message)
+}
+
+func TestWriter_KeepsFirstFieldsInLoggerOrder(t *testing.T) {
+	cw := newClockwork(t)
+	collector := activeCollector(cw, "", "")
+
+	w := NewWriter(&bytes.Buffer{}, cw)
+	logger := zl.New(w).Hook(w)
+	event := logger.Info()
+	for i := 25; i > 0; i-- {
+		event = event.Int(fmt.Sprintf("f%02d", i), i)
+	}
+	event.Msg("many fields")
+
+	fields := collector.GetMetadata().LogEntries[0].Context
+	require.Len(t, fields, maxContextFields)
+	require.Contains(t, fields, "f25")
+	require.Contains(t, fields, "f06")
+	require.NotContains(t, fields, "f05")
+}
+
+func TestWriter_RecordsCallerAsTrace(t *testing.T) {
+	cw := newClockwork(t)
+	collector := activeCollector(cw, "", "")
+
+	w := NewWriter(&bytes.Buffer{}, cw)
+	logger := zl.New(w).Hook(w).With().Caller().Logger()
+	logger.Info().Msg("with caller")
+
+	trace := collector.GetMetadata().LogEntries[0].Trace
+	require.NotEmpty(t, trace)
+	require.Contains(t, trace[0].File, "zerolog_test.go")
+	require.Positive(t, trace[0].Line)
+}
+
+func TestNewWriter_DisabledPassesThrough(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.Enabled = false
+	var out bytes.Buffer
+	w := NewWriter(&out, clockwork.NewClockwork(cfg, nil))
+	logger := zl.New(w).Hook(w)
+	logger.Info().Msg("plain")
+	require.Contains(t, out.String(), `"message":"plain"`)
+}
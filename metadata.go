@@ -17,15 +17,57 @@ type Metadata struct {
 	Controller string            `json:"controller,omitempty"`
 	Headers    map[string]string `json:"headers,omitempty"`
 
+	// GetData holds query string parameters; repeated parameters become lists.
+	GetData map[string]interface{} `json:"getData,omitempty"`
+	// PostData holds decoded form and multipart fields; uploaded files are summarized.
+	PostData map[string]interface{} `json:"postData,omitempty"`
+	// RequestData holds a request body that is not form data: parsed JSON or raw text.
+	RequestData interface{} `json:"requestData,omitempty"`
+	// ResponseData holds the response body: parsed JSON or raw text.
+	ResponseData interface{} `json:"responseData,omitempty"`
+
+	Cookies           map[string]string      `json:"cookies,omitempty"`
+	SessionData       map[string]interface{} `json:"sessionData,omitempty"`
+	AuthenticatedUser *AuthenticatedUser     `json:"authenticatedUser,omitempty"`
+
 	TraceID string `json:"traceId,omitempty"`
 	SpanID  string `json:"spanId,omitempty"`
 
+	// Parent is the Clockwork ID of the upstream request that called this one.
+	Parent string `json:"parent,omitempty"`
+	// Profiles lists the pprof profiles (cpu, allocs) served at /__clockwork/:id/profile.
+	Profiles []string `json:"profiles,omitempty"`
+	// Subrequests link to captures made by downstream services during this request.
+	Subrequests []Subrequest `json:"subrequests,omitempty"`
+
 	DatabaseQueries      []DatabaseQuery `json:"databaseQueries"`
 	DatabaseQueriesCount int             `json:"databaseQueriesCount"`
 	DatabaseDuration     float64         `json:"databaseDuration"`
+	// QueryWarnings lists N+1 and duplicate query groups; each is also logged as a warning.
+	QueryWarnings []QueryWarning `json:"queryWarnings,omitempty"`
+
+	// ModelsActions lists ORM model events; the Models* maps count them per model,
+	// including events dropped from the list by MaxDatabaseQueries.
+	ModelsActions   []ModelAction  `json:"modelsActions,omitempty"`
+	ModelsRetrieved map[string]int `json:"modelsRetrieved,omitempty"`
+	ModelsCreated   map[string]int `json:"modelsCreated,omitempty"`
+	ModelsUpdated   map[string]int `json:"modelsUpdated,omitempty"`
+	ModelsDeleted   map[string]int `json:"modelsDeleted,omitempty"`
 
-	CacheQueries []CacheQuery `json:"cacheQueries"`
-	LogEntries   []LogEntry   `json:"log"`
+	CacheQueries []CacheQuery  `json:"cacheQueries"`
+	HTTPRequests []HTTPRequest `json:"httpRequests,omitempty"`
+	LogEntries   []LogEntry    `json:"log"`
+
+	// Cache* summarize cache operations, including those dropped from CacheQueries by
+	// MaxCacheQueries. CacheReads counts hits and misses; CacheTime is in milliseconds.
+	CacheReads   int     `json:"cacheReads"`
+	CacheHits    int     `json:"cacheHits"`
+	CacheWrites  int     `json:"cacheWrites"`
+	CacheDeletes int     `json:"cacheDeletes"`
+	CacheTime    float64 `json:"cacheTime"`
+
+	// RedisCommands lists Redis commands recorded by integrations/goredis.
+	RedisCommands []RedisCommand `json:"redisCommands,omitempty"`
 
 	TimelineEvents []TimelineEvent `json:"timelineData"`
 
@@ -37,6 +79,13 @@ type Metadata struct {
 	UserData map[string]interface{} `json:"userData,omitempty"`
 }
 
+// AuthenticatedUser identifies the user a request was made by.
+type AuthenticatedUser struct {
+	ID       string `json:"id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
 // DatabaseQuery represents a database query in Clockwork payload.
 type DatabaseQuery struct {
 	Query      string  `json:"query"`
@@ -47,14 +96,109 @@ type DatabaseQuery struct {
 	Line       int     `json:"line,omitempty"`
 	Slow       bool    `json:"slow"`
 	Timestamp  float64 `json:"timestamp"`
+	// Operation is the statement verb and Tables every table the query references,
+	// primary table first; both are parsed from Query.
+	Operation string   `json:"operation,omitempty"`
+	Tables    []string `json:"tables,omitempty"`
+	// Bindings are the query's bound arguments; Runnable is Query with them interpolated
+	// for the dialect given in QueryDetails.
+	Bindings []interface{} `json:"bindings,omitempty"`
+	Runnable string        `json:"runnable,omitempty"`
+	// RowsAffected is reported by Exec statements; Error is set when the statement failed.
+	RowsAffected int64  `json:"rowsAffected,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// Explain is the query plan fetched through QueryDetails.Explain.
+	Explain string `json:"explain,omitempty"`
+
+	// dialect selects string literal escaping when fingerprinting Query.
+	dialect string
+}
+
+// QueryDetails carries optional details for Collector.AddDatabaseQueryDetailed.
+type QueryDetails struct {
+	RowsAffected int64
+	Err          error
+	// Bindings are the bound arguments, in placeholder order.
+	Bindings []interface{}
+	// Dialect (DialectPostgres, DialectMySQL, DialectSQLite, DialectSQLServer) enables
+	// DatabaseQuery.Runnable; leave empty to record bindings only.
+	Dialect string
+	// Explain fetches the query plan once the handler has returned; the request is saved
+	// after it completes. At most 10 plans are fetched per request.
+	Explain ExplainFunc
+}
+
+// ModelAction represents an ORM model event in Clockwork payload. Count is a Go extension:
+// one statement can retrieve or change many rows of a model.
+type ModelAction struct {
+	Model      string      `json:"model"`
+	Action     string      `json:"action"`
+	Key        interface{} `json:"key,omitempty"`
+	Count      int         `json:"count,omitempty"`
+	Query      string      `json:"query,omitempty"`
+	Duration   float64     `json:"duration,omitempty"`
+	Connection string      `json:"connection,omitempty"`
+	File       string      `json:"file,omitempty"`
+	Line       int         `json:"line,omitempty"`
+	Timestamp  float64     `json:"time"`
 }
 
-// CacheQuery represents a cache operation in Clockwork payload.
+// CacheQuery represents a cache operation in Clockwork payload. Connection names the
+// cache store and Expiration is the TTL of a write in seconds; Size (value bytes) and
+// Error are Go extensions.
 type CacheQuery struct {
-	Type      string  `json:"type"`
-	Key       string  `json:"key"`
-	Duration  float64 `json:"duration"`
-	Timestamp float64 `json:"timestamp"`
+	Type       string  `json:"type"`
+	Key        string  `json:"key"`
+	Duration   float64 `json:"duration"`
+	Timestamp  float64 `json:"timestamp"`
+	Connection string  `json:"connection,omitempty"`
+	Expiration float64 `json:"expiration,omitempty"`
+	Size       int     `json:"size,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// RedisCommand represents a Redis command in Clockwork payload.
+type RedisCommand struct {
+	Command    string        `json:"command"`
+	Parameters []interface{} `json:"parameters,omitempty"`
+	Duration   float64       `json:"duration"`
+	Connection string        `json:"connection,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	File       string        `json:"file,omitempty"`
+	Line       int           `json:"line,omitempty"`
+	Timestamp  float64       `json:"time"`
+}
+
+// HTTPRequest represents an outgoing HTTP call in Clockwork payload.
+type HTTPRequest struct {
+	Request   HTTPRequestInfo  `json:"request"`
+	Response  HTTPResponseInfo `json:"response"`
+	Error     string           `json:"error,omitempty"`
+	Duration  float64          `json:"duration"`
+	Timestamp float64          `json:"time"`
+}
+
+// HTTPRequestInfo describes the request side of an outgoing HTTP call.
+type HTTPRequestInfo struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Size    int64             `json:"size"`
+}
+
+// HTTPResponseInfo describes the response side of an outgoing HTTP call.
+type HTTPResponseInfo struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Size    int64             `json:"size"`
+}
+
+// Subrequest links to a downstream service's capture: its metadata is served at
+// the origin of URL, under Path, with ID.
+type Subrequest struct {
+	URL  string `json:"url"`
+	ID   string `json:"id"`
+	Path string `json:"path"`
 }
 
 // LogEntry represents a log message in Clockwork payload.
@@ -75,7 +219,11 @@ type LogTraceFrame struct {
 }
 
 // TimelineEvent represents a timeline event in Clockwork payload.
+// Start and End are unix seconds; Duration is in milliseconds.
+// Events recorded through StartEvent carry an ID and, when nested, their Parent's ID.
 type TimelineEvent struct {
+	ID          string  `json:"id,omitempty"`
+	Parent      string  `json:"parent,omitempty"`
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Start       float64 `json:"start"`
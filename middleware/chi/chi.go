@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/RezaKargar/go-clockwork"
+	"github.com/RezaKargar/go-clockwork/ui"
 	chimw "github.com/go-chi/chi/v5"
 )
 
@@ -26,35 +27,42 @@ func Middleware(cw *clockwork.Clockwork) func(http.Handler) http.Handler {
 				return
 			}
 
-			if clockwork.ShouldSkipPath(r.URL.Path) || !clockwork.ShouldCapture(r.Header, cw.Config().HeaderName) {
+			collector, ok := clockwork.CaptureRequest(cw, r)
+			if !ok {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			collector := cw.NewCollector(r.Method, r.RequestURI)
-			if collector == nil {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			collector.SetHeaders(clockwork.ExtractSafeHeaders(r.Header))
+			collector.SetHeaders(cw.Redactor().Headers(r.Header))
 			collector.SetURL(clockwork.BuildRequestURL(r))
+			collector.SetGetData(clockwork.QueryData(r.URL.Query()))
+			collector.SetCookies(clockwork.CookieData(r.Cookies()))
 
-			traceID, spanID := clockwork.TraceFromContext(r.Context())
-			collector.SetTrace(traceID, spanID)
-			if traceID != "" {
-				cw.RegisterTrace(traceID, collector)
-			}
+			collector.SetTrace(clockwork.TraceFromContext(r.Context()))
+			cw.RegisterCollector(collector)
 
 			r = r.WithContext(clockwork.ContextWithCollector(r.Context(), collector))
-			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			requestBody := cw.CaptureRequestBody(r)
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK, body: cw.ResponseBodyBuffer()}
 			rw.Header().Set(cw.Config().IDHeader, collector.ID())
 			rw.Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
 
 			started := time.Now()
+			completed := false
+			defer func() {
+				if !completed {
+					// The handler panicked; store the request as a server error so its
+					// collector leaves the correlation registry.
+					_ = cw.CompleteRequest(r.Context(), collector, http.StatusInternalServerError, time.Since(started))
+				}
+			}()
 			next.ServeHTTP(rw, r)
+			completed = true
 			duration := time.Since(started)
 
+			cw.RecordRequestBody(collector, r.Header.Get("Content-Type"), requestBody)
+			cw.RecordResponseBody(collector, rw.Header().Get("Content-Type"), rw.body)
+
 			if routePattern := resolveControllerName(r); routePattern != "" {
 				collector.SetController(routePattern)
 			}
@@ -64,23 +72,30 @@ func Middleware(cw *clockwork.Clockwork) func(http.Handler) http.Handler {
 	}
 }
 
-// RegisterRoutes registers GET /__clockwork/:id on the Chi router.
+// RegisterRoutes registers GET /__clockwork/:id, the latest/previous/next/search/profile routes and the web app on the Chi router.
 func RegisterRoutes(r chimw.Router, cw *clockwork.Clockwork) {
 	if r == nil || cw == nil || !cw.IsEnabled() {
 		return
 	}
+	r.Get("/__clockwork/latest", LatestHandler(cw).ServeHTTP)
+	r.Get("/__clockwork/search", SearchHandler(cw).ServeHTTP)
 	r.Get("/__clockwork/{id}", MetadataHandler(cw).ServeHTTP)
+	r.Get("/__clockwork/{id}/previous", PreviousHandler(cw).ServeHTTP)
+	r.Get("/__clockwork/{id}/previous/{count}", PreviousHandler(cw).ServeHTTP)
+	r.Get("/__clockwork/{id}/next", NextHandler(cw).ServeHTTP)
+	r.Get("/__clockwork/{id}/next/{count}", NextHandler(cw).ServeHTTP)
+	r.Get("/__clockwork/{id}/profile", ProfileHandler(cw).ServeHTTP)
+
+	app := ui.Handler(cw)
+	r.Get("/__clockwork", ui.RedirectHandler().ServeHTTP)
+	r.Get(ui.AppPath, app.ServeHTTP)
+	r.Get(ui.RequestsPath, app.ServeHTTP)
 }
 
 // MetadataHandler returns an http.Handler for GET /__clockwork/:id.
 func MetadataHandler(cw *clockwork.Clockwork) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if cw == nil || !cw.IsEnabled() {
-			http.NotFound(w, r)
-			return
-		}
-		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		if !allowRequest(w, r, cw) {
 			return
 		}
 
@@ -96,16 +111,129 @@ func MetadataHandler(cw *clockwork.Clockwork) http.Handler {
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+		writeJSON(w, http.StatusOK, metadata)
+	})
+}
+
+// LatestHandler returns an http.Handler for GET /__clockwork/latest.
+func LatestHandler(cw *clockwork.Clockwork) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, r, cw) {
+			return
+		}
+
+		metadata, err := cw.LatestMetadata(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "metadata not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, metadata)
+	})
+}
+
+// SearchHandler returns an http.Handler for GET /__clockwork/search; see clockwork.ParseQuery for parameters.
+func SearchHandler(cw *clockwork.Clockwork) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, r, cw) {
+			return
+		}
+
+		query, err := clockwork.ParseQuery(r.URL.Query())
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		items, err := cw.SearchMetadata(r.Context(), query)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "metadata search failed")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, items)
+	})
+}
+
+// ProfileHandler returns an http.Handler for GET /__clockwork/:id/profile?kind=cpu|allocs|allocs_base.
+func ProfileHandler(cw *clockwork.Clockwork) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, r, cw) {
+			return
+		}
+
+		id := strings.TrimSpace(chimw.URLParam(r, "id"))
+		if id == "" {
+			writeJSONError(w, http.StatusBadRequest, "metadata id is required")
+			return
+		}
+
+		kind := r.URL.Query().Get("kind")
+		data, err := cw.GetProfile(r.Context(), id, kind)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "profile not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+clockwork.ProfileFilename(id, kind)+`"`)
 		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(metadata)
+		_, _ = w.Write(data)
+	})
+}
+
+// PreviousHandler returns an http.Handler for GET /__clockwork/:id/previous[/:count].
+func PreviousHandler(cw *clockwork.Clockwork) http.Handler {
+	return navigationHandler(cw, func(r *http.Request, id string, count int) ([]*clockwork.Metadata, error) {
+		return cw.PreviousMetadata(r.Context(), id, count)
+	})
+}
+
+// NextHandler returns an http.Handler for GET /__clockwork/:id/next[/:count].
+func NextHandler(cw *clockwork.Clockwork) http.Handler {
+	return navigationHandler(cw, func(r *http.Request, id string, count int) ([]*clockwork.Metadata, error) {
+		return cw.NextMetadata(r.Context(), id, count)
 	})
 }
 
+func navigationHandler(cw *clockwork.Clockwork, fetch func(r *http.Request, id string, count int) ([]*clockwork.Metadata, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, r, cw) {
+			return
+		}
+
+		id := strings.TrimSpace(chimw.URLParam(r, "id"))
+		if id == "" {
+			writeJSONError(w, http.StatusBadRequest, "metadata id is required")
+			return
+		}
+
+		items, err := fetch(r, id, clockwork.ParseNavigationCount(chimw.URLParam(r, "count")))
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "metadata not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, items)
+	})
+}
+
+func allowRequest(w http.ResponseWriter, r *http.Request, cw *clockwork.Clockwork) bool {
+	if cw == nil || !cw.IsEnabled() {
+		http.NotFound(w, r)
+		return false
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	body       *clockwork.BodyBuffer
 }
 
 func (w *responseWriter) WriteHeader(statusCode int) {
@@ -113,6 +241,12 @@ func (w *responseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+func (w *responseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	_, _ = w.body.Write(p[:n])
+	return n, err
+}
+
 func resolveControllerName(r *http.Request) string {
 	if r == nil {
 		return ""
@@ -154,9 +288,13 @@ func resolveMetadataID(r *http.Request, idHeader string) string {
 	return strings.TrimSpace(segments[2])
 }
 
-func writeJSONError(w http.ResponseWriter, status int, message string) {
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
 	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
 }
@@ -1,11 +1,14 @@
 package echo
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/RezaKargar/go-clockwork"
+	"github.com/RezaKargar/go-clockwork/ui"
 	"github.com/labstack/echo/v4"
 )
 
@@ -22,36 +25,44 @@ func Middleware(cw *clockwork.Clockwork) echo.MiddlewareFunc {
 				return next(c)
 			}
 
-			if clockwork.ShouldSkipPath(req.URL.Path) || !clockwork.ShouldCapture(req.Header, cw.Config().HeaderName) {
+			collector, ok := clockwork.CaptureRequest(cw, req)
+			if !ok {
 				return next(c)
 			}
 
-			collector := cw.NewCollector(req.Method, req.RequestURI)
-			if collector == nil {
-				return next(c)
-			}
-
-			collector.SetHeaders(clockwork.ExtractSafeHeaders(req.Header))
+			collector.SetHeaders(cw.Redactor().Headers(req.Header))
 			collector.SetURL(clockwork.BuildRequestURL(req))
+			collector.SetGetData(clockwork.QueryData(req.URL.Query()))
+			collector.SetCookies(clockwork.CookieData(req.Cookies()))
 
-			traceID, spanID := clockwork.TraceFromContext(req.Context())
-			collector.SetTrace(traceID, spanID)
-			if traceID != "" {
-				cw.RegisterTrace(traceID, collector)
-			}
+			collector.SetTrace(clockwork.TraceFromContext(req.Context()))
+			cw.RegisterCollector(collector)
 
 			c.SetRequest(req.WithContext(clockwork.ContextWithCollector(req.Context(), collector)))
+			requestBody := cw.CaptureRequestBody(c.Request())
+			responseBody := cw.ResponseBodyBuffer()
+			if responseBody != nil {
+				c.Response().Writer = &bodyWriter{ResponseWriter: c.Response().Writer, body: responseBody}
+			}
 			c.Response().Header().Set(cw.Config().IDHeader, collector.ID())
 			c.Response().Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
 
 			started := time.Now()
+			completed := false
+			defer func() {
+				if !completed {
+					// The handler panicked; store the request as a server error so its
+					// collector leaves the correlation registry.
+					_ = cw.CompleteRequest(c.Request().Context(), collector, http.StatusInternalServerError, time.Since(started))
+				}
+			}()
 			err := next(c)
+			completed = true
 			duration := time.Since(started)
+			cw.RecordRequestBody(collector, c.Request().Header.Get("Content-Type"), requestBody)
+			cw.RecordResponseBody(collector, c.Response().Header().Get("Content-Type"), responseBody)
 
-			status := c.Response().Status
-			if status == 0 {
-				status = http.StatusOK
-			}
+			status := responseStatus(c, err)
 			if route := c.Path(); strings.TrimSpace(route) != "" {
 				collector.SetController(route)
 			}
@@ -62,11 +73,51 @@ func Middleware(cw *clockwork.Clockwork) echo.MiddlewareFunc {
 	}
 }
 
-// RegisterRoutes registers GET /__clockwork/:id on the Echo instance.
+// responseStatus returns the status the request ends with. An error returned by the
+// handler is only turned into a response by Echo's error handler after the middleware
+// returns, so its status is taken from the error unless a response was already written.
+func responseStatus(c echo.Context, err error) int {
+	if err != nil && !c.Response().Committed {
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			return httpErr.Code
+		}
+		return http.StatusInternalServerError
+	}
+	if status := c.Response().Status; status != 0 {
+		return status
+	}
+	return http.StatusOK
+}
+
+// RegisterRoutes registers GET /__clockwork/:id, the latest/previous/next/search/profile routes and the web app on the Echo instance.
 func RegisterRoutes(e *echo.Echo, cw *clockwork.Clockwork) {
 	if e == nil || cw == nil || !cw.IsEnabled() {
 		return
 	}
+	e.GET("/__clockwork/latest", func(c echo.Context) error {
+		metadata, err := cw.LatestMetadata(c.Request().Context())
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "metadata not found"})
+		}
+
+		c.Response().Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+		return c.JSON(http.StatusOK, metadata)
+	})
+	e.GET("/__clockwork/search", func(c echo.Context) error {
+		query, err := clockwork.ParseQuery(c.QueryParams())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		items, err := cw.SearchMetadata(c.Request().Context(), query)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "metadata search failed"})
+		}
+
+		c.Response().Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+		return c.JSON(http.StatusOK, items)
+	})
 	e.GET("/__clockwork/:id", func(c echo.Context) error {
 		id := resolveMetadataID(c, cw.Config().IDHeader)
 		if id == "" {
@@ -81,6 +132,52 @@ func RegisterRoutes(e *echo.Echo, cw *clockwork.Clockwork) {
 		c.Response().Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
 		return c.JSON(http.StatusOK, metadata)
 	})
+
+	previous := navigationHandler(cw.PreviousMetadata)
+	e.GET("/__clockwork/:id/previous", previous)
+	e.GET("/__clockwork/:id/previous/:count", previous)
+
+	next := navigationHandler(cw.NextMetadata)
+	e.GET("/__clockwork/:id/next", next)
+	e.GET("/__clockwork/:id/next/:count", next)
+
+	e.GET("/__clockwork/:id/profile", func(c echo.Context) error {
+		id := strings.TrimSpace(c.Param("id"))
+		if id == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "metadata id is required"})
+		}
+
+		kind := c.QueryParam("kind")
+		data, err := cw.GetProfile(c.Request().Context(), id, kind)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "profile not found"})
+		}
+
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+clockwork.ProfileFilename(id, kind)+`"`)
+		return c.Blob(http.StatusOK, echo.MIMEOctetStream, data)
+	})
+
+	app := echo.WrapHandler(ui.Handler(cw))
+	e.GET("/__clockwork", echo.WrapHandler(ui.RedirectHandler()))
+	e.GET(ui.AppPath, app)
+	e.GET(ui.RequestsPath, app)
+}
+
+func navigationHandler(fetch func(ctx context.Context, id string, limit int) ([]*clockwork.Metadata, error)) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := strings.TrimSpace(c.Param("id"))
+		if id == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "metadata id is required"})
+		}
+
+		items, err := fetch(c.Request().Context(), id, clockwork.ParseNavigationCount(c.Param("count")))
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "metadata not found"})
+		}
+
+		c.Response().Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+		return c.JSON(http.StatusOK, items)
+	}
 }
 
 func resolveMetadataID(c echo.Context, idHeader string) string {
@@ -91,3 +188,21 @@ func resolveMetadataID(c echo.Context, idHeader string) string {
 	}
 	return strings.TrimSpace(c.Param("id"))
 }
+
+// bodyWriter tees response writes into a Clockwork body buffer.
+type bodyWriter struct {
+	http.ResponseWriter
+	body *clockwork.BodyBuffer
+}
+
+func (w *bodyWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	_, _ = w.body.Write(p[:n])
+	return n, err
+}
+
+func (w *bodyWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
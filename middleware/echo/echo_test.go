@@ -0,0 +1,58 @@
+package echo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_TailSamplingKeepsHandlerErrors(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.TailServerErrors = true
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+
+	e := echo.New()
+	e.Use(Middleware(cw))
+	e.GET("/ok", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+	e.GET("/missing", func(echo.Context) error { return echo.ErrNotFound })
+	e.GET("/unavailable", func(echo.Context) error { return echo.NewHTTPError(http.StatusServiceUnavailable, "maintenance") })
+	e.GET("/broken", func(echo.Context) error { return errors.New("boom") })
+	e.GET("/written", func(c echo.Context) error {
+		_ = c.String(http.StatusBadGateway, "upstream down")
+		return errors.New("upstream down")
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		kept   bool
+	}{
+		{"/ok", http.StatusOK, false},
+		{"/missing", http.StatusNotFound, false},
+		{"/unavailable", http.StatusServiceUnavailable, true},
+		{"/broken", http.StatusInternalServerError, true},
+		{"/written", http.StatusBadGateway, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, tt.status, rec.Code, tt.path)
+		id := rec.Header().Get(cw.Config().IDHeader)
+		require.NotEmpty(t, id, tt.path)
+
+		metadata, err := cw.GetMetadata(req.Context(), id)
+		if !tt.kept {
+			require.Error(t, err, tt.path)
+			continue
+		}
+		require.NoError(t, err, tt.path)
+		require.Equal(t, tt.status, metadata.ResponseStatus, tt.path)
+	}
+}
@@ -1,12 +1,17 @@
 package fiber
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/RezaKargar/go-clockwork"
+	"github.com/RezaKargar/go-clockwork/ui"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 )
 
 // Middleware returns Fiber middleware for Clockwork request profiling.
@@ -16,38 +21,53 @@ func Middleware(cw *clockwork.Clockwork) fiber.Handler {
 			return c.Next()
 		}
 
-		path := string(c.Path())
-		headers := requestHeadersToHTTP(c)
-		if clockwork.ShouldSkipPath(path) || !clockwork.ShouldCapture(headers, cw.Config().HeaderName) {
+		if clockwork.ShouldSkipPath(c.Path()) {
 			return c.Next()
 		}
 
-		collector := cw.NewCollector(c.Method(), c.Path())
-		if collector == nil {
+		req := captureRequest(c)
+		collector, ok := clockwork.CaptureRequest(cw, req)
+		if !ok {
 			return c.Next()
 		}
 
-		collector.SetHeaders(clockwork.ExtractSafeHeaders(headers))
+		collector.SetHeaders(cw.Redactor().Headers(req.Header))
 		collector.SetURL(buildRequestURL(c))
+		collector.SetGetData(clockwork.QueryData(req.URL.Query()))
+		collector.SetCookies(clockwork.CookieData(req.Cookies()))
 
-		traceID, spanID := clockwork.TraceFromContext(c.UserContext())
-		collector.SetTrace(traceID, spanID)
-		if traceID != "" {
-			cw.RegisterTrace(traceID, collector)
-		}
+		collector.SetTrace(clockwork.TraceFromContext(c.UserContext()))
+		cw.RegisterCollector(collector)
 
 		c.SetUserContext(clockwork.ContextWithCollector(c.UserContext(), collector))
 		c.Set(cw.Config().IDHeader, collector.ID())
 		c.Set("X-Clockwork-Version", clockwork.ProtocolVersion)
 
 		started := time.Now()
+		completed := false
+		defer func() {
+			if !completed {
+				// The handler panicked; store the request as a server error so its
+				// collector leaves the correlation registry.
+				_ = cw.CompleteRequest(c.UserContext(), collector, fiber.StatusInternalServerError, time.Since(started))
+			}
+		}()
 		err := c.Next()
+		completed = true
 		duration := time.Since(started)
 
-		status := c.Response().StatusCode()
-		if status == 0 {
-			status = fiber.StatusOK
+		contentType := string(c.Request().Header.ContentType())
+		if cw.Config().CaptureRequestBody && cw.BodyContentTypeAllowed(contentType) {
+			body := clockwork.NewBodyBuffer(cw.Config().MaxRequestPayloadBytes)
+			_, _ = body.Write(c.Body())
+			cw.RecordRequestBody(collector, contentType, body)
+		}
+		if body := cw.ResponseBodyBuffer(); body != nil {
+			_, _ = body.Write(c.Response().Body())
+			cw.RecordResponseBody(collector, string(c.Response().Header.ContentType()), body)
 		}
+
+		status := responseStatus(c, err)
 		if routePattern := strings.TrimSpace(c.Route().Path); routePattern != "" {
 			collector.SetController(routePattern)
 		}
@@ -57,11 +77,44 @@ func Middleware(cw *clockwork.Clockwork) fiber.Handler {
 	}
 }
 
-// RegisterRoutes registers GET /__clockwork/:id on the Fiber app.
+// RegisterRoutes registers GET /__clockwork/:id, the latest/previous/next/search/profile routes and the web app on the Fiber app.
 func RegisterRoutes(app *fiber.App, cw *clockwork.Clockwork) {
 	if app == nil || cw == nil || !cw.IsEnabled() {
 		return
 	}
+
+	// Fiber matches routes in registration order, so static paths go before /:id.
+	web := adaptor.HTTPHandler(ui.Handler(cw))
+	app.Get("/__clockwork", func(c *fiber.Ctx) error {
+		return c.Redirect(ui.AppPath, fiber.StatusFound)
+	})
+	app.Get(ui.AppPath, web)
+	app.Get(ui.RequestsPath, web)
+	app.Get("/__clockwork/latest", func(c *fiber.Ctx) error {
+		metadata, err := cw.LatestMetadata(c.UserContext())
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "metadata not found"})
+		}
+
+		c.Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+		c.Set("Content-Type", "application/json")
+		return c.Status(fiber.StatusOK).JSON(metadata)
+	})
+	app.Get("/__clockwork/search", func(c *fiber.Ctx) error {
+		query, err := clockwork.ParseQuery(queryValues(c))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		items, err := cw.SearchMetadata(c.UserContext(), query)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "metadata search failed"})
+		}
+
+		c.Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+		c.Set("Content-Type", "application/json")
+		return c.Status(fiber.StatusOK).JSON(items)
+	})
 	app.Get("/__clockwork/:id", func(c *fiber.Ctx) error {
 		id := resolveMetadataID(c, cw.Config().IDHeader)
 		if id == "" {
@@ -77,14 +130,89 @@ func RegisterRoutes(app *fiber.App, cw *clockwork.Clockwork) {
 		c.Set("Content-Type", "application/json")
 		return c.Status(fiber.StatusOK).JSON(metadata)
 	})
+
+	previous := navigationHandler(cw.PreviousMetadata)
+	app.Get("/__clockwork/:id/previous/:count?", previous)
+
+	next := navigationHandler(cw.NextMetadata)
+	app.Get("/__clockwork/:id/next/:count?", next)
+
+	app.Get("/__clockwork/:id/profile", func(c *fiber.Ctx) error {
+		id := strings.TrimSpace(c.Params("id"))
+		if id == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "metadata id is required"})
+		}
+
+		kind := c.Query("kind")
+		data, err := cw.GetProfile(c.UserContext(), id, kind)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "profile not found"})
+		}
+
+		c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+clockwork.ProfileFilename(id, kind)+`"`)
+		return c.Status(fiber.StatusOK).Send(data)
+	})
+}
+
+func navigationHandler(fetch func(ctx context.Context, id string, limit int) ([]*clockwork.Metadata, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := strings.TrimSpace(c.Params("id"))
+		if id == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "metadata id is required"})
+		}
+
+		items, err := fetch(c.UserContext(), id, clockwork.ParseNavigationCount(c.Params("count")))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "metadata not found"})
+		}
+
+		c.Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+		c.Set("Content-Type", "application/json")
+		return c.Status(fiber.StatusOK).JSON(items)
+	}
+}
+
+// responseStatus returns the status the request ends with. Fiber's error handler writes
+// the response for an error returned by the handler only after the middleware returns,
+// so the status is taken from the error: a *fiber.Error's code, otherwise 500.
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return fiberErr.Code
+		}
+		return fiber.StatusInternalServerError
+	}
+	if status := c.Response().StatusCode(); status != 0 {
+		return status
+	}
+	return fiber.StatusOK
 }
 
-func requestHeadersToHTTP(c *fiber.Ctx) http.Header {
-	h := make(http.Header)
+// captureRequest builds the *http.Request seen by capture policies and session resolvers:
+// method, URL, headers and client address, without copying the body.
+func captureRequest(c *fiber.Ctx) *http.Request {
+	header := make(http.Header)
 	c.Request().Header.VisitAll(func(key, value []byte) {
-		h.Add(string(key), string(value))
+		header.Add(string(key), string(value))
+	})
+	return &http.Request{
+		Method:     c.Method(),
+		URL:        &url.URL{Path: c.Path(), RawQuery: string(c.Request().URI().QueryString())},
+		RequestURI: c.Path(),
+		Host:       c.Hostname(),
+		Header:     header,
+		RemoteAddr: c.Context().RemoteAddr().String(),
+	}
+}
+
+func queryValues(c *fiber.Ctx) url.Values {
+	values := make(url.Values)
+	c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		values.Add(string(key), string(value))
 	})
-	return h
+	return values
 }
 
 func buildRequestURL(c *fiber.Ctx) string {
@@ -0,0 +1,101 @@
+package fiber
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newApp(t *testing.T, cfg clockwork.Config) (*fiber.App, *clockwork.Clockwork) {
+	t.Helper()
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+	app := fiber.New()
+	app.Use(Middleware(cw))
+	RegisterRoutes(app, cw)
+	app.Get("/orders/:id", func(c *fiber.Ctx) error {
+		return c.SendString("order " + c.Params("id"))
+	})
+	app.Get("/unavailable", func(*fiber.Ctx) error { return fiber.NewError(fiber.StatusServiceUnavailable, "maintenance") })
+	app.Get("/missing", func(*fiber.Ctx) error { return fiber.ErrNotFound })
+	app.Get("/broken", func(*fiber.Ctx) error { return errors.New("boom") })
+	return app, cw
+}
+
+func TestMiddleware_CapturesRequest(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.CaptureMode = clockwork.CaptureModeAlways
+	app, cw := newApp(t, cfg)
+
+	req := httptest.NewRequest("GET", "/orders/42?expand=items", nil)
+	req.Header.Set("Cookie", "theme=dark")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	id := resp.Header.Get(cw.Config().IDHeader)
+	require.NotEmpty(t, id)
+
+	metadata, err := cw.GetMetadata(req.Context(), id)
+	require.NoError(t, err)
+	require.Equal(t, "/orders/42", metadata.URI)
+	require.Equal(t, "/orders/:id", metadata.Controller)
+	require.Equal(t, fiber.StatusOK, metadata.ResponseStatus)
+	require.Equal(t, "items", metadata.GetData["expand"])
+	require.Equal(t, "dark", metadata.Cookies["theme"])
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/__clockwork/latest", nil))
+	require.NoError(t, err)
+	require.Empty(t, resp.Header.Get(cw.Config().IDHeader), "Clockwork routes are not captured")
+}
+
+func TestMiddleware_PoliciesSeeRemoteAddress(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.CaptureMode = clockwork.CaptureModeAlways
+	app, cw := newApp(t, cfg)
+
+	cw.SetCapturePolicy(clockwork.AllowIPs("10.0.0.0/8"))
+	resp, err := app.Test(httptest.NewRequest("GET", "/orders/1", nil))
+	require.NoError(t, err)
+	require.Empty(t, resp.Header.Get(cw.Config().IDHeader))
+
+	cw.SetCapturePolicy(clockwork.AllowIPs("0.0.0.0"))
+	resp, err = app.Test(httptest.NewRequest("GET", "/orders/1", nil))
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Header.Get(cw.Config().IDHeader))
+}
+
+func TestMiddleware_TailSamplingKeepsHandlerErrors(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.TailServerErrors = true
+	app, cw := newApp(t, cfg)
+
+	tests := []struct {
+		path   string
+		status int
+		kept   bool
+	}{
+		{"/orders/1", fiber.StatusOK, false},
+		{"/missing", fiber.StatusNotFound, false},
+		{"/unavailable", fiber.StatusServiceUnavailable, true},
+		{"/broken", fiber.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, tt.status, resp.StatusCode, tt.path)
+		id := resp.Header.Get(cw.Config().IDHeader)
+		require.NotEmpty(t, id, tt.path)
+
+		metadata, err := cw.GetMetadata(req.Context(), id)
+		if !tt.kept {
+			require.Error(t, err, tt.path)
+			continue
+		}
+		require.NoError(t, err, tt.path)
+		require.Equal(t, tt.status, metadata.ResponseStatus, tt.path)
+	}
+}
@@ -1,11 +1,13 @@
 package gin
 
 import (
+	"context"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/RezaKargar/go-clockwork"
+	"github.com/RezaKargar/go-clockwork/ui"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,40 +27,56 @@ func Middleware(cw *clockwork.Clockwork, logger clockwork.Logger) gin.HandlerFun
 			return
 		}
 
-		if c.Request == nil || !clockwork.ShouldCapture(c.Request.Header, cw.Config().HeaderName) {
+		if c.Request == nil {
 			c.Next()
 			return
 		}
 
-		collector := cw.NewCollector(c.Request.Method, c.Request.RequestURI)
-		if collector == nil {
+		collector, ok := clockwork.CaptureRequest(cw, c.Request)
+		if !ok {
 			c.Next()
 			return
 		}
 
-		collector.SetHeaders(clockwork.ExtractSafeHeaders(c.Request.Header))
+		collector.SetHeaders(cw.Redactor().Headers(c.Request.Header))
 		collector.SetURL(clockwork.BuildRequestURL(c.Request))
+		collector.SetGetData(clockwork.QueryData(c.Request.URL.Query()))
+		collector.SetCookies(clockwork.CookieData(c.Request.Cookies()))
 		collector.AddLogEntry("info", "clockwork capture enabled", map[string]interface{}{
 			"method": c.Request.Method,
 			"uri":    c.Request.RequestURI,
 		})
 
-		traceID, spanID := clockwork.TraceFromContext(c.Request.Context())
-		collector.SetTrace(traceID, spanID)
-		if traceID != "" {
-			cw.RegisterTrace(traceID, collector)
-		}
+		collector.SetTrace(clockwork.TraceFromContext(c.Request.Context()))
+		cw.RegisterCollector(collector)
 
 		ctx := clockwork.ContextWithCollector(c.Request.Context(), collector)
 		c.Request = c.Request.WithContext(ctx)
+		requestBody := cw.CaptureRequestBody(c.Request)
+		var responseBody *clockwork.BodyBuffer
+		if buf := cw.ResponseBodyBuffer(); buf != nil {
+			responseBody = buf
+			c.Writer = &bodyWriter{ResponseWriter: c.Writer, body: buf}
+		}
 
 		c.Header(cw.Config().IDHeader, collector.ID())
 		c.Header("X-Clockwork-Version", clockwork.ProtocolVersion)
 
 		start := time.Now()
+		completed := false
+		defer func() {
+			if !completed {
+				// A handler panicked past any inner gin.Recovery; store the request as a
+				// server error so its collector leaves the correlation registry.
+				_ = cw.CompleteRequest(ctx, collector, http.StatusInternalServerError, time.Since(start))
+			}
+		}()
 		c.Next()
+		completed = true
 
 		duration := time.Since(start)
+		cw.RecordRequestBody(collector, c.Request.Header.Get("Content-Type"), requestBody)
+		cw.RecordResponseBody(collector, c.Writer.Header().Get("Content-Type"), responseBody)
 		if controller := resolveControllerName(c); controller != "" {
 			collector.SetController(controller)
 		}
@@ -80,6 +98,42 @@ func RegisterRoutes(router *gin.Engine, cw *clockwork.Clockwork, logger clockwor
 
 	group := router.Group("/__clockwork", routeMiddlewares...)
 
+	app := gin.WrapH(ui.Handler(cw))
+	group.GET("", gin.WrapH(ui.RedirectHandler()))
+	group.GET(strings.TrimPrefix(ui.AppPath, group.BasePath()), app)
+	group.GET(strings.TrimPrefix(ui.RequestsPath, group.BasePath()), app)
+
+	group.GET("/latest", func(c *gin.Context) {
+		metadata, err := cw.LatestMetadata(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "metadata not found"})
+			return
+		}
+
+		c.Header("X-Clockwork-Version", clockwork.ProtocolVersion)
+		c.JSON(http.StatusOK, metadata)
+	})
+
+	group.GET("/search", func(c *gin.Context) {
+		query, err := clockwork.ParseQuery(c.Request.URL.Query())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		items, err := cw.SearchMetadata(c.Request.Context(), query)
+		if err != nil {
+			if logger != nil {
+				logger.Warn("clockwork metadata search failed", "error", err)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "metadata search failed"})
+			return
+		}
+
+		c.Header("X-Clockwork-Version", clockwork.ProtocolVersion)
+		c.JSON(http.StatusOK, items)
+	})
+
 	group.GET("/:id", func(c *gin.Context) {
 		id := resolveMetadataID(c, cw.Config().IDHeader)
 		if id == "" {
@@ -100,6 +154,57 @@ func RegisterRoutes(router *gin.Engine, cw *clockwork.Clockwork, logger clockwor
 		c.Header("Content-Type", "application/json")
 		c.JSON(http.StatusOK, metadata)
 	})
+
+	previous := navigationHandler(cw.PreviousMetadata, logger)
+	group.GET("/:id/previous", previous)
+	group.GET("/:id/previous/:count", previous)
+
+	next := navigationHandler(cw.NextMetadata, logger)
+	group.GET("/:id/next", next)
+	group.GET("/:id/next/:count", next)
+
+	group.GET("/:id/profile", func(c *gin.Context) {
+		id := strings.TrimSpace(c.Param("id"))
+		if id == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "metadata id is required"})
+			return
+		}
+
+		kind := c.Query("kind")
+		data, err := cw.GetProfile(c.Request.Context(), id, kind)
+		if err != nil {
+			if logger != nil {
+				logger.Warn("clockwork profile not found", "id", id, "kind", kind, "error", err)
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "profile not found"})
+			return
+		}
+
+		c.Header("Content-Disposition", `attachment; filename="`+clockwork.ProfileFilename(id, kind)+`"`)
+		c.Data(http.StatusOK, "application/octet-stream", data)
+	})
+}
+
+func navigationHandler(fetch func(ctx context.Context, id string, limit int) ([]*clockwork.Metadata, error), logger clockwork.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := strings.TrimSpace(c.Param("id"))
+		if id == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "metadata id is required"})
+			return
+		}
+
+		items, err := fetch(c.Request.Context(), id, clockwork.ParseNavigationCount(c.Param("count")))
+		if err != nil {
+			if logger != nil {
+				logger.Warn("clockwork metadata not found", "id", id, "error", err)
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "metadata not found"})
+			return
+		}
+
+		c.Header("X-Clockwork-Version", clockwork.ProtocolVersion)
+		c.JSON(http.StatusOK, items)
+	}
 }
 
 func resolveMetadataID(c *gin.Context, idHeaderName string) string {
@@ -153,3 +258,21 @@ func compactHandlerName(full string) string {
 		return trimmed
 	}
 }
+
+// bodyWriter tees response writes into a Clockwork body buffer.
+type bodyWriter struct {
+	gin.ResponseWriter
+	body *clockwork.BodyBuffer
+}
+
+func (w *bodyWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	_, _ = w.body.Write(p[:n])
+	return n, err
+}
+
+func (w *bodyWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	_, _ = w.body.Write([]byte(s[:n]))
+	return n, err
+}
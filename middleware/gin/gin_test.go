@@ -3,6 +3,7 @@ package gin
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -35,6 +36,18 @@ func (m *mockStorage) List(ctx context.Context, limit int) ([]*clockwork.Metadat
 	return m.items, nil
 }
 
+func (m *mockStorage) Previous(ctx context.Context, id string, limit int) ([]*clockwork.Metadata, error) {
+	return nil, nil
+}
+
+func (m *mockStorage) Next(ctx context.Context, id string, limit int) ([]*clockwork.Metadata, error) {
+	return nil, nil
+}
+
+func (m *mockStorage) Search(ctx context.Context, query clockwork.Query) ([]*clockwork.Metadata, error) {
+	return nil, nil
+}
+
 func (m *mockStorage) Cleanup(ctx context.Context, maxAge time.Duration) error {
 	return nil
 }
@@ -69,6 +82,34 @@ func TestMiddleware_ActivatesOnlyWhenHeaderPresent(t *testing.T) {
 	require.Len(t, store.items, 1)
 }
 
+func TestMiddleware_RecoveredPanicLeavesRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := clockwork.Config{Enabled: true, HeaderName: "X-Clockwork", IDHeader: "X-Clockwork-Id"}
+	cfg.Normalize()
+	store := &mockStorage{}
+	cw := clockwork.NewClockwork(cfg, store)
+
+	router := gin.New()
+	router.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.Use(Middleware(cw, nil))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header["X-Clockwork"] = []string{""}
+	router.ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusInternalServerError, res.Code)
+	require.False(t, cw.HasActiveTraces())
+	require.Len(t, store.items, 1)
+	require.Equal(t, http.StatusInternalServerError, store.items[0].ResponseStatus)
+}
+
 func TestMiddleware_SkipsFaviconEvenWhenHeaderPresent(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -236,3 +277,70 @@ func TestClockworkRoute_HeaderIDStrictOverrideNotFound(t *testing.T) {
 
 	require.Equal(t, http.StatusNotFound, res.Code)
 }
+
+func TestClockworkRoute_LatestPreviousNext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := clockwork.Config{Enabled: true, HeaderName: "X-Clockwork", IDHeader: "X-Clockwork-Id"}
+	cfg.Normalize()
+	store := clockwork.NewInMemoryStorage(10, 1024*1024)
+	cw := clockwork.NewClockwork(cfg, store)
+
+	for _, id := range []string{"first", "second", "third"} {
+		require.NoError(t, cw.SaveMetadata(context.Background(), &clockwork.Metadata{ID: id, Method: "GET", URI: "/" + id}))
+	}
+
+	router := gin.New()
+	RegisterRoutes(router, cw, nil)
+
+	latestRes := httptest.NewRecorder()
+	router.ServeHTTP(latestRes, httptest.NewRequest(http.MethodGet, "/__clockwork/latest", nil))
+	require.Equal(t, http.StatusOK, latestRes.Code)
+	var latest clockwork.Metadata
+	require.NoError(t, json.Unmarshal(latestRes.Body.Bytes(), &latest))
+	require.Equal(t, "third", latest.ID)
+
+	previousRes := httptest.NewRecorder()
+	router.ServeHTTP(previousRes, httptest.NewRequest(http.MethodGet, "/__clockwork/third/previous/1", nil))
+	require.Equal(t, http.StatusOK, previousRes.Code)
+	var previous []clockwork.Metadata
+	require.NoError(t, json.Unmarshal(previousRes.Body.Bytes(), &previous))
+	require.Len(t, previous, 1)
+	require.Equal(t, "second", previous[0].ID)
+
+	nextRes := httptest.NewRecorder()
+	router.ServeHTTP(nextRes, httptest.NewRequest(http.MethodGet, "/__clockwork/first/next", nil))
+	require.Equal(t, http.StatusOK, nextRes.Code)
+	var next []clockwork.Metadata
+	require.NoError(t, json.Unmarshal(nextRes.Body.Bytes(), &next))
+	require.Len(t, next, 2)
+	require.Equal(t, "second", next[0].ID)
+	require.Equal(t, "third", next[1].ID)
+}
+
+func TestClockworkRoute_ServesWebApp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := clockwork.Config{Enabled: true, HeaderName: "X-Clockwork", IDHeader: "X-Clockwork-Id"}
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(10, 1024*1024))
+	require.NoError(t, cw.SaveMetadata(context.Background(), &clockwork.Metadata{ID: "app-id", Method: "GET", URI: "/app"}))
+
+	router := gin.New()
+	RegisterRoutes(router, cw, nil)
+
+	redirectRes := httptest.NewRecorder()
+	router.ServeHTTP(redirectRes, httptest.NewRequest(http.MethodGet, "/__clockwork", nil))
+	require.Equal(t, http.StatusFound, redirectRes.Code)
+	require.Equal(t, "/__clockwork/app", redirectRes.Header().Get("Location"))
+
+	appRes := httptest.NewRecorder()
+	router.ServeHTTP(appRes, httptest.NewRequest(http.MethodGet, "/__clockwork/app", nil))
+	require.Equal(t, http.StatusOK, appRes.Code)
+	require.Contains(t, appRes.Header().Get("Content-Type"), "text/html")
+
+	listRes := httptest.NewRecorder()
+	router.ServeHTTP(listRes, httptest.NewRequest(http.MethodGet, "/__clockwork/app/requests", nil))
+	require.Equal(t, http.StatusOK, listRes.Code)
+	require.Contains(t, listRes.Body.String(), "app-id")
+}
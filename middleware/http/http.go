@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/RezaKargar/go-clockwork"
+	"github.com/RezaKargar/go-clockwork/ui"
 )
 
 // Middleware returns a net/http middleware for Clockwork request profiling.
@@ -24,30 +25,42 @@ func Middleware(cw *clockwork.Clockwork, next http.Handler) http.Handler {
 			return
 		}
 
-		collector, ok := clockwork.NewRequestCapture(cw, r.Method, r.URL.Path, r.RequestURI, r.Header)
+		collector, ok := clockwork.CaptureRequest(cw, r)
 		if !ok {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		collector.SetHeaders(clockwork.ExtractSafeHeaders(r.Header))
+		collector.SetHeaders(cw.Redactor().Headers(r.Header))
 		collector.SetURL(clockwork.BuildRequestURL(r))
+		collector.SetGetData(clockwork.QueryData(r.URL.Query()))
+		collector.SetCookies(clockwork.CookieData(r.Cookies()))
 
-		traceID, spanID := clockwork.TraceFromContext(r.Context())
-		collector.SetTrace(traceID, spanID)
-		if traceID != "" {
-			cw.RegisterTrace(traceID, collector)
-		}
+		collector.SetTrace(clockwork.TraceFromContext(r.Context()))
+		cw.RegisterCollector(collector)
 
 		r = r.WithContext(clockwork.ContextWithCollector(r.Context(), collector))
-		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		requestBody := cw.CaptureRequestBody(r)
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK, body: cw.ResponseBodyBuffer()}
 		rw.Header().Set(cw.Config().IDHeader, collector.ID())
 		rw.Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
 
 		started := time.Now()
+		completed := false
+		defer func() {
+			if !completed {
+				// The handler panicked; store the request as a server error so its
+				// collector leaves the correlation registry.
+				_ = cw.CompleteRequest(r.Context(), collector, http.StatusInternalServerError, time.Since(started))
+			}
+		}()
 		next.ServeHTTP(rw, r)
+		completed = true
 		duration := time.Since(started)
 
+		cw.RecordRequestBody(collector, r.Header.Get("Content-Type"), requestBody)
+		cw.RecordResponseBody(collector, rw.Header().Get("Content-Type"), rw.body)
+
 		_ = cw.CompleteRequest(r.Context(), collector, rw.statusCode, duration)
 	})
 }
@@ -55,12 +68,7 @@ func Middleware(cw *clockwork.Clockwork, next http.Handler) http.Handler {
 // MetadataHandler handles GET /__clockwork/:id lookups.
 func MetadataHandler(cw *clockwork.Clockwork) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if cw == nil || !cw.IsEnabled() {
-			http.NotFound(w, r)
-			return
-		}
-		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		if !allowRequest(w, r, cw) {
 			return
 		}
 
@@ -76,14 +84,92 @@ func MetadataHandler(cw *clockwork.Clockwork) http.Handler {
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+		writeJSON(w, http.StatusOK, metadata)
+	})
+}
+
+// LatestHandler handles GET /__clockwork/latest lookups.
+func LatestHandler(cw *clockwork.Clockwork) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, r, cw) {
+			return
+		}
+
+		metadata, err := cw.LatestMetadata(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "metadata not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, metadata)
+	})
+}
+
+// SearchHandler handles GET /__clockwork/search lookups; see clockwork.ParseQuery for parameters.
+func SearchHandler(cw *clockwork.Clockwork) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, r, cw) {
+			return
+		}
+
+		query, err := clockwork.ParseQuery(r.URL.Query())
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		items, err := cw.SearchMetadata(r.Context(), query)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "metadata search failed")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, items)
+	})
+}
+
+// ProfileHandler handles GET /__clockwork/:id/profile?kind=cpu|allocs|allocs_base downloads.
+func ProfileHandler(cw *clockwork.Clockwork) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, r, cw) {
+			return
+		}
+
+		id := pathSegment(r, 2)
+		if id == "" {
+			writeJSONError(w, http.StatusBadRequest, "metadata id is required")
+			return
+		}
+
+		kind := r.URL.Query().Get("kind")
+		data, err := cw.GetProfile(r.Context(), id, kind)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "profile not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+clockwork.ProfileFilename(id, kind)+`"`)
 		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(metadata)
+		_, _ = w.Write(data)
 	})
 }
 
-// RegisterMetadataRoute registers GET /__clockwork/:id on provided mux.
+// PreviousHandler handles GET /__clockwork/:id/previous[/:count] lookups.
+func PreviousHandler(cw *clockwork.Clockwork) http.Handler {
+	return navigationHandler(cw, func(r *http.Request, id string, count int) ([]*clockwork.Metadata, error) {
+		return cw.PreviousMetadata(r.Context(), id, count)
+	})
+}
+
+// NextHandler handles GET /__clockwork/:id/next[/:count] lookups.
+func NextHandler(cw *clockwork.Clockwork) http.Handler {
+	return navigationHandler(cw, func(r *http.Request, id string, count int) ([]*clockwork.Metadata, error) {
+		return cw.NextMetadata(r.Context(), id, count)
+	})
+}
+
+// RegisterMetadataRoute registers GET /__clockwork/:id, the latest/previous/next/search/profile routes and the web app on provided mux.
 func RegisterMetadataRoute(mux *http.ServeMux, cw *clockwork.Clockwork) {
 	if mux == nil || cw == nil || !cw.IsEnabled() {
 		return
@@ -91,11 +177,58 @@ func RegisterMetadataRoute(mux *http.ServeMux, cw *clockwork.Clockwork) {
 	h := MetadataHandler(cw)
 	mux.Handle("GET /__clockwork/{id}", h)
 	mux.Handle("GET /__clockwork/", h)
+	mux.Handle("GET /__clockwork/latest", LatestHandler(cw))
+	mux.Handle("GET /__clockwork/search", SearchHandler(cw))
+	mux.Handle("GET /__clockwork/{id}/previous", PreviousHandler(cw))
+	mux.Handle("GET /__clockwork/{id}/previous/{count}", PreviousHandler(cw))
+	mux.Handle("GET /__clockwork/{id}/next", NextHandler(cw))
+	mux.Handle("GET /__clockwork/{id}/next/{count}", NextHandler(cw))
+	mux.Handle("GET /__clockwork/{id}/profile", ProfileHandler(cw))
+
+	app := ui.Handler(cw)
+	mux.Handle("GET /__clockwork", ui.RedirectHandler())
+	mux.Handle("GET "+ui.AppPath, app)
+	mux.Handle("GET "+ui.RequestsPath, app)
+}
+
+func navigationHandler(cw *clockwork.Clockwork, fetch func(r *http.Request, id string, count int) ([]*clockwork.Metadata, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, r, cw) {
+			return
+		}
+
+		id := pathSegment(r, 2)
+		if id == "" {
+			writeJSONError(w, http.StatusBadRequest, "metadata id is required")
+			return
+		}
+
+		items, err := fetch(r, id, clockwork.ParseNavigationCount(pathSegment(r, 4)))
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "metadata not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, items)
+	})
+}
+
+func allowRequest(w http.ResponseWriter, r *http.Request, cw *clockwork.Clockwork) bool {
+	if cw == nil || !cw.IsEnabled() {
+		http.NotFound(w, r)
+		return false
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
 }
 
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	body       *clockwork.BodyBuffer
 }
 
 func (w *responseWriter) WriteHeader(statusCode int) {
@@ -103,6 +236,12 @@ func (w *responseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+func (w *responseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	_, _ = w.body.Write(p[:n])
+	return n, err
+}
+
 func resolveMetadataID(r *http.Request, idHeader string) string {
 	if r == nil {
 		return ""
@@ -112,20 +251,32 @@ func resolveMetadataID(r *http.Request, idHeader string) string {
 			return headerID
 		}
 	}
+	return pathSegment(r, 2)
+}
+
+// pathSegment returns the segment at index of a /__clockwork/... request path.
+func pathSegment(r *http.Request, index int) string {
+	if r == nil || r.URL == nil {
+		return ""
+	}
 	path := strings.TrimSpace(strings.TrimSuffix(r.URL.Path, "/"))
 	if path == "" {
 		return ""
 	}
 	segments := strings.Split(path, "/")
-	if len(segments) < 3 || segments[1] != "__clockwork" {
+	if len(segments) <= index || segments[1] != "__clockwork" {
 		return ""
 	}
-	return strings.TrimSpace(segments[2])
+	return strings.TrimSpace(segments[index])
 }
 
-func writeJSONError(w http.ResponseWriter, status int, message string) {
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
 	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
 }
@@ -1,9 +1,11 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/RezaKargar/go-clockwork"
@@ -34,6 +36,28 @@ func TestMiddleware_ActivatesOnlyWhenHeaderPresent(t *testing.T) {
 	require.Equal(t, clockwork.ProtocolVersion, withHeader.Header().Get("X-Clockwork-Version"))
 }
 
+func TestMiddleware_PanickingHandlerLeavesRegistry(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	store := clockwork.NewInMemoryStorage(20, 1024*1024)
+	cw := clockwork.NewClockwork(cfg, store)
+
+	handler := Middleware(cw, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(cfg.HeaderName, "")
+	require.PanicsWithValue(t, "boom", func() { handler.ServeHTTP(res, req) })
+
+	require.False(t, cw.HasActiveTraces())
+	require.Zero(t, cw.CorrelationStats().ActiveCollectors)
+	meta, err := cw.GetMetadata(context.Background(), res.Header().Get(cfg.IDHeader))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, meta.ResponseStatus)
+}
+
 func TestMetadataHandler_ReturnsCapturedMetadata(t *testing.T) {
 	cfg := clockwork.DefaultConfig()
 	cfg.Normalize()
@@ -60,3 +84,158 @@ func TestMetadataHandler_ReturnsCapturedMetadata(t *testing.T) {
 	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &meta))
 	require.Equal(t, clockworkID, meta.ID)
 }
+
+func TestRegisterMetadataRoute_LatestPreviousNext(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	store := clockwork.NewInMemoryStorage(20, 1024*1024)
+	cw := clockwork.NewClockwork(cfg, store)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, cw.SaveMetadata(context.Background(), &clockwork.Metadata{ID: id}))
+	}
+
+	mux := http.NewServeMux()
+	RegisterMetadataRoute(mux, cw)
+
+	latestRes := httptest.NewRecorder()
+	mux.ServeHTTP(latestRes, httptest.NewRequest(http.MethodGet, "/__clockwork/latest", nil))
+	require.Equal(t, http.StatusOK, latestRes.Code)
+	var latest clockwork.Metadata
+	require.NoError(t, json.Unmarshal(latestRes.Body.Bytes(), &latest))
+	require.Equal(t, "c", latest.ID)
+
+	previousRes := httptest.NewRecorder()
+	mux.ServeHTTP(previousRes, httptest.NewRequest(http.MethodGet, "/__clockwork/c/previous", nil))
+	require.Equal(t, http.StatusOK, previousRes.Code)
+	var previous []clockwork.Metadata
+	require.NoError(t, json.Unmarshal(previousRes.Body.Bytes(), &previous))
+	require.Len(t, previous, 2)
+	require.Equal(t, "a", previous[0].ID)
+
+	nextRes := httptest.NewRecorder()
+	mux.ServeHTTP(nextRes, httptest.NewRequest(http.MethodGet, "/__clockwork/a/next/1", nil))
+	require.Equal(t, http.StatusOK, nextRes.Code)
+	var next []clockwork.Metadata
+	require.NoError(t, json.Unmarshal(nextRes.Body.Bytes(), &next))
+	require.Len(t, next, 1)
+	require.Equal(t, "b", next[0].ID)
+
+	missingRes := httptest.NewRecorder()
+	mux.ServeHTTP(missingRes, httptest.NewRequest(http.MethodGet, "/__clockwork/missing/next", nil))
+	require.Equal(t, http.StatusNotFound, missingRes.Code)
+}
+
+func TestRegisterMetadataRoute_Search(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(20, 1024*1024))
+
+	require.NoError(t, cw.SaveMetadata(context.Background(), &clockwork.Metadata{ID: "ok", URI: "/orders", ResponseStatus: 200}))
+	require.NoError(t, cw.SaveMetadata(context.Background(), &clockwork.Metadata{ID: "failed", URI: "/orders", ResponseStatus: 500}))
+
+	mux := http.NewServeMux()
+	RegisterMetadataRoute(mux, cw)
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/__clockwork/search?uri=/orders&status=500-599", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	var items []clockwork.Metadata
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &items))
+	require.Len(t, items, 1)
+	require.Equal(t, "failed", items[0].ID)
+
+	badRes := httptest.NewRecorder()
+	mux.ServeHTTP(badRes, httptest.NewRequest(http.MethodGet, "/__clockwork/search?status=bad", nil))
+	require.Equal(t, http.StatusBadRequest, badRes.Code)
+}
+
+func TestMiddleware_CapturesBodies(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.CaptureRequestBody = true
+	cfg.CaptureResponseBody = true
+	store := clockwork.NewInMemoryStorage(20, 1024*1024)
+	cw := clockwork.NewClockwork(cfg, store)
+
+	handler := Middleware(cw, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"token":"t-1"}`))
+	}))
+
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"jane","password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(cfg.HeaderName, "1")
+	handler.ServeHTTP(res, req)
+	require.JSONEq(t, `{"ok":true,"token":"t-1"}`, res.Body.String())
+
+	metadata, err := store.Get(context.Background(), res.Header().Get(cfg.IDHeader))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"user": "jane", "password": "[REDACTED]"}, metadata.RequestData)
+	require.Equal(t, map[string]interface{}{"ok": true, "token": "[REDACTED]"}, metadata.ResponseData)
+}
+
+func TestMiddleware_CapturesQueryCookiesAndSession(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	store := clockwork.NewInMemoryStorage(20, 1024*1024)
+	cw := clockwork.NewClockwork(cfg, store)
+	cw.SetSessionResolver(clockwork.SessionResolverFunc(func(ctx context.Context, r *http.Request) (*clockwork.AuthenticatedUser, map[string]interface{}) {
+		return &clockwork.AuthenticatedUser{ID: "7", Username: "jane"}, map[string]interface{}{"cart": 3}
+	}))
+
+	handler := Middleware(cw, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/search?q=shoes&tag=a&tag=b&access_token=t", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "abc"})
+	req.Header.Set(cfg.HeaderName, "1")
+	handler.ServeHTTP(res, req)
+
+	metadata, err := store.Get(context.Background(), res.Header().Get(cfg.IDHeader))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"q":            "shoes",
+		"tag":          []interface{}{"a", "b"},
+		"access_token": "[REDACTED]",
+	}, metadata.GetData)
+	require.Equal(t, map[string]string{"theme": "dark", "sid": "[REDACTED]"}, metadata.Cookies)
+	require.Equal(t, &clockwork.AuthenticatedUser{ID: "7", Username: "jane"}, metadata.AuthenticatedUser)
+	require.Equal(t, map[string]interface{}{"cart": 3}, metadata.SessionData)
+}
+
+func TestRegisterMetadataRoute_Profile(t *testing.T) {
+	cfg := clockwork.DefaultConfig()
+	cfg.ProfilingEnabled = true
+	cfg.Normalize()
+	cw := clockwork.NewClockwork(cfg, clockwork.NewInMemoryStorage(20, 16*1024*1024))
+
+	mux := http.NewServeMux()
+	mux.Handle("/report", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	RegisterMetadataRoute(mux, cw)
+	handler := Middleware(cw, mux)
+
+	captureRes := httptest.NewRecorder()
+	captureReq := httptest.NewRequest(http.MethodGet, "/report", nil)
+	captureReq.Header.Set(cfg.ProfileHeader, "allocs")
+	handler.ServeHTTP(captureRes, captureReq)
+	id := captureRes.Header().Get(cfg.IDHeader)
+	require.NotEmpty(t, id)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/__clockwork/"+id+"/profile?kind=allocs", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, "application/octet-stream", res.Header().Get("Content-Type"))
+	require.Contains(t, res.Header().Get("Content-Disposition"), id+"-allocs.pprof")
+	require.NotEmpty(t, res.Body.Bytes())
+
+	missing := httptest.NewRecorder()
+	handler.ServeHTTP(missing, httptest.NewRequest(http.MethodGet, "/__clockwork/"+id+"/profile?kind=cpu", nil))
+	require.Equal(t, http.StatusNotFound, missing.Code)
+}
@@ -3,20 +3,17 @@ package clockwork
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"go.opentelemetry.io/otel/trace"
 )
 
-// ShouldSkipPath returns true if the request path should not be profiled (e.g. favicon, __clockwork routes).
+// ShouldSkipPath returns true for Clockwork's own /__clockwork routes, which are never profiled.
+// Favicon requests are excluded by the default CapturePolicyConfig.ExcludePaths instead.
 func ShouldSkipPath(path string) bool {
 	path = strings.TrimSpace(strings.TrimSuffix(path, "/"))
-	if path == "" {
-		path = "/"
-	}
-	if strings.EqualFold(path, "/favicon.ico") || strings.HasSuffix(strings.ToLower(path), "/favicon.ico") {
-		return true
-	}
 	return strings.HasPrefix(path, "/__clockwork")
 }
 
@@ -54,30 +51,42 @@ func BuildRequestURL(req *http.Request) string {
 	return scheme + "://" + host + req.URL.RequestURI()
 }
 
-// ExtractSafeHeaders returns a safe subset of headers to avoid sensitive values.
+// ExtractSafeHeaders returns the headers recorded under the default redaction rules.
+// Adapters use (*Clockwork).Redactor().Headers so configured rules apply.
 func ExtractSafeHeaders(headers http.Header) map[string]string {
-	safeHeaders := make(map[string]string)
-	safeHeaderNames := map[string]bool{
-		"Content-Type":    true,
-		"Accept":          true,
-		"User-Agent":      true,
-		"Accept-Language": true,
-		"Accept-Encoding": true,
-		"X-Request-ID":    true,
-		"X-City-ID":       true,
-		"X-Stage":         true,
-		"X-App-Version":   true,
-		"Origin":          true,
-		"Referer":         true,
+	return defaultRedactor.Headers(headers)
+}
+
+// QueryData converts query parameters for Collector.SetGetData; repeated parameters become lists.
+func QueryData(values url.Values) map[string]interface{} {
+	if len(values) == 0 {
+		return nil
 	}
+	return formValues(values)
+}
 
-	for key, values := range headers {
-		if safeHeaderNames[key] && len(values) > 0 {
-			safeHeaders[key] = values[0]
+// CookieData converts request cookies for Collector.SetCookies, keeping the first value per name.
+func CookieData(cookies []*http.Cookie) map[string]string {
+	if len(cookies) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(cookies))
+	for _, cookie := range cookies {
+		if _, ok := out[cookie.Name]; !ok {
+			out[cookie.Name] = cookie.Value
 		}
 	}
+	return out
+}
 
-	return safeHeaders
+// ParseNavigationCount parses the optional count segment of the previous/next routes.
+// Empty, invalid, or non-positive values return 0 so storage applies its own default.
+func ParseNavigationCount(raw string) int {
+	count, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || count <= 0 {
+		return 0
+	}
+	return count
 }
 
 // TraceFromContext returns trace and span IDs from the context (OpenTelemetry).
@@ -90,19 +99,52 @@ func TraceFromContext(ctx context.Context) (traceID, spanID string) {
 	return spanCtx.TraceID().String(), spanCtx.SpanID().String()
 }
 
-// NewRequestCapture decides whether to capture this request and, if so, returns a new Collector.
-// path is used for skip logic (e.g. favicon, /__clockwork); uri is stored on the collector (e.g. request URI).
+// CaptureRequest decides whether to capture r and, if so, returns a new Collector.
+// Clockwork's own routes are always skipped; otherwise the active CapturePolicy is evaluated,
+// then Config.CaptureMode. With tail sampling enabled, requests may be collected here and
+// discarded by CompleteRequest when they turn out fast and successful.
 // Framework middleware should call this first; if ok is false, skip Clockwork and run the next handler.
-func NewRequestCapture(cw *Clockwork, method, path, uri string, headers http.Header) (*Collector, bool) {
-	if cw == nil || !cw.IsEnabled() {
+func CaptureRequest(cw *Clockwork, r *http.Request) (*Collector, bool) {
+	if cw == nil || !cw.IsEnabled() || r == nil || r.URL == nil {
 		return nil, false
 	}
-	if ShouldSkipPath(path) || !ShouldCapture(headers, cw.Config().HeaderName) {
+	if ShouldSkipPath(r.URL.Path) {
 		return nil, false
 	}
-	collector := cw.NewCollector(method, uri)
+	decision := cw.decideCapture(r)
+	if decision == captureNone {
+		return nil, false
+	}
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+	collector := cw.NewCollector(r.Method, uri)
 	if collector == nil {
 		return nil, false
 	}
+	collector.tailSampled = decision == captureTail
+	collector.request = r
+	collector.SetParent(strings.TrimSpace(r.Header.Get(ParentIDHeader)))
+	if cw.profileRequested(r) {
+		cw.startProfile(collector, r.Header.Get(cw.config.ProfileHeader))
+	}
 	return collector, true
 }
+
+// NewRequestCapture is CaptureRequest for adapters that have no *http.Request.
+// path is used for policy evaluation; uri is stored on the collector (e.g. request URI).
+// The request has no remote address, so AllowIPs denies it; adapters that know the client
+// address should build an *http.Request with RemoteAddr set and call CaptureRequest instead.
+func NewRequestCapture(cw *Clockwork, method, path, uri string, headers http.Header) (*Collector, bool) {
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	r := &http.Request{
+		Method:     method,
+		URL:        &url.URL{Path: path},
+		RequestURI: uri,
+		Header:     headers,
+	}
+	return CaptureRequest(cw, r)
+}
@@ -0,0 +1,60 @@
+package clockwork
+
+// Model actions accepted by Collector.AddModelAction. Each has a count map in Metadata.
+const (
+	ModelRetrieved = "retrieved"
+	ModelCreated   = "created"
+	ModelUpdated   = "updated"
+	ModelDeleted   = "deleted"
+)
+
+// AddModelAction records an ORM model event such as rows of a model being retrieved or
+// created. Count defaults to 1 and Timestamp to now. Events count toward the per-model
+// totals even when the list is full.
+func (c *Collector) AddModelAction(action ModelAction) {
+	if c == nil || action.Model == "" || action.Action == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if action.Count <= 0 {
+		action.Count = 1
+	}
+	if c.modelCounts == nil {
+		c.modelCounts = make(map[string]map[string]int, 4)
+	}
+	counts := c.modelCounts[action.Action]
+	if counts == nil {
+		counts = make(map[string]int)
+		c.modelCounts[action.Action] = counts
+	}
+	counts[action.Model] += action.Count
+
+	if !c.reserveLocked("models", c.limits.maxDBQueries, len(c.modelActions), len(action.Model)+len(action.Query)+96) {
+		return
+	}
+	action.Model = c.truncate(action.Model)
+	action.Action = c.truncate(action.Action)
+	action.Query = c.truncate(action.Query)
+	action.Connection = c.truncate(action.Connection)
+	action.File = c.truncate(action.File)
+	if action.Key != nil {
+		action.Key = c.normalizeBinding(bindingValue(action.Key))
+	}
+	if action.Timestamp == 0 {
+		action.Timestamp = unixTimestamp()
+	}
+	c.modelActions = append(c.modelActions, action)
+}
+
+func copyCounts(in map[string]int) map[string]int {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]int, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
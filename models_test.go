@@ -0,0 +1,24 @@
+package clockwork
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector_AddModelAction(t *testing.T) {
+	collector := NewCollector("GET", "/users", collectorLimits{maxDBQueries: 2})
+	collector.AddModelAction(ModelAction{Model: "User", Action: ModelRetrieved, Count: 3, Query: "SELECT * FROM users"})
+	collector.AddModelAction(ModelAction{Model: "User", Action: ModelUpdated, Key: int64(7)})
+	collector.AddModelAction(ModelAction{Model: "User", Action: ModelRetrieved})
+	collector.AddModelAction(ModelAction{Model: "", Action: ModelDeleted})
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.ModelsActions, 2)
+	require.Equal(t, int64(7), meta.ModelsActions[1].Key)
+	require.NotZero(t, meta.ModelsActions[0].Timestamp)
+	require.Equal(t, map[string]int{"User": 4}, meta.ModelsRetrieved, "dropped actions still count")
+	require.Equal(t, map[string]int{"User": 1}, meta.ModelsUpdated)
+	require.Nil(t, meta.ModelsDeleted)
+	require.Equal(t, 1, meta.Dropped["models"])
+}
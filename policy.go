@@ -0,0 +1,252 @@
+package clockwork
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// CaptureVerdict is the outcome of evaluating a CapturePolicy.
+type CaptureVerdict int
+
+const (
+	// CaptureAbstain leaves the decision to the next policy and then to Config.CaptureMode.
+	CaptureAbstain CaptureVerdict = iota
+	// CaptureAllow captures the request regardless of Config.CaptureMode.
+	CaptureAllow
+	// CaptureDeny never captures the request.
+	CaptureDeny
+)
+
+// CapturePolicy decides whether a request may be captured.
+// Install one with Clockwork.SetCapturePolicy; CaptureRequest evaluates it before the capture mode.
+type CapturePolicy interface {
+	Evaluate(r *http.Request) CaptureVerdict
+}
+
+// CapturePolicyFunc adapts a function to CapturePolicy.
+type CapturePolicyFunc func(r *http.Request) CaptureVerdict
+
+// Evaluate calls f(r).
+func (f CapturePolicyFunc) Evaluate(r *http.Request) CaptureVerdict {
+	return f(r)
+}
+
+// CapturePolicyConfig configures the built-in capture policies. See PolicyFromConfig.
+type CapturePolicyConfig struct {
+	// IncludePaths, when set, denies requests whose path matches none of the globs.
+	IncludePaths []string `mapstructure:"include_paths"`
+	// ExcludePaths denies requests whose path matches any glob. Nil defaults to favicon requests.
+	ExcludePaths []string `mapstructure:"exclude_paths"`
+	// MatchHeaders allows requests carrying a header whose value matches the glob ("" matches any value).
+	MatchHeaders map[string]string `mapstructure:"match_headers"`
+	// MatchCookies allows requests carrying a cookie whose value matches the glob ("" matches any value).
+	MatchCookies map[string]string `mapstructure:"match_cookies"`
+	// AllowIPs, when set, denies requests whose remote address is outside the listed IPs or CIDRs.
+	AllowIPs []string `mapstructure:"allow_ips"`
+	// ExcludeUserAgents denies requests whose User-Agent matches any glob.
+	ExcludeUserAgents []string `mapstructure:"exclude_user_agents"`
+}
+
+// PolicyFromConfig builds the policy chain described by cfg. Deny rules are evaluated
+// before allow rules: exclude paths, include paths, IP allow-list, user agents, then
+// header and cookie matches.
+func PolicyFromConfig(cfg CapturePolicyConfig) CapturePolicy {
+	policies := make([]CapturePolicy, 0, 6)
+	if len(cfg.ExcludePaths) > 0 {
+		policies = append(policies, ExcludePaths(cfg.ExcludePaths...))
+	}
+	if len(cfg.IncludePaths) > 0 {
+		policies = append(policies, IncludePaths(cfg.IncludePaths...))
+	}
+	if len(cfg.AllowIPs) > 0 {
+		policies = append(policies, AllowIPs(cfg.AllowIPs...))
+	}
+	if len(cfg.ExcludeUserAgents) > 0 {
+		policies = append(policies, ExcludeUserAgents(cfg.ExcludeUserAgents...))
+	}
+	for name, pattern := range cfg.MatchHeaders {
+		policies = append(policies, MatchHeader(name, pattern))
+	}
+	for name, pattern := range cfg.MatchCookies {
+		policies = append(policies, MatchCookie(name, pattern))
+	}
+	return ChainPolicies(policies...)
+}
+
+// ChainPolicies evaluates policies in order and returns the first verdict that is not CaptureAbstain.
+func ChainPolicies(policies ...CapturePolicy) CapturePolicy {
+	chain := make([]CapturePolicy, 0, len(policies))
+	for _, policy := range policies {
+		if policy != nil {
+			chain = append(chain, policy)
+		}
+	}
+	return CapturePolicyFunc(func(r *http.Request) CaptureVerdict {
+		for _, policy := range chain {
+			if verdict := policy.Evaluate(r); verdict != CaptureAbstain {
+				return verdict
+			}
+		}
+		return CaptureAbstain
+	})
+}
+
+// ExcludePaths denies requests whose path matches any of the globs. * matches across "/".
+func ExcludePaths(patterns ...string) CapturePolicy {
+	matchers := compileGlobs(patterns)
+	return CapturePolicyFunc(func(r *http.Request) CaptureVerdict {
+		if matchAny(matchers, requestPath(r)) {
+			return CaptureDeny
+		}
+		return CaptureAbstain
+	})
+}
+
+// IncludePaths denies requests whose path matches none of the globs.
+func IncludePaths(patterns ...string) CapturePolicy {
+	matchers := compileGlobs(patterns)
+	return CapturePolicyFunc(func(r *http.Request) CaptureVerdict {
+		if matchAny(matchers, requestPath(r)) {
+			return CaptureAbstain
+		}
+		return CaptureDeny
+	})
+}
+
+// MatchHeader allows requests carrying header name with a value matching pattern.
+// An empty pattern matches any value, including an empty one.
+func MatchHeader(name, pattern string) CapturePolicy {
+	name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+	matcher := compileValuePattern(pattern)
+	return CapturePolicyFunc(func(r *http.Request) CaptureVerdict {
+		if r == nil || name == "" {
+			return CaptureAbstain
+		}
+		for _, value := range r.Header.Values(name) {
+			if matcher(value) {
+				return CaptureAllow
+			}
+		}
+		return CaptureAbstain
+	})
+}
+
+// MatchCookie allows requests carrying cookie name with a value matching pattern.
+// Cookie names are compared case-insensitively because config keys are lowercased.
+func MatchCookie(name, pattern string) CapturePolicy {
+	name = strings.TrimSpace(name)
+	matcher := compileValuePattern(pattern)
+	return CapturePolicyFunc(func(r *http.Request) CaptureVerdict {
+		if r == nil || name == "" {
+			return CaptureAbstain
+		}
+		for _, cookie := range r.Cookies() {
+			if strings.EqualFold(cookie.Name, name) && matcher(cookie.Value) {
+				return CaptureAllow
+			}
+		}
+		return CaptureAbstain
+	})
+}
+
+// AllowIPs denies requests whose remote address is not one of the listed IPs or CIDR ranges.
+// Only r.RemoteAddr is consulted; forwarding headers are not trusted. Invalid entries are ignored.
+func AllowIPs(entries ...string) CapturePolicy {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	return CapturePolicyFunc(func(r *http.Request) CaptureVerdict {
+		addr, ok := remoteAddr(r)
+		if !ok {
+			return CaptureDeny
+		}
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return CaptureAbstain
+			}
+		}
+		return CaptureDeny
+	})
+}
+
+// ExcludeUserAgents denies requests whose User-Agent matches any of the globs (case-insensitive).
+func ExcludeUserAgents(patterns ...string) CapturePolicy {
+	matchers := compileGlobs(patterns)
+	return CapturePolicyFunc(func(r *http.Request) CaptureVerdict {
+		if r != nil && matchAny(matchers, r.UserAgent()) {
+			return CaptureDeny
+		}
+		return CaptureAbstain
+	})
+}
+
+func requestPath(r *http.Request) string {
+	if r == nil || r.URL == nil {
+		return ""
+	}
+	path := strings.TrimSpace(strings.TrimSuffix(r.URL.Path, "/"))
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
+
+func remoteAddr(r *http.Request) (netip.Addr, bool) {
+	if r == nil {
+		return netip.Addr{}, false
+	}
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(strings.Trim(host, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func compileGlobs(patterns []string) []*regexp.Regexp {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		re := compileGlob(pattern)
+		if re == nil {
+			re = regexp.MustCompile("(?i)^" + regexp.QuoteMeta(pattern) + "$")
+		}
+		out = append(out, re)
+	}
+	return out
+}
+
+func compileValuePattern(pattern string) func(string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return func(string) bool { return true }
+	}
+	matchers := compileGlobs([]string{pattern})
+	return func(value string) bool { return matchAny(matchers, value) }
+}
+
+func matchAny(matchers []*regexp.Regexp, value string) bool {
+	for _, re := range matchers {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
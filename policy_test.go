@@ -0,0 +1,61 @@
+package clockwork
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapturePolicies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/orders/42/", nil)
+	req.RemoteAddr = "10.1.2.3:5555"
+	req.Header.Set("User-Agent", "kube-probe/1.29")
+	req.Header.Set("X-Tenant", "acme-eu")
+	req.AddCookie(&http.Cookie{Name: "staff", Value: "1"})
+
+	require.Equal(t, CaptureDeny, ExcludePaths("/orders/*").Evaluate(req))
+	require.Equal(t, CaptureAbstain, ExcludePaths("/users/*").Evaluate(req))
+	require.Equal(t, CaptureAbstain, IncludePaths("/orders/*").Evaluate(req))
+	require.Equal(t, CaptureDeny, IncludePaths("/users/*").Evaluate(req))
+	require.Equal(t, CaptureAllow, MatchHeader("x-tenant", "acme-*").Evaluate(req))
+	require.Equal(t, CaptureAbstain, MatchHeader("X-Tenant", "globex").Evaluate(req))
+	require.Equal(t, CaptureAllow, MatchCookie("STAFF", "").Evaluate(req))
+	require.Equal(t, CaptureAbstain, MatchCookie("admin", "").Evaluate(req))
+	require.Equal(t, CaptureAbstain, AllowIPs("10.0.0.0/8").Evaluate(req))
+	require.Equal(t, CaptureDeny, AllowIPs("192.168.0.1", "not-an-ip").Evaluate(req))
+	require.Equal(t, CaptureDeny, ExcludeUserAgents("kube-probe/*").Evaluate(req))
+
+	chain := ChainPolicies(ExcludeUserAgents("curl/*"), MatchCookie("staff", "1"), ExcludePaths("/orders/*"))
+	require.Equal(t, CaptureAllow, chain.Evaluate(req))
+}
+
+func TestCaptureRequest_UsesPolicy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CaptureMode = CaptureModeAlways
+	cfg.CapturePolicy.ExcludePaths = []string{"/health", "*/favicon.ico"}
+	cw := NewClockwork(cfg, NewInMemoryStorage(10, 1024*1024))
+
+	_, ok := CaptureRequest(cw, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.False(t, ok)
+	_, ok = CaptureRequest(cw, httptest.NewRequest(http.MethodGet, "/static/favicon.ico", nil))
+	require.False(t, ok)
+	collector, ok := CaptureRequest(cw, httptest.NewRequest(http.MethodGet, "/orders?page=2", nil))
+	require.True(t, ok)
+	require.Equal(t, "/orders?page=2", collector.GetMetadata().URI)
+
+	cw.SetCapturePolicy(CapturePolicyFunc(func(r *http.Request) CaptureVerdict {
+		if r.Header.Get("X-Staff") == "" {
+			return CaptureDeny
+		}
+		return CaptureAbstain
+	}))
+	_, ok = CaptureRequest(cw, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	require.False(t, ok)
+
+	staff := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	staff.Header.Set("X-Staff", "yes")
+	_, ok = CaptureRequest(cw, staff)
+	require.True(t, ok)
+}
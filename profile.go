@@ -0,0 +1,211 @@
+package clockwork
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profile kinds accepted in the profile header and served by GET /__clockwork/:id/profile.
+const (
+	// ProfileCPU is a CPU profile covering the request.
+	ProfileCPU = "cpu"
+	// ProfileAllocs is the process allocation profile (alloc_objects/alloc_space) when the
+	// request completed.
+	ProfileAllocs = "allocs"
+	// ProfileAllocsBase is the allocation profile when the request started. Pass it to
+	// go tool pprof -base with ProfileAllocs to see the allocations made in between.
+	ProfileAllocsBase = "allocs_base"
+)
+
+// ProfileStorage is implemented by storages that keep pprof profiles next to metadata.
+// Profiles are stored after their metadata and expire with it.
+type ProfileStorage interface {
+	StoreProfile(ctx context.Context, id, kind string, data []byte) error
+	GetProfile(ctx context.Context, id, kind string) ([]byte, error)
+}
+
+// cpuProfileMu serializes CPU profiling, which is process-wide in the Go runtime.
+var cpuProfileMu sync.Mutex
+
+// profileSession is a profile in progress for one request.
+type profileSession struct {
+	mu       sync.Mutex
+	cpu      *bytes.Buffer
+	cpuTimer *time.Timer
+	allocs   []byte // allocation profile at the start of the request
+	done     bool
+	profiles map[string][]byte
+}
+
+// profileRequested reports whether profiling is enabled and r carries the profile header.
+func (c *Clockwork) profileRequested(r *http.Request) bool {
+	return c.config.ProfilingEnabled && ShouldCapture(r.Header, c.config.ProfileHeader)
+}
+
+// parseProfileKinds reads a profile header value: a comma-separated list of kinds,
+// where any other non-empty value (e.g. "1") means cpu.
+func parseProfileKinds(value string) map[string]bool {
+	kinds := make(map[string]bool, 2)
+	for _, item := range strings.Split(value, ",") {
+		switch kind := strings.ToLower(strings.TrimSpace(item)); kind {
+		case ProfileCPU, ProfileAllocs:
+			kinds[kind] = true
+		case "":
+		default:
+			kinds[ProfileCPU] = true
+		}
+	}
+	if len(kinds) == 0 {
+		kinds[ProfileCPU] = true
+	}
+	return kinds
+}
+
+// startProfile begins the profiles requested by value. A CPU profile is skipped (and noted in
+// the log tab) while another request is being CPU profiled. Profiles stop on their own after
+// Config.ProfileMaxDuration in case the request never completes.
+func (c *Clockwork) startProfile(collector *Collector, value string) {
+	kinds := parseProfileKinds(value)
+	session := &profileSession{}
+
+	if kinds[ProfileAllocs] {
+		if data, err := allocsSnapshot(false); err == nil {
+			session.allocs = data
+		} else {
+			collector.AddLogEntry("warning", "clockwork allocation profile not started", map[string]interface{}{"error": err.Error()})
+		}
+	}
+	if kinds[ProfileCPU] {
+		if cpuProfileMu.TryLock() {
+			session.cpu = new(bytes.Buffer)
+			if err := pprof.StartCPUProfile(session.cpu); err != nil {
+				cpuProfileMu.Unlock()
+				session.cpu = nil
+				collector.AddLogEntry("warning", "clockwork cpu profile not started", map[string]interface{}{"error": err.Error()})
+			} else {
+				session.cpuTimer = time.AfterFunc(c.config.ProfileMaxDuration, func() { session.stop() })
+			}
+		} else {
+			collector.AddLogEntry("warning", "clockwork cpu profile skipped: another request is being profiled", nil)
+		}
+	}
+
+	if session.cpu != nil || session.allocs != nil {
+		collector.mu.Lock()
+		collector.profile = session
+		collector.mu.Unlock()
+	}
+}
+
+// stop ends the session once and returns the encoded profiles by kind.
+func (s *profileSession) stop() map[string][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return s.profiles
+	}
+	s.done = true
+	s.profiles = make(map[string][]byte, 3)
+
+	if s.cpu != nil {
+		if s.cpuTimer != nil {
+			s.cpuTimer.Stop()
+		}
+		pprof.StopCPUProfile()
+		cpuProfileMu.Unlock()
+		s.profiles[ProfileCPU] = s.cpu.Bytes()
+	}
+	if s.allocs != nil {
+		if data, err := allocsSnapshot(true); err == nil {
+			s.profiles[ProfileAllocs] = data
+			s.profiles[ProfileAllocsBase] = s.allocs
+		}
+	}
+	return s.profiles
+}
+
+// finishProfile stops the collector's profiles and lists their kinds in the metadata.
+func (c *Clockwork) finishProfile(collector *Collector) map[string][]byte {
+	collector.mu.Lock()
+	session := collector.profile
+	collector.profile = nil
+	collector.mu.Unlock()
+	if session == nil {
+		return nil
+	}
+
+	profiles := session.stop()
+	collector.mu.Lock()
+	for _, kind := range []string{ProfileCPU, ProfileAllocs, ProfileAllocsBase} {
+		if len(profiles[kind]) > 0 {
+			collector.profiles = append(collector.profiles, kind)
+		}
+	}
+	collector.mu.Unlock()
+	return profiles
+}
+
+// storeProfiles saves profiles when the storage supports it.
+func (c *Clockwork) storeProfiles(ctx context.Context, id string, profiles map[string][]byte) error {
+	store, ok := c.storage.(ProfileStorage)
+	if !ok || len(profiles) == 0 {
+		return nil
+	}
+	for kind, data := range profiles {
+		if len(data) == 0 {
+			continue
+		}
+		if err := store.StoreProfile(ctx, id, kind, data); err != nil {
+			return fmt.Errorf("store %s profile: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// GetProfile fetches a stored pprof profile (ProfileCPU, ProfileAllocs or ProfileAllocsBase) for a request.
+func (c *Clockwork) GetProfile(ctx context.Context, id, kind string) ([]byte, error) {
+	if c == nil || c.storage == nil {
+		return nil, fmt.Errorf("clockwork storage is not configured")
+	}
+	store, ok := c.storage.(ProfileStorage)
+	if !ok {
+		return nil, fmt.Errorf("clockwork storage does not support profiles")
+	}
+	if kind == "" {
+		kind = ProfileCPU
+	}
+	if kind != ProfileCPU && kind != ProfileAllocs && kind != ProfileAllocsBase {
+		return nil, fmt.Errorf("unknown profile kind: %s", kind)
+	}
+	return store.GetProfile(ctx, id, kind)
+}
+
+// ProfileFilename returns the download name for a request's profile, e.g. "<id>-cpu.pprof".
+func ProfileFilename(id, kind string) string {
+	if kind == "" {
+		kind = ProfileCPU
+	}
+	return id + "-" + kind + ".pprof"
+}
+
+// allocsSnapshot writes the process allocation profile in pprof format. The runtime
+// publishes allocations at the end of a GC cycle, so with collect set a collection runs
+// first to include every allocation up to now. The start snapshot skips it, so allocations
+// made since the last cycle before the request show up in the request's diff.
+func allocsSnapshot(collect bool) ([]byte, error) {
+	if collect {
+		runtime.GC()
+	}
+	var buf bytes.Buffer
+	if err := pprof.Lookup("allocs").WriteTo(&buf, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,108 @@
+package clockwork
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProfileKinds(t *testing.T) {
+	require.Equal(t, map[string]bool{ProfileCPU: true}, parseProfileKinds("1"))
+	require.Equal(t, map[string]bool{ProfileCPU: true}, parseProfileKinds(""))
+	require.Equal(t, map[string]bool{ProfileAllocs: true}, parseProfileKinds("allocs"))
+	require.Equal(t, map[string]bool{ProfileCPU: true, ProfileAllocs: true}, parseProfileKinds("CPU, allocs"))
+}
+
+func TestCompleteRequest_StoresProfiles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ProfilingEnabled = true
+	cw := NewClockwork(cfg, NewInMemoryStorage(10, 16*1024*1024))
+	ctx := context.Background()
+
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	req.Header.Set(cfg.ProfileHeader, "cpu,allocs")
+	collector, ok := CaptureRequest(cw, req)
+	require.True(t, ok, "profile header alone captures the request")
+
+	sink := make([][]byte, 0, 64)
+	for i := 0; i < 64; i++ {
+		sink = append(sink, make([]byte, 4096))
+	}
+	require.Len(t, sink, 64)
+
+	require.NoError(t, cw.CompleteRequest(ctx, collector, http.StatusOK, 20*time.Millisecond))
+
+	meta, err := cw.GetMetadata(ctx, collector.ID())
+	require.NoError(t, err)
+	require.Equal(t, []string{ProfileCPU, ProfileAllocs, ProfileAllocsBase}, meta.Profiles)
+
+	for _, kind := range []string{ProfileCPU, ProfileAllocs, ProfileAllocsBase} {
+		data, err := cw.GetProfile(ctx, collector.ID(), kind)
+		require.NoError(t, err, kind)
+		require.Greater(t, len(data), 2, kind)
+		require.Equal(t, []byte{0x1f, 0x8b}, data[:2], "%s profile is gzipped", kind)
+	}
+
+	_, err = cw.GetProfile(ctx, collector.ID(), "heap")
+	require.Error(t, err)
+	require.Equal(t, collector.ID()+"-allocs.pprof", ProfileFilename(collector.ID(), ProfileAllocs))
+}
+
+var profileSink [][]byte
+
+//go:noinline
+func allocateForProfile() {
+	for i := 0; i < 256; i++ {
+		profileSink = append(profileSink, make([]byte, 64*1024))
+	}
+}
+
+func TestCompleteRequest_AllocsProfileDiffsWithPprof(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	cfg := DefaultConfig()
+	cfg.ProfilingEnabled = true
+	cw := NewClockwork(cfg, NewInMemoryStorage(10, 16*1024*1024))
+	ctx := context.Background()
+
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	req.Header.Set(cfg.ProfileHeader, "allocs")
+	collector, ok := CaptureRequest(cw, req)
+	require.True(t, ok)
+	allocateForProfile()
+	defer func() { profileSink = nil }()
+	require.NoError(t, cw.CompleteRequest(ctx, collector, http.StatusOK, time.Millisecond))
+
+	dir := t.TempDir()
+	paths := make(map[string]string, 2)
+	for _, kind := range []string{ProfileAllocs, ProfileAllocsBase} {
+		data, err := cw.GetProfile(ctx, collector.ID(), kind)
+		require.NoError(t, err, kind)
+		paths[kind] = filepath.Join(dir, ProfileFilename(collector.ID(), kind))
+		require.NoError(t, os.WriteFile(paths[kind], data, 0o600))
+	}
+
+	out, err := exec.Command(goTool, "tool", "pprof", "-top", "-sample_index=alloc_space",
+		"-base", paths[ProfileAllocsBase], paths[ProfileAllocs]).CombinedOutput()
+	require.NoError(t, err, string(out))
+	require.Contains(t, string(out), "allocateForProfile")
+	require.NotContains(t, string(out), "runtime.mallocgc")
+}
+
+func TestCaptureRequest_ProfilingDisabled(t *testing.T) {
+	cw := NewClockwork(DefaultConfig(), NewInMemoryStorage(10, 1024*1024))
+
+	req := httptest.NewRequest(http.MethodGet, "/report", nil)
+	req.Header.Set("X-Clockwork-Profile", "cpu")
+	_, ok := CaptureRequest(cw, req)
+	require.False(t, ok)
+}
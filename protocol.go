@@ -2,3 +2,14 @@ package clockwork
 
 // ProtocolVersion is the Clockwork protocol version reported in response headers.
 const ProtocolVersion = "5.3.5"
+
+// ParentIDHeader carries the calling request's Clockwork ID to downstream services,
+// which record it as Metadata.Parent.
+const ParentIDHeader = "X-Clockwork-Parent-Id"
+
+// PathHeader lets a downstream service advertise where its metadata is served;
+// subrequests without it default to DefaultPath.
+const PathHeader = "X-Clockwork-Path"
+
+// DefaultPath is the metadata route prefix served by the adapters.
+const DefaultPath = "/__clockwork/"
@@ -0,0 +1,213 @@
+package clockwork
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query filters stored metadata. Zero-valued fields are ignored, so an empty
+// Query matches every entry.
+type Query struct {
+	// URI matches as a case-insensitive substring, or as a glob when it contains *, ? or [.
+	URI    string
+	Method string
+	// StatusMin and StatusMax bound ResponseStatus inclusively.
+	StatusMin int
+	StatusMax int
+	// MinDuration is compared against ResponseDuration.
+	MinDuration        time.Duration
+	MinDatabaseQueries int
+	// From and To bound the request start time inclusively.
+	From       time.Time
+	To         time.Time
+	Controller string
+	TraceID    string
+	// Limit caps the number of results; storage applies its default when <= 0.
+	Limit int
+}
+
+// Matcher compiles the query into a predicate. Use it when matching many entries.
+func (q Query) Matcher() func(*Metadata) bool {
+	uriGlob := compileGlob(q.URI)
+	uriNeedle := strings.ToLower(strings.TrimSpace(q.URI))
+	controller := strings.ToLower(strings.TrimSpace(q.Controller))
+	minDurationMs := DurationMs(q.MinDuration)
+	from := 0.0
+	if !q.From.IsZero() {
+		from = unixFromTime(q.From)
+	}
+	to := 0.0
+	if !q.To.IsZero() {
+		to = unixFromTime(q.To)
+	}
+
+	return func(m *Metadata) bool {
+		if m == nil {
+			return false
+		}
+		if uriGlob != nil {
+			if !uriGlob.MatchString(m.URI) {
+				return false
+			}
+		} else if uriNeedle != "" && !strings.Contains(strings.ToLower(m.URI), uriNeedle) {
+			return false
+		}
+		if q.Method != "" && !strings.EqualFold(q.Method, m.Method) {
+			return false
+		}
+		if q.StatusMin > 0 && m.ResponseStatus < q.StatusMin {
+			return false
+		}
+		if q.StatusMax > 0 && m.ResponseStatus > q.StatusMax {
+			return false
+		}
+		if minDurationMs > 0 && m.ResponseDuration < minDurationMs {
+			return false
+		}
+		if q.MinDatabaseQueries > 0 && m.DatabaseQueriesCount < q.MinDatabaseQueries {
+			return false
+		}
+		if from > 0 && m.Time < from {
+			return false
+		}
+		if to > 0 && m.Time > to {
+			return false
+		}
+		if controller != "" && !strings.Contains(strings.ToLower(m.Controller), controller) {
+			return false
+		}
+		if q.TraceID != "" && q.TraceID != m.TraceID {
+			return false
+		}
+		return true
+	}
+}
+
+// Matches reports whether m satisfies every criterion of the query.
+func (q Query) Matches(m *Metadata) bool {
+	return q.Matcher()(m)
+}
+
+// ParseQuery builds a Query from URL parameters as accepted by GET /__clockwork/search:
+// uri, method, status (e.g. "500" or "400-599"), min_duration (milliseconds or a Go
+// duration such as "250ms"), min_queries, from and to (RFC 3339 or unix seconds),
+// controller, trace_id and limit.
+func ParseQuery(values url.Values) (Query, error) {
+	q := Query{
+		URI:        strings.TrimSpace(values.Get("uri")),
+		Method:     strings.TrimSpace(values.Get("method")),
+		Controller: strings.TrimSpace(values.Get("controller")),
+		TraceID:    strings.TrimSpace(values.Get("trace_id")),
+	}
+
+	if raw := strings.TrimSpace(values.Get("status")); raw != "" {
+		low, high, found := strings.Cut(raw, "-")
+		minStatus, err := strconv.Atoi(strings.TrimSpace(low))
+		if err != nil {
+			return Query{}, fmt.Errorf("invalid status %q", raw)
+		}
+		maxStatus := minStatus
+		if found {
+			if maxStatus, err = strconv.Atoi(strings.TrimSpace(high)); err != nil {
+				return Query{}, fmt.Errorf("invalid status %q", raw)
+			}
+		}
+		q.StatusMin, q.StatusMax = minStatus, maxStatus
+	}
+	if raw := strings.TrimSpace(values.Get("min_duration")); raw != "" {
+		d, err := parseQueryDuration(raw)
+		if err != nil {
+			return Query{}, fmt.Errorf("invalid min_duration %q", raw)
+		}
+		q.MinDuration = d
+	}
+	if raw := strings.TrimSpace(values.Get("min_queries")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return Query{}, fmt.Errorf("invalid min_queries %q", raw)
+		}
+		q.MinDatabaseQueries = n
+	}
+	if raw := strings.TrimSpace(values.Get("from")); raw != "" {
+		t, err := parseQueryTime(raw)
+		if err != nil {
+			return Query{}, fmt.Errorf("invalid from %q", raw)
+		}
+		q.From = t
+	}
+	if raw := strings.TrimSpace(values.Get("to")); raw != "" {
+		t, err := parseQueryTime(raw)
+		if err != nil {
+			return Query{}, fmt.Errorf("invalid to %q", raw)
+		}
+		q.To = t
+	}
+	if raw := strings.TrimSpace(values.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return Query{}, fmt.Errorf("invalid limit %q", raw)
+		}
+		q.Limit = n
+	}
+
+	return q, nil
+}
+
+func parseQueryDuration(raw string) (time.Duration, error) {
+	if ms, err := strconv.ParseFloat(raw, 64); err == nil {
+		return time.Duration(ms * float64(time.Millisecond)), nil
+	}
+	return time.ParseDuration(raw)
+}
+
+func parseQueryTime(raw string) (time.Time, error) {
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		return time.Unix(0, int64(seconds*1e9)), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// compileGlob returns nil when pattern has no glob metacharacters.
+// Unlike path.Match, * also matches across "/".
+func compileGlob(pattern string) *regexp.Regexp {
+	pattern = strings.TrimSpace(pattern)
+	if !strings.ContainsAny(pattern, "*?[") {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end <= 1 {
+				b.WriteString(regexp.QuoteMeta(string(ch)))
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+	return re
+}
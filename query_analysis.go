@@ -0,0 +1,206 @@
+package clockwork
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Query warning types reported in Metadata.QueryWarnings.
+const (
+	// QueryWarningNPlusOne marks similar queries repeated from one call site, typically a query in a loop.
+	QueryWarningNPlusOne = "n+1"
+	// QueryWarningDuplicate marks an identical statement executed more than once.
+	QueryWarningDuplicate = "duplicate"
+)
+
+// defaultNPlusOneThreshold is used when the collector was built without Config.NPlusOneThreshold.
+const defaultNPlusOneThreshold = 5
+
+// QueryWarning describes a group of database queries flagged by query analysis.
+type QueryWarning struct {
+	Type string `json:"type"`
+	// Query is the fingerprint for n+1 warnings and the statement for duplicates.
+	Query    string  `json:"query"`
+	Count    int     `json:"count"`
+	Duration float64 `json:"duration"`
+	File     string  `json:"file,omitempty"`
+	Line     int     `json:"line,omitempty"`
+}
+
+var (
+	inListPattern    = regexp.MustCompile(`(?i)\bin \(\?(?:, \?)+\)`)
+	valuesRowPattern = regexp.MustCompile(`\(\?(?:, \?)*\)(?:, \(\?(?:, \?)*\))+`)
+)
+
+// FingerprintQuery normalizes a SQL statement so queries differing only in literal values
+// compare equal: string and numeric literals and placeholders become "?", IN lists and
+// multi-row VALUES collapse to one item, comments are dropped, whitespace is collapsed and
+// text outside quoted identifiers is lower-cased.
+// Backslashes in string literals are not escapes, as in standard SQL.
+func FingerprintQuery(query string) string {
+	return fingerprintQuery(query, "")
+}
+
+// fingerprintQuery is FingerprintQuery for a dialect; MySQL string literals use backslash escapes.
+func fingerprintQuery(query, dialect string) string {
+	toks := tokenizeSQL(query, dialect)
+	var b strings.Builder
+	b.Grow(len(query))
+	prev := ""
+	for i, tok := range toks {
+		text := tok.raw
+		switch tok.kind {
+		case sqlWord:
+			text = strings.ToLower(text)
+		case sqlValue:
+			text = "?"
+		}
+		if i > 0 && prev != "(" && prev != "." && text != ")" && text != "," && text != "." {
+			b.WriteByte(' ')
+		}
+		b.WriteString(text)
+		prev = text
+	}
+
+	out := inListPattern.ReplaceAllString(b.String(), "in (?)")
+	return valuesRowPattern.ReplaceAllStringFunc(out, func(rows string) string {
+		first, _, _ := strings.Cut(rows, "), (")
+		return first + ")"
+	})
+}
+
+type queryGroup struct {
+	query    string
+	file     string
+	line     int
+	first    int
+	count    int
+	duration float64
+}
+
+// analyzeQueries groups queries by fingerprint and call site and by exact statement and
+// bindings. A call-site group reaching threshold is an N+1 warning; statements repeated
+// with the same bindings outside those groups are duplicate warnings. Warnings are ordered
+// by first occurrence. A negative threshold disables analysis.
+func analyzeQueries(queries []DatabaseQuery, threshold int) []QueryWarning {
+	if threshold < 0 || len(queries) < 2 {
+		return nil
+	}
+	if threshold == 0 {
+		threshold = defaultNPlusOneThreshold
+	}
+	if threshold < 2 {
+		threshold = 2
+	}
+
+	type site struct {
+		fingerprint string
+		file        string
+		line        int
+	}
+	siteOf := make([]site, len(queries))
+	bySite := make(map[site]*queryGroup)
+	for i, q := range queries {
+		key := site{fingerprintQuery(q.Query, q.dialect), q.File, q.Line}
+		siteOf[i] = key
+		g, ok := bySite[key]
+		if !ok {
+			g = &queryGroup{query: key.fingerprint, file: q.File, line: q.Line, first: i}
+			bySite[key] = g
+		}
+		g.count++
+		g.duration += q.Duration
+	}
+
+	byStatement := make(map[string]*queryGroup)
+	for i, q := range queries {
+		if bySite[siteOf[i]].count >= threshold {
+			continue
+		}
+		key := q.Query
+		if len(q.Bindings) > 0 {
+			key += fmt.Sprintf("\x00%#v", q.Bindings)
+		} else if hasPlaceholders(q.Query, q.dialect) {
+			// The arguments were not recorded, so repeats may well differ.
+			continue
+		}
+		g, ok := byStatement[key]
+		if !ok {
+			g = &queryGroup{query: q.Query, file: q.File, line: q.Line, first: i}
+			byStatement[key] = g
+		}
+		g.count++
+		g.duration += q.Duration
+	}
+
+	var groups []*queryGroup
+	kinds := make(map[*queryGroup]string)
+	for _, g := range bySite {
+		if g.count >= threshold {
+			groups = append(groups, g)
+			kinds[g] = QueryWarningNPlusOne
+		}
+	}
+	for _, g := range byStatement {
+		if g.count >= 2 {
+			groups = append(groups, g)
+			kinds[g] = QueryWarningDuplicate
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].first != groups[j].first {
+			return groups[i].first < groups[j].first
+		}
+		return kinds[groups[i]] == QueryWarningNPlusOne
+	})
+
+	warnings := make([]QueryWarning, 0, len(groups))
+	for _, g := range groups {
+		warnings = append(warnings, QueryWarning{
+			Type:     kinds[g],
+			Query:    g.query,
+			Count:    g.count,
+			Duration: g.duration,
+			File:     g.file,
+			Line:     g.line,
+		})
+	}
+	return warnings
+}
+
+// queryWarningLogEntry describes w as a warning for the log tab.
+func queryWarningLogEntry(w QueryWarning, timestamp float64) LogEntry {
+	label := "N+1 query"
+	if w.Type == QueryWarningDuplicate {
+		label = "Duplicate query"
+	}
+	message := fmt.Sprintf("%s: executed %d times (%.2f ms)", label, w.Count, w.Duration)
+	entry := LogEntry{
+		Level:   "warning",
+		Message: message,
+		Context: map[string]interface{}{
+			"type":     w.Type,
+			"query":    w.Query,
+			"count":    w.Count,
+			"duration": w.Duration,
+		},
+		Timestamp: timestamp,
+	}
+	if w.File != "" {
+		entry.Message = fmt.Sprintf("%s at %s:%d", message, w.File, w.Line)
+		entry.Trace = []LogTraceFrame{{File: w.File, Line: w.Line}}
+	}
+	return entry
+}
+
+// hasPlaceholders reports whether query has bind parameters such as ?, $1, :name or @p1.
+func hasPlaceholders(query, dialect string) bool {
+	for _, tok := range tokenizeSQL(query, dialect) {
+		if tok.kind == sqlValue && strings.ContainsRune("?$@:", rune(tok.raw[0])) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package clockwork
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintQuery(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM users WHERE id = 42", "select * from users where id = ?"},
+		{"select *\n  from users where id=$1", "select * from users where id = ?"},
+		{"SELECT * FROM users WHERE name = 'O''Brien' AND age > 3.5", "select * from users where name = ? and age > ?"},
+		{"SELECT * FROM orders WHERE id IN (1, 2, 3)", "select * from orders where id in (?)"},
+		{"SELECT * FROM orders WHERE id IN(?,?)", "select * from orders where id in (?)"},
+		{"INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y')", "insert into t (a, b) values (?, ?)"},
+		{`SELECT "Name" FROM t2 -- trailing comment`, `select "Name" from t2`},
+		{"SELECT a::int FROM t WHERE b = :name /* hint */", "select a :: int from t where b = ?"},
+		{"SELECT o.id, t.* FROM [dbo].[Orders] o WHERE o.total > .5", "select o.id, t.* from [dbo].[Orders] o where o.total > ?"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, FingerprintQuery(tt.sql), tt.sql)
+	}
+}
+
+func TestFingerprintQuery_BackslashEscapes(t *testing.T) {
+	require.Equal(t, "select ? as p, ? from t", FingerprintQuery(`SELECT 'C:\' AS p, 1 FROM t`))
+	require.Equal(t, "select ? as p, ? from t", fingerprintQuery(`SELECT 'it\'s' AS p, 1 FROM t`, DialectMySQL))
+}
+
+func TestCollector_QueryWarnings(t *testing.T) {
+	collector := NewCollector("GET", "/orders", collectorLimits{nPlusOne: 3})
+
+	collector.AddDatabaseQueryDetailed("SELECT * FROM orders", 2*time.Millisecond, "pg", false, "", "handler.go", 10)
+	for i := 1; i <= 4; i++ {
+		query := "SELECT * FROM items WHERE order_id = " + strconv.Itoa(i)
+		collector.AddDatabaseQueryDetailed(query, time.Millisecond, "pg", false, "", "repo.go", 42)
+	}
+	collector.AddDatabaseQueryDetailed("SELECT * FROM settings", time.Millisecond, "pg", false, "", "config.go", 7)
+	collector.AddDatabaseQueryDetailed("SELECT * FROM settings", time.Millisecond, "pg", false, "", "config.go", 9)
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.QueryWarnings, 2)
+
+	nPlusOne := meta.QueryWarnings[0]
+	require.Equal(t, QueryWarningNPlusOne, nPlusOne.Type)
+	require.Equal(t, "select * from items where order_id = ?", nPlusOne.Query)
+	require.Equal(t, 4, nPlusOne.Count)
+	require.InDelta(t, 4.0, nPlusOne.Duration, 0.5)
+	require.Equal(t, "repo.go", nPlusOne.File)
+	require.Equal(t, 42, nPlusOne.Line)
+
+	duplicate := meta.QueryWarnings[1]
+	require.Equal(t, QueryWarningDuplicate, duplicate.Type)
+	require.Equal(t, "SELECT * FROM settings", duplicate.Query)
+	require.Equal(t, 2, duplicate.Count)
+	require.Equal(t, "config.go", duplicate.File)
+
+	require.Len(t, meta.LogEntries, 2)
+	require.Equal(t, "warning", meta.LogEntries[0].Level)
+	require.Contains(t, meta.LogEntries[0].Message, "repo.go:42")
+
+	// Warnings are derived on every call, not accumulated.
+	require.Len(t, collector.GetMetadata().LogEntries, 2)
+}
+
+func TestCollector_QueryWarningsSkipUnboundPlaceholders(t *testing.T) {
+	collector := NewCollector("GET", "/orders", collectorLimits{nPlusOne: 5})
+	for _, query := range []string{"SELECT * FROM users WHERE id = $1", "SELECT * FROM carts WHERE id = ?"} {
+		collector.AddDatabaseQueryDetailed(query, time.Millisecond, "pg", false, "", "repo.go", 1)
+		collector.AddDatabaseQueryDetailed(query, time.Millisecond, "pg", false, "", "repo.go", 2)
+	}
+	collector.AddDatabaseQueryDetailed("SELECT * FROM items WHERE id = $1", time.Millisecond, "pg", false, "", "items.go", 1,
+		QueryDetails{Bindings: []interface{}{1}})
+	collector.AddDatabaseQueryDetailed("SELECT * FROM items WHERE id = $1", time.Millisecond, "pg", false, "", "items.go", 2,
+		QueryDetails{Bindings: []interface{}{1}})
+
+	warnings := collector.GetMetadata().QueryWarnings
+	require.Len(t, warnings, 1, "statements with unrecorded arguments are not duplicates")
+	require.Equal(t, QueryWarningDuplicate, warnings[0].Type)
+	require.Equal(t, "items.go", warnings[0].File)
+}
+
+func TestCollector_QueryWarningLogsRespectLogLimit(t *testing.T) {
+	collector := NewCollector("GET", "/orders", collectorLimits{maxLogs: 2, nPlusOne: 2})
+	collector.AddLogEntry("info", "handler started", nil)
+	collector.AddDatabaseQueryDetailed("SELECT * FROM a WHERE id = 1", time.Millisecond, "pg", false, "", "a.go", 1)
+	collector.AddDatabaseQueryDetailed("SELECT * FROM a WHERE id = 2", time.Millisecond, "pg", false, "", "a.go", 1)
+	collector.AddDatabaseQueryDetailed("SELECT * FROM b WHERE id = 1", time.Millisecond, "pg", false, "", "b.go", 1)
+	collector.AddDatabaseQueryDetailed("SELECT * FROM b WHERE id = 2", time.Millisecond, "pg", false, "", "b.go", 1)
+
+	for range 2 {
+		meta := collector.GetMetadata()
+		require.Len(t, meta.QueryWarnings, 2)
+		require.Len(t, meta.LogEntries, 2)
+		require.Contains(t, meta.LogEntries[1].Message, "a.go:1")
+		require.Equal(t, 1, meta.Dropped["logs"])
+		require.True(t, meta.Truncated)
+	}
+}
+
+func TestCollector_QueryWarningsDisabled(t *testing.T) {
+	collector := NewCollector("GET", "/orders", collectorLimits{nPlusOne: -1})
+	collector.AddDatabaseQuery("SELECT 1", time.Millisecond, "pg", false)
+	collector.AddDatabaseQuery("SELECT 1", time.Millisecond, "pg", false)
+
+	meta := collector.GetMetadata()
+	require.Empty(t, meta.QueryWarnings)
+	require.Empty(t, meta.LogEntries)
+}
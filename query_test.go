@@ -0,0 +1,87 @@
+package clockwork
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery_MatchesCriteria(t *testing.T) {
+	now := time.Now()
+	meta := &Metadata{
+		Method:               "POST",
+		URI:                  "/orders/42/items",
+		Controller:           "OrderHandler.Create",
+		ResponseStatus:       500,
+		ResponseDuration:     320,
+		DatabaseQueriesCount: 12,
+		Time:                 unixFromTime(now),
+		TraceID:              "trace-1",
+	}
+
+	require.True(t, Query{}.Matches(meta))
+	require.True(t, Query{URI: "orders"}.Matches(meta))
+	require.True(t, Query{URI: "/orders/*/items"}.Matches(meta))
+	require.False(t, Query{URI: "/users/*"}.Matches(meta))
+	require.True(t, Query{Method: "post", StatusMin: 500, StatusMax: 599}.Matches(meta))
+	require.False(t, Query{StatusMax: 499}.Matches(meta))
+	require.True(t, Query{MinDuration: 300 * time.Millisecond, MinDatabaseQueries: 10}.Matches(meta))
+	require.False(t, Query{MinDuration: time.Second}.Matches(meta))
+	require.True(t, Query{From: now.Add(-time.Minute), To: now.Add(time.Minute)}.Matches(meta))
+	require.False(t, Query{From: now.Add(time.Minute)}.Matches(meta))
+	require.True(t, Query{Controller: "orderhandler", TraceID: "trace-1"}.Matches(meta))
+	require.False(t, Query{TraceID: "trace-2"}.Matches(meta))
+}
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(url.Values{
+		"uri":          {"/orders*"},
+		"method":       {"GET"},
+		"status":       {"500-599"},
+		"min_duration": {"250ms"},
+		"min_queries":  {"5"},
+		"from":         {"1700000000"},
+		"limit":        {"10"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "/orders*", q.URI)
+	require.Equal(t, 500, q.StatusMin)
+	require.Equal(t, 599, q.StatusMax)
+	require.Equal(t, 250*time.Millisecond, q.MinDuration)
+	require.Equal(t, 5, q.MinDatabaseQueries)
+	require.Equal(t, int64(1700000000), q.From.Unix())
+	require.Equal(t, 10, q.Limit)
+
+	q, err = ParseQuery(url.Values{"status": {"404"}, "min_duration": {"100"}})
+	require.NoError(t, err)
+	require.Equal(t, 404, q.StatusMin)
+	require.Equal(t, 404, q.StatusMax)
+	require.Equal(t, 100*time.Millisecond, q.MinDuration)
+
+	_, err = ParseQuery(url.Values{"status": {"abc"}})
+	require.Error(t, err)
+}
+
+func TestInMemoryStorage_Search(t *testing.T) {
+	store := NewInMemoryStorage(10, 1024*1024)
+	ctx := context.Background()
+
+	require.NoError(t, store.Store(ctx, &Metadata{ID: "a", URI: "/orders", ResponseStatus: 500}))
+	require.NoError(t, store.Store(ctx, &Metadata{ID: "b", URI: "/users", ResponseStatus: 500}))
+	require.NoError(t, store.Store(ctx, &Metadata{ID: "c", URI: "/orders/1", ResponseStatus: 200}))
+	require.NoError(t, store.Store(ctx, &Metadata{ID: "d", URI: "/orders/2", ResponseStatus: 503}))
+
+	items, err := store.Search(ctx, Query{URI: "/orders", StatusMin: 500})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "d", items[0].ID)
+	require.Equal(t, "a", items[1].ID)
+
+	items, err = store.Search(ctx, Query{StatusMin: 500, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, "d", items[0].ID)
+}
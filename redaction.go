@@ -0,0 +1,510 @@
+package clockwork
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Built-in value pattern names accepted in RedactionConfig.Patterns.
+const (
+	RedactBearer = "bearer"
+	RedactJWT    = "jwt"
+	RedactEmail  = "email"
+	RedactCard   = "card"
+)
+
+// RedactionConfig controls which headers are recorded and how sensitive values are masked.
+// Nil slices take the defaults from DefaultConfig when the config is normalized.
+type RedactionConfig struct {
+	// CaptureAllHeaders records every header not in DenyHeaders instead of only AllowHeaders.
+	CaptureAllHeaders bool `mapstructure:"capture_all_headers"`
+	// AllowHeaders lists header name globs recorded when CaptureAllHeaders is off.
+	AllowHeaders []string `mapstructure:"allow_headers"`
+	// DenyHeaders lists header name globs that are never recorded.
+	DenyHeaders []string `mapstructure:"deny_headers"`
+	// MaskHeaders lists header name globs recorded with their value replaced by Mask.
+	MaskHeaders []string `mapstructure:"mask_headers"`
+	// MaskKeys lists field name globs (log context, userData, query parameters) whose values are replaced by Mask.
+	MaskKeys []string `mapstructure:"mask_keys"`
+	// Patterns lists built-in names (bearer, jwt, email, card) or regular expressions; matches inside values are replaced by Mask.
+	Patterns []string `mapstructure:"patterns"`
+	// Mask replaces redacted values.
+	Mask string `mapstructure:"mask"`
+}
+
+// DefaultRedactionConfig returns the default header allow-list and masking rules.
+func DefaultRedactionConfig() RedactionConfig {
+	return RedactionConfig{
+		AllowHeaders: []string{
+			"Content-Type", "Content-Length", "Accept", "Accept-Language", "Accept-Encoding",
+			"User-Agent", "Origin", "Referer", "X-Request-ID", "X-App-Version",
+		},
+		DenyHeaders: []string{},
+		MaskHeaders: []string{
+			"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+			"X-Api-Key", "X-Auth-Token", "X-Csrf-Token", "X-Xsrf-Token",
+		},
+		MaskKeys: []string{
+			"*password*", "*passwd*", "*secret*", "*token*", "*api_key*", "*apikey*",
+			"authorization", "cookie", "*session*", "sid", "*csrf*", "*xsrf*", "remember_*",
+			"*card_number*", "cvv", "cvc",
+		},
+		Patterns: []string{RedactBearer, RedactJWT, RedactEmail, RedactCard},
+		Mask:     "[REDACTED]",
+	}
+}
+
+func (c *RedactionConfig) normalize() {
+	d := DefaultRedactionConfig()
+	if c.AllowHeaders == nil {
+		c.AllowHeaders = d.AllowHeaders
+	}
+	if c.DenyHeaders == nil {
+		c.DenyHeaders = d.DenyHeaders
+	}
+	if c.MaskHeaders == nil {
+		c.MaskHeaders = d.MaskHeaders
+	}
+	if c.MaskKeys == nil {
+		c.MaskKeys = d.MaskKeys
+	}
+	if c.Patterns == nil {
+		c.Patterns = d.Patterns
+	}
+	if c.Mask == "" {
+		c.Mask = d.Mask
+	}
+}
+
+var (
+	bearerPattern = regexp.MustCompile(`(?i)\b(?:bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`)
+	jwtPattern    = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	cardPattern   = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+)
+
+type valuePattern struct {
+	re *regexp.Regexp
+	// valid filters candidate matches, e.g. a Luhn check for card numbers.
+	valid func(string) bool
+}
+
+// Redactor applies RedactionConfig to headers and recorded values.
+// A nil *Redactor applies the default configuration.
+type Redactor struct {
+	captureAll  bool
+	allow       []*regexp.Regexp
+	deny        []*regexp.Regexp
+	maskHeaders []*regexp.Regexp
+	maskKeys    []*regexp.Regexp
+	patterns    []valuePattern
+	mask        string
+}
+
+var defaultRedactor, _ = NewRedactor(DefaultRedactionConfig())
+
+// NewRedactor builds a Redactor. Invalid patterns are skipped and reported in the returned
+// error; the Redactor is usable either way.
+func NewRedactor(cfg RedactionConfig) (*Redactor, error) {
+	cfg.normalize()
+	r := &Redactor{
+		captureAll:  cfg.CaptureAllHeaders,
+		allow:       compileGlobs(cfg.AllowHeaders),
+		deny:        compileGlobs(cfg.DenyHeaders),
+		maskHeaders: compileGlobs(cfg.MaskHeaders),
+		maskKeys:    compileGlobs(cfg.MaskKeys),
+		mask:        cfg.Mask,
+	}
+
+	var errs []error
+	for _, pattern := range cfg.Patterns {
+		switch strings.ToLower(strings.TrimSpace(pattern)) {
+		case "":
+			continue
+		case RedactBearer:
+			r.patterns = append(r.patterns, valuePattern{re: bearerPattern})
+		case RedactJWT:
+			r.patterns = append(r.patterns, valuePattern{re: jwtPattern})
+		case RedactEmail:
+			r.patterns = append(r.patterns, valuePattern{re: emailPattern})
+		case RedactCard:
+			r.patterns = append(r.patterns, valuePattern{re: cardPattern, valid: luhnValid})
+		default:
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("redaction pattern %q: %w", pattern, err))
+				continue
+			}
+			r.patterns = append(r.patterns, valuePattern{re: re})
+		}
+	}
+	return r, errors.Join(errs...)
+}
+
+// Headers returns the recorded subset of headers (first value per name) with sensitive values masked.
+func (r *Redactor) Headers(headers http.Header) map[string]string {
+	out := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if len(values) == 0 {
+			continue
+		}
+		if value, ok := r.header(name, values[0]); ok {
+			out[name] = value
+		}
+	}
+	return out
+}
+
+func (r *Redactor) header(name, value string) (string, bool) {
+	if r == nil {
+		r = defaultRedactor
+	}
+	if matchAny(r.deny, name) {
+		return "", false
+	}
+	if !r.captureAll && !matchAny(r.allow, name) {
+		return "", false
+	}
+	if matchAny(r.maskHeaders, name) || matchAny(r.maskKeys, name) {
+		return r.mask, true
+	}
+	return r.String(value), true
+}
+
+func (r *Redactor) headerMap(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if redacted, ok := r.header(name, value); ok {
+			out[name] = redacted
+		}
+	}
+	return out
+}
+
+// String masks pattern matches inside value.
+func (r *Redactor) String(value string) string {
+	if r == nil {
+		r = defaultRedactor
+	}
+	if value == "" {
+		return value
+	}
+	for _, p := range r.patterns {
+		if p.valid == nil {
+			value = p.re.ReplaceAllString(value, r.mask)
+			continue
+		}
+		value = p.re.ReplaceAllStringFunc(value, func(match string) string {
+			if p.valid(match) {
+				return r.mask
+			}
+			return match
+		})
+	}
+	return value
+}
+
+// Field masks value entirely when key is sensitive, otherwise redacts it recursively.
+func (r *Redactor) Field(key string, value interface{}) interface{} {
+	if r == nil {
+		r = defaultRedactor
+	}
+	if matchAny(r.maskKeys, key) {
+		return r.mask
+	}
+	return r.Value(value)
+}
+
+// Value redacts strings, maps and slices recursively. Other composite values are
+// converted through JSON so nested fields are redacted too.
+func (r *Redactor) Value(value interface{}) interface{} {
+	if r == nil {
+		r = defaultRedactor
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.String(v)
+	case []byte:
+		return r.String(string(v))
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = r.Field(k, item)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = r.Field(k, item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.Value(item)
+		}
+		return out
+	case []string:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.String(item)
+		}
+		return out
+	case error:
+		return r.String(v.Error())
+	case fmt.Stringer:
+		return r.String(v.String())
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return r.String(toCompactString(v))
+		}
+		var generic interface{}
+		if err := json.Unmarshal(raw, &generic); err != nil {
+			return r.String(string(raw))
+		}
+		return r.Value(generic)
+	}
+}
+
+// URL masks sensitive query parameter values and pattern matches in a URL or request URI.
+// Parameters keep their order and encoding; only masked values are rewritten.
+func (r *Redactor) URL(raw string) string {
+	if r == nil {
+		r = defaultRedactor
+	}
+	if raw == "" {
+		return raw
+	}
+	base, rawQuery, hasQuery := strings.Cut(raw, "?")
+	if !hasQuery {
+		return r.String(raw)
+	}
+	fragment := ""
+	if q, f, ok := strings.Cut(rawQuery, "#"); ok {
+		rawQuery, fragment = q, "#"+r.String(f)
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if matchAny(r.maskKeys, name) {
+			params[i] = key + "=" + r.mask
+			continue
+		}
+		if redacted, changed := r.queryValue(value); changed {
+			params[i] = key + "=" + redacted
+		}
+	}
+	return r.String(base) + "?" + strings.Join(params, "&") + fragment
+}
+
+// queryValue masks pattern matches in an encoded query value. A changed value is
+// re-encoded with the mask itself left unescaped.
+func (r *Redactor) queryValue(value string) (string, bool) {
+	decoded, err := url.QueryUnescape(value)
+	if err != nil {
+		return r.String(value), r.String(value) != value
+	}
+	redacted := r.String(decoded)
+	if redacted == decoded {
+		return value, false
+	}
+	return strings.ReplaceAll(url.QueryEscape(redacted), url.QueryEscape(r.mask), r.mask), true
+}
+
+// timelineEvent redacts the description of e with the rules of the field it was built
+// from: request and http events hold a method and URL, the others a query, key or command.
+func (r *Redactor) timelineEvent(e TimelineEvent) TimelineEvent {
+	switch e.Name {
+	case "request", "http":
+		if method, target, ok := strings.Cut(e.Description, " "); ok {
+			e.Description = method + " " + r.URL(target)
+			return e
+		}
+	}
+	e.Description = r.String(e.Description)
+	return e
+}
+
+// Metadata returns a copy of m with headers, URLs, request data, cookies, session data,
+// outgoing HTTP calls, log entries, queries, query warnings, cache keys, timeline event
+// descriptions and userData redacted.
+// Slices and maps that change are copied; m is not modified.
+func (r *Redactor) Metadata(m *Metadata) *Metadata {
+	if m == nil {
+		return nil
+	}
+	if r == nil {
+		r = defaultRedactor
+	}
+
+	out := *m
+	out.URI = r.URL(m.URI)
+	out.URL = r.URL(m.URL)
+
+	out.Headers = r.headerMap(m.Headers)
+
+	if m.GetData != nil {
+		out.GetData = make(map[string]interface{}, len(m.GetData))
+		for key, value := range m.GetData {
+			out.GetData[key] = r.Field(key, value)
+		}
+	}
+	if m.Cookies != nil {
+		out.Cookies = make(map[string]string, len(m.Cookies))
+		for name, value := range m.Cookies {
+			if matchAny(r.maskKeys, name) {
+				out.Cookies[name] = r.mask
+			} else {
+				out.Cookies[name] = r.String(value)
+			}
+		}
+	}
+	if m.SessionData != nil {
+		out.SessionData = make(map[string]interface{}, len(m.SessionData))
+		for key, value := range m.SessionData {
+			out.SessionData[key] = r.Field(key, value)
+		}
+	}
+	if m.PostData != nil {
+		out.PostData = make(map[string]interface{}, len(m.PostData))
+		for key, value := range m.PostData {
+			out.PostData[key] = r.Field(key, value)
+		}
+	}
+	out.RequestData = r.Value(m.RequestData)
+	out.ResponseData = r.Value(m.ResponseData)
+
+	if m.LogEntries != nil {
+		out.LogEntries = make([]LogEntry, len(m.LogEntries))
+		for i, entry := range m.LogEntries {
+			entry.Message = r.String(entry.Message)
+			if entry.Context != nil {
+				entry.Context, _ = r.Value(entry.Context).(map[string]interface{})
+			}
+			out.LogEntries[i] = entry
+		}
+	}
+
+	if m.DatabaseQueries != nil {
+		out.DatabaseQueries = make([]DatabaseQuery, len(m.DatabaseQueries))
+		for i, q := range m.DatabaseQueries {
+			q.Query = r.String(q.Query)
+			q.Runnable = r.String(q.Runnable)
+			q.Error = r.String(q.Error)
+			q.Explain = r.String(q.Explain)
+			if q.Bindings != nil {
+				q.Bindings, _ = r.Value(q.Bindings).([]interface{})
+			}
+			out.DatabaseQueries[i] = q
+		}
+	}
+
+	if m.ModelsActions != nil {
+		out.ModelsActions = make([]ModelAction, len(m.ModelsActions))
+		for i, a := range m.ModelsActions {
+			a.Query = r.String(a.Query)
+			out.ModelsActions[i] = a
+		}
+	}
+	if m.QueryWarnings != nil {
+		out.QueryWarnings = make([]QueryWarning, len(m.QueryWarnings))
+		for i, w := range m.QueryWarnings {
+			w.Query = r.String(w.Query)
+			out.QueryWarnings[i] = w
+		}
+	}
+
+	if m.RedisCommands != nil {
+		out.RedisCommands = make([]RedisCommand, len(m.RedisCommands))
+		for i, cmd := range m.RedisCommands {
+			cmd.Error = r.String(cmd.Error)
+			if cmd.Parameters != nil {
+				cmd.Parameters, _ = r.Value(cmd.Parameters).([]interface{})
+			}
+			out.RedisCommands[i] = cmd
+		}
+	}
+	if m.HTTPRequests != nil {
+		out.HTTPRequests = make([]HTTPRequest, len(m.HTTPRequests))
+		for i, call := range m.HTTPRequests {
+			call.Request.URL = r.URL(call.Request.URL)
+			call.Request.Headers = r.headerMap(call.Request.Headers)
+			call.Response.Headers = r.headerMap(call.Response.Headers)
+			call.Error = r.String(call.Error)
+			out.HTTPRequests[i] = call
+		}
+	}
+
+	if m.Subrequests != nil {
+		out.Subrequests = make([]Subrequest, len(m.Subrequests))
+		for i, sub := range m.Subrequests {
+			sub.URL = r.URL(sub.URL)
+			out.Subrequests[i] = sub
+		}
+	}
+
+	if m.CacheQueries != nil {
+		out.CacheQueries = make([]CacheQuery, len(m.CacheQueries))
+		for i, q := range m.CacheQueries {
+			q.Key = r.String(q.Key)
+			q.Error = r.String(q.Error)
+			out.CacheQueries[i] = q
+		}
+	}
+
+	if m.TimelineEvents != nil {
+		out.TimelineEvents = make([]TimelineEvent, len(m.TimelineEvents))
+		for i, e := range m.TimelineEvents {
+			out.TimelineEvents[i] = r.timelineEvent(e)
+		}
+	}
+
+	if m.UserData != nil {
+		out.UserData = make(map[string]interface{}, len(m.UserData))
+		for key, value := range m.UserData {
+			out.UserData[key] = r.Field(key, value)
+		}
+	}
+
+	return &out
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, count := 0, 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		ch := s[i]
+		if ch < '0' || ch > '9' {
+			continue
+		}
+		d := int(ch - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		count++
+	}
+	return count >= 13 && sum%10 == 0
+}
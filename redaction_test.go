@@ -0,0 +1,95 @@
+package clockwork
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_Headers(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Authorization", "Bearer abc.def")
+	headers.Set("X-City-ID", "12")
+	headers.Set("X-Internal-Key", "k")
+
+	require.Equal(t, map[string]string{"Content-Type": "application/json"}, ExtractSafeHeaders(headers))
+
+	r, err := NewRedactor(RedactionConfig{CaptureAllHeaders: true, DenyHeaders: []string{"x-internal-*"}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "[REDACTED]",
+		"X-City-Id":     "12",
+	}, r.Headers(headers))
+}
+
+func TestRedactor_Values(t *testing.T) {
+	r, err := NewRedactor(RedactionConfig{Patterns: []string{"email", "card", `acct-\d+`}})
+	require.NoError(t, err)
+
+	require.Equal(t, "mail [REDACTED] now", r.String("mail jane@example.com now"))
+	require.Equal(t, "card [REDACTED]", r.String("card 4111 1111 1111 1111"))
+	require.Equal(t, "order 1234567890123", r.String("order 1234567890123"))
+	require.Equal(t, "[REDACTED] closed", r.String("acct-991 closed"))
+	require.Equal(t, "[REDACTED]", r.Field("user_password", "hunter2"))
+	require.Equal(t, map[string]interface{}{
+		"id":    42,
+		"token": "[REDACTED]",
+		"tags":  []interface{}{"[REDACTED]"},
+	}, r.Value(map[string]interface{}{"id": 42, "token": "t", "tags": []interface{}{"jane@example.com"}}))
+	require.Equal(t, "/login?next=/home&api_key=[REDACTED]&to=[REDACTED]%2Cx", r.URL("/login?next=/home&api_key=secret&to=jane%40example.com,x"))
+
+	_, err = NewRedactor(RedactionConfig{Patterns: []string{"("}})
+	require.Error(t, err)
+}
+
+func TestSaveMetadata_Redacts(t *testing.T) {
+	store := NewInMemoryStorage(10, 1024*1024)
+	cw := NewClockwork(DefaultConfig(), store)
+
+	metadata := &Metadata{
+		ID:         "req-1",
+		URI:        "/reset?token=abc",
+		Headers:    map[string]string{"Cookie": "sid=1", "Accept": "*/*"},
+		LogEntries: []LogEntry{{Message: "sent to jane@example.com", Context: map[string]interface{}{"password": "x"}}},
+		UserData:   map[string]interface{}{"profile": map[string]interface{}{"email": "jane@example.com"}},
+	}
+	require.NoError(t, cw.SaveMetadata(context.Background(), metadata))
+
+	stored, err := store.Get(context.Background(), "req-1")
+	require.NoError(t, err)
+	require.Equal(t, "/reset?token=[REDACTED]", stored.URI)
+	require.Equal(t, map[string]string{"Accept": "*/*"}, stored.Headers)
+	require.Equal(t, "sent to [REDACTED]", stored.LogEntries[0].Message)
+	require.Equal(t, "[REDACTED]", stored.LogEntries[0].Context["password"])
+	require.Equal(t, map[string]interface{}{"email": "[REDACTED]"}, stored.UserData["profile"])
+	require.Equal(t, "sid=1", metadata.Headers["Cookie"])
+}
+
+func TestRedactor_MetadataRedactsTimeline(t *testing.T) {
+	collector := NewCollector("GET", "/orders?token=supersecret&email=bob@example.com", collectorLimits{})
+	collector.AddDatabaseQuery("SELECT * FROM users WHERE email = 'alice@example.com'", time.Millisecond, "pg", false)
+	collector.AddHTTPRequest(HTTPRequest{Request: HTTPRequestInfo{Method: "GET", URL: "https://api.example.com/v1?api_key=k-123"}})
+	collector.AddCacheQuery(CacheHit, "session:alice@example.com", time.Millisecond)
+	collector.AddRedisCommand(RedisCommand{Command: "get"})
+	collector.SetResponseData(200, time.Millisecond)
+
+	meta := defaultRedactor.Metadata(collector.GetMetadata())
+	raw, err := json.Marshal(meta.TimelineEvents)
+	require.NoError(t, err)
+	for _, secret := range []string{"supersecret", "bob@example.com", "alice@example.com", "k-123"} {
+		require.NotContains(t, string(raw), secret)
+	}
+	descriptions := make(map[string]string, len(meta.TimelineEvents))
+	for _, e := range meta.TimelineEvents {
+		descriptions[e.Name] = e.Description
+	}
+	require.Equal(t, "GET /orders?token=[REDACTED]&email=[REDACTED]", descriptions["request"])
+	require.Equal(t, "hit: session:[REDACTED]", descriptions["cache"])
+	require.Equal(t, "get", descriptions["redis"])
+}
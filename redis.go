@@ -0,0 +1,53 @@
+package clockwork
+
+import (
+	"fmt"
+)
+
+// maxRedisParameters bounds the parameters kept per Redis command; the rest are summarized.
+const maxRedisParameters = 32
+
+// AddRedisCommand adds a Redis command. Duration is in milliseconds; a zero Timestamp (unix
+// seconds when the command started) is taken as now minus Duration. Parameters are
+// truncated like query bindings and long parameter lists are cut to 32 entries. Commands
+// beyond Config.MaxRedisCommands are dropped.
+func (c *Collector) AddRedisCommand(command RedisCommand) {
+	if c == nil || command.Command == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	params := command.Parameters
+	if len(params) > maxRedisParameters {
+		params = params[:maxRedisParameters]
+	}
+	if !c.reserveLocked("redis", c.limits.maxRedisCmds, len(c.redisCommands), len(command.Command)+bindingsEstimate(params)+64) {
+		return
+	}
+
+	command.Command = c.truncate(command.Command)
+	command.Connection = c.truncate(command.Connection)
+	command.Error = c.truncate(command.Error)
+	command.File = c.truncate(command.File)
+	if len(command.Parameters) > 0 {
+		out := make([]interface{}, len(params), len(params)+1)
+		for i, v := range params {
+			out[i] = c.normalizeBinding(bindingValue(v))
+		}
+		if more := len(command.Parameters) - len(params); more > 0 {
+			out = append(out, fmt.Sprintf("[%d more]", more))
+		}
+		command.Parameters = out
+	}
+	if command.Timestamp == 0 {
+		command.Timestamp = unixTimestamp() - command.Duration/1000
+	}
+	c.redisCommands = append(c.redisCommands, command)
+
+	color := "orange"
+	if command.Error != "" {
+		color = "red"
+	}
+	c.appendTimelineLocked("redis", command.Command, command.Timestamp, command.Timestamp+command.Duration/1000, color)
+}
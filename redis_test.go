@@ -0,0 +1,36 @@
+package clockwork
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector_AddRedisCommand(t *testing.T) {
+	collector := NewCollector("GET", "/cart", collectorLimits{maxStringLen: 16, maxRedisCmds: 2})
+
+	params := make([]interface{}, 40)
+	for i := range params {
+		params[i] = i
+	}
+	params[0] = strings.Repeat("k", 20)
+	collector.AddRedisCommand(RedisCommand{Command: "mset", Parameters: params, Duration: 2, Connection: "cache"})
+	collector.AddRedisCommand(RedisCommand{Command: "get", Parameters: []interface{}{"ann@example.com"}, Error: "i/o timeout"})
+	collector.AddRedisCommand(RedisCommand{Command: "del", Parameters: []interface{}{"x"}})
+
+	meta := collector.GetMetadata()
+	require.Len(t, meta.RedisCommands, 2)
+	mset := meta.RedisCommands[0]
+	require.Len(t, mset.Parameters, maxRedisParameters+1)
+	require.Equal(t, strings.Repeat("k", 16), mset.Parameters[0])
+	require.Equal(t, "[8 more]", mset.Parameters[maxRedisParameters])
+	require.NotZero(t, mset.Timestamp)
+	require.Equal(t, 1, meta.Dropped["redis"])
+
+	require.Equal(t, "redis", meta.TimelineEvents[len(meta.TimelineEvents)-1].Name)
+	require.Equal(t, "red", meta.TimelineEvents[len(meta.TimelineEvents)-1].Color)
+
+	redacted := defaultRedactor.Metadata(meta)
+	require.Equal(t, "[REDACTED]", redacted.RedisCommands[1].Parameters[0])
+}
@@ -0,0 +1,188 @@
+package clockwork
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// correlationRegistry indexes the collectors of in-flight requests by collector ID, span
+// ID and trace ID, so logs recorded outside a request context can find their request.
+// A trace or span ID may map to several collectors: fan-out requests and retries share a
+// trace. The zero value is ready to use.
+type correlationRegistry struct {
+	mu      sync.RWMutex
+	byID    map[string]*registration
+	bySpan  map[string]map[string]*Collector // span ID -> collector ID -> collector
+	byTrace map[string]map[string]*Collector // trace ID -> collector ID -> collector
+
+	// active mirrors len(byID) for lock-free checks on the logging path.
+	active atomic.Int64
+
+	attributed   atomic.Uint64
+	broadcast    atomic.Uint64
+	unattributed atomic.Uint64
+}
+
+// registration remembers the keys a collector was indexed under, for removal.
+type registration struct {
+	collector *Collector
+	traces    []string
+	spans     []string
+}
+
+// CorrelationStats counts logs routed through the RecordLogFor* methods, typically by
+// logging integrations that cannot see the request context.
+type CorrelationStats struct {
+	// ActiveCollectors is the number of in-flight requests registered for correlation.
+	ActiveCollectors int
+	// AttributedLogs were recorded on exactly one request.
+	AttributedLogs uint64
+	// BroadcastLogs matched several requests of one trace and were recorded on each.
+	BroadcastLogs uint64
+	// UnattributedLogs matched no request and were dropped.
+	UnattributedLogs uint64
+}
+
+func (r *correlationRegistry) add(collector *Collector, traceID, spanID string) {
+	id := collector.ID()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byID == nil {
+		r.byID = make(map[string]*registration)
+		r.bySpan = make(map[string]map[string]*Collector)
+		r.byTrace = make(map[string]map[string]*Collector)
+	}
+	reg := r.byID[id]
+	if reg == nil {
+		reg = &registration{collector: collector}
+		r.byID[id] = reg
+		r.active.Add(1)
+	}
+	if traceID != "" && indexAdd(r.byTrace, traceID, id, collector) {
+		reg.traces = append(reg.traces, traceID)
+	}
+	if spanID != "" && indexAdd(r.bySpan, spanID, id, collector) {
+		reg.spans = append(reg.spans, spanID)
+	}
+}
+
+func (r *correlationRegistry) remove(collector *Collector) {
+	id := collector.ID()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reg := r.byID[id]
+	if reg == nil {
+		return
+	}
+	for _, traceID := range reg.traces {
+		indexRemove(r.byTrace, traceID, id)
+	}
+	for _, spanID := range reg.spans {
+		indexRemove(r.bySpan, spanID, id)
+	}
+	delete(r.byID, id)
+	r.active.Add(-1)
+}
+
+// collector returns the registered collector with the given ID, or nil.
+func (r *correlationRegistry) collector(id string) *Collector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if reg := r.byID[id]; reg != nil {
+		return reg.collector
+	}
+	return nil
+}
+
+// lookup returns the collectors registered for spanID, or when there are none, every
+// collector registered for traceID.
+func (r *correlationRegistry) lookup(traceID, spanID string) []*Collector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if spanID != "" {
+		if set := r.bySpan[spanID]; len(set) > 0 {
+			return collectorsOf(set)
+		}
+	}
+	if traceID != "" {
+		return collectorsOf(r.byTrace[traceID])
+	}
+	return nil
+}
+
+// single returns the only registered collector, or nil when there are none or several.
+func (r *correlationRegistry) single() *Collector {
+	if r.active.Load() != 1 {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.byID) != 1 {
+		return nil
+	}
+	for _, reg := range r.byID {
+		return reg.collector
+	}
+	return nil
+}
+
+// record adds a log entry to collectors and updates the log counters.
+func (r *correlationRegistry) record(collectors []*Collector, level, message string, fields map[string]interface{}, trace []LogTraceFrame) bool {
+	switch len(collectors) {
+	case 0:
+		r.unattributed.Add(1)
+		return false
+	case 1:
+		r.attributed.Add(1)
+	default:
+		r.broadcast.Add(1)
+	}
+	for _, collector := range collectors {
+		collector.AddLogEntryWithTrace(level, message, fields, trace)
+	}
+	return true
+}
+
+func (r *correlationRegistry) stats() CorrelationStats {
+	return CorrelationStats{
+		ActiveCollectors: int(r.active.Load()),
+		AttributedLogs:   r.attributed.Load(),
+		BroadcastLogs:    r.broadcast.Load(),
+		UnattributedLogs: r.unattributed.Load(),
+	}
+}
+
+// indexAdd adds collector to index[key] and reports whether it was not there yet.
+func indexAdd(index map[string]map[string]*Collector, key, id string, collector *Collector) bool {
+	set := index[key]
+	if set == nil {
+		set = make(map[string]*Collector, 1)
+		index[key] = set
+	}
+	if _, ok := set[id]; ok {
+		return false
+	}
+	set[id] = collector
+	return true
+}
+
+func indexRemove(index map[string]map[string]*Collector, key, id string) {
+	set := index[key]
+	delete(set, id)
+	if len(set) == 0 {
+		delete(index, key)
+	}
+}
+
+func collectorsOf(set map[string]*Collector) []*Collector {
+	if len(set) == 0 {
+		return nil
+	}
+	out := make([]*Collector, 0, len(set))
+	for _, collector := range set {
+		out = append(out, collector)
+	}
+	return out
+}
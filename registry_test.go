@@ -0,0 +1,42 @@
+package clockwork
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockwork_RecordLogForSpanRoutesWithinTrace(t *testing.T) {
+	cw := NewClockwork(DefaultConfig(), nil)
+	first := NewCollector("GET", "/a", collectorLimits{})
+	first.SetTrace("trace-1", "span-a")
+	second := NewCollector("GET", "/b", collectorLimits{})
+	second.SetTrace("trace-1", "span-b")
+	other := NewCollector("GET", "/c", collectorLimits{})
+	cw.RegisterCollector(first)
+	cw.RegisterCollector(second)
+	cw.RegisterTrace("trace-2", other)
+
+	require.Same(t, second, cw.ActiveCollector(second.ID()))
+	require.True(t, cw.RecordLogForSpan("trace-1", "span-b", "info", "to b", nil))
+	require.True(t, cw.RecordLogForSpan("trace-1", "child-span", "info", "to both", nil))
+	cw.RecordLogForTrace("trace-2", "info", "to other", nil)
+	require.False(t, cw.RecordLogForSpan("trace-3", "", "info", "lost", nil))
+	require.False(t, cw.RecordLogForSingleActive("info", "ambiguous", nil))
+
+	require.Len(t, first.GetMetadata().LogEntries, 1)
+	require.Len(t, second.GetMetadata().LogEntries, 2)
+	require.Equal(t, "to other", other.GetMetadata().LogEntries[0].Message)
+	require.Equal(t, CorrelationStats{ActiveCollectors: 3, AttributedLogs: 2, BroadcastLogs: 1, UnattributedLogs: 2}, cw.CorrelationStats())
+
+	cw.registry.remove(first)
+	cw.registry.remove(other)
+	require.Nil(t, cw.ActiveCollector(first.ID()))
+	require.True(t, cw.RecordLogForSingleActive("info", "only b", nil))
+	require.Equal(t, "only b", second.GetMetadata().LogEntries[2].Message)
+
+	cw.registry.remove(second)
+	require.False(t, cw.HasActiveTraces())
+	require.Empty(t, cw.registry.byTrace)
+	require.Empty(t, cw.registry.bySpan)
+}
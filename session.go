@@ -0,0 +1,48 @@
+package clockwork
+
+import (
+	"context"
+	"net/http"
+)
+
+// SessionResolver reports the authenticated user and session data for a request.
+// Register it with Clockwork.SetSessionResolver; it runs when each captured request completes,
+// after DataSources. Either return value may be nil. Handlers that know the user directly can
+// instead call CollectorFromContext(ctx).SetAuthenticatedUser.
+type SessionResolver interface {
+	ResolveSession(ctx context.Context, r *http.Request) (*AuthenticatedUser, map[string]interface{})
+}
+
+// SessionResolverFunc adapts a function to SessionResolver.
+type SessionResolverFunc func(ctx context.Context, r *http.Request) (*AuthenticatedUser, map[string]interface{})
+
+// ResolveSession calls f(ctx, r).
+func (f SessionResolverFunc) ResolveSession(ctx context.Context, r *http.Request) (*AuthenticatedUser, map[string]interface{}) {
+	return f(ctx, r)
+}
+
+// SetSessionResolver sets the resolver used to report session data and the authenticated user.
+// Pass nil to remove it.
+func (c *Clockwork) SetSessionResolver(resolver SessionResolver) {
+	if c == nil {
+		return
+	}
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.session = resolver
+}
+
+func (c *Clockwork) resolveSession(ctx context.Context, collector *Collector) {
+	c.sessionMu.RLock()
+	resolver := c.session
+	c.sessionMu.RUnlock()
+	if resolver == nil || collector.request == nil {
+		return
+	}
+
+	user, data := resolver.ResolveSession(ctx, collector.request)
+	if user != nil {
+		collector.SetAuthenticatedUser(*user)
+	}
+	collector.SetSessionData(data)
+}
@@ -0,0 +1,133 @@
+package clockwork
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// eventColors are assigned to events without an explicit color by nesting depth.
+var eventColors = []string{"blue", "purple", "green", "orange", "grey"}
+
+type eventContextKey struct{}
+
+// Event is a timeline event started by StartEvent and recorded when End is called.
+// Methods on a nil *Event are no-ops, so callers need not check whether a request is captured.
+type Event struct {
+	collector *Collector
+	id        string
+	parent    string
+	depth     int
+	start     time.Time
+
+	mu          sync.Mutex
+	name        string
+	description string
+	color       string
+	ended       bool
+}
+
+// StartEvent starts a timeline event on the collector in ctx. The returned context carries the
+// event, so events started from it are recorded as its children. Without a collector in ctx it
+// returns ctx unchanged and a nil *Event.
+func StartEvent(ctx context.Context, name string) (context.Context, *Event) {
+	collector := CollectorFromContext(ctx)
+	if collector == nil {
+		return ctx, nil
+	}
+
+	event := &Event{
+		collector: collector,
+		id:        collector.nextEventID(),
+		name:      name,
+		start:     time.Now(),
+	}
+	if parent, ok := ctx.Value(eventContextKey{}).(*Event); ok && parent != nil && parent.collector == collector {
+		event.parent = parent.id
+		event.depth = parent.depth + 1
+	}
+	return context.WithValue(ctx, eventContextKey{}, event), event
+}
+
+// Measure runs fn inside an event named name. The event is colored red when fn returns an error,
+// which is returned unchanged.
+func Measure(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, event := StartEvent(ctx, name)
+	err := fn(ctx)
+	if err != nil {
+		event.SetColor("red")
+		event.SetDescription(err.Error())
+	}
+	event.End()
+	return err
+}
+
+// SetDescription sets the text shown next to the event name.
+func (e *Event) SetDescription(description string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.description = description
+}
+
+// SetColor overrides the depth-based color.
+func (e *Event) SetColor(color string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.color = color
+}
+
+// End records the event on the collector. Only the first call has an effect; events that are
+// never ended are not recorded.
+func (e *Event) End() {
+	if e == nil {
+		return
+	}
+	end := time.Now()
+
+	e.mu.Lock()
+	if e.ended {
+		e.mu.Unlock()
+		return
+	}
+	e.ended = true
+	color := e.color
+	if color == "" {
+		color = eventColors[e.depth%len(eventColors)]
+	}
+	event := TimelineEvent{
+		ID:          e.id,
+		Parent:      e.parent,
+		Name:        e.name,
+		Description: e.description,
+		Start:       unixFromTime(e.start),
+		End:         unixFromTime(end),
+		Color:       color,
+	}
+	e.mu.Unlock()
+
+	e.collector.addEvent(event)
+}
+
+func (c *Collector) nextEventID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.eventSeq++
+	return "e" + strconv.Itoa(c.eventSeq)
+}
+
+func (c *Collector) addEvent(event TimelineEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.reserveLocked("timeline", c.limits.maxTimelineEvent, len(c.timelineEvents), len(event.Name)+len(event.Description)+48) {
+		return
+	}
+	c.appendEventLocked(event)
+}
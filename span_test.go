@@ -0,0 +1,41 @@
+package clockwork
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartEvent_Nesting(t *testing.T) {
+	collector := NewCollector("GET", "/orders", limitsFromConfig(DefaultConfig()))
+	ctx := ContextWithCollector(context.Background(), collector)
+
+	ctx, parent := StartEvent(ctx, "service.Load")
+	err := Measure(ctx, "repo.Find", func(ctx context.Context) error {
+		return errors.New("not found")
+	})
+	require.EqualError(t, err, "not found")
+	parent.End()
+	parent.End()
+
+	events := collector.GetMetadata().TimelineEvents
+	require.Len(t, events, 2)
+	child, root := events[0], events[1]
+	require.Equal(t, "repo.Find", child.Name)
+	require.Equal(t, root.ID, child.Parent)
+	require.Equal(t, "red", child.Color)
+	require.Equal(t, "not found", child.Description)
+	require.Empty(t, root.Parent)
+	require.Equal(t, "blue", root.Color)
+	require.GreaterOrEqual(t, root.End, root.Start)
+}
+
+func TestStartEvent_WithoutCollector(t *testing.T) {
+	ctx, event := StartEvent(context.Background(), "noop")
+	require.Nil(t, event)
+	require.Equal(t, context.Background(), ctx)
+	event.SetColor("red")
+	event.End()
+}
@@ -0,0 +1,452 @@
+package clockwork
+
+import (
+	"strings"
+)
+
+// SQLInfo describes the statement verb and tables of a SQL query.
+type SQLInfo struct {
+	// Operation is the upper-cased statement verb (SELECT, INSERT, UPDATE, ...). For WITH
+	// queries it is the verb of the main statement.
+	Operation string
+	// Tables lists the referenced tables without quotes, schema-qualified as written. The
+	// table the statement operates on comes first, then the rest in order of appearance.
+	// CTE names and table functions are left out.
+	Tables []string
+	// ReadOnly is set for SELECT statements whose CTEs do not modify data either and that
+	// neither lock rows (FOR UPDATE, FOR SHARE, LOCK IN SHARE MODE) nor store their result
+	// with INTO.
+	ReadOnly bool
+}
+
+// ParseSQL extracts the statement verb and referenced tables from query. It is a
+// lightweight tokenizer, not a full SQL parser: comments, string literals, quoted and
+// schema-qualified identifiers, CTEs and subqueries are handled; anything it does not
+// understand is skipped. Backslashes in string literals are not escapes, as in standard SQL.
+func ParseSQL(query string) SQLInfo {
+	return parseSQL(query, "")
+}
+
+// ParseSQLDialect is ParseSQL for a dialect; MySQL string literals use backslash escapes.
+func ParseSQLDialect(query, dialect string) SQLInfo {
+	return parseSQL(query, dialect)
+}
+
+func parseSQL(query, dialect string) SQLInfo {
+	toks := tokenizeSQL(query, dialect)
+	verb, ctes := statementVerb(toks)
+
+	var info SQLInfo
+	if verb >= 0 {
+		info.Operation = strings.ToUpper(toks[verb].text)
+		info.ReadOnly = info.Operation == "SELECT" && !modifiesData(toks[:verb]) && !locksOrStores(toks[verb:])
+	}
+
+	p := tableScanner{toks: toks, ctes: ctes, seen: make(map[string]bool), verb: verb}
+	p.scan()
+	info.Tables = p.tables
+	if p.primary > 0 {
+		primary := info.Tables[p.primary]
+		copy(info.Tables[1:p.primary+1], info.Tables[:p.primary])
+		info.Tables[0] = primary
+	}
+	return info
+}
+
+// extractTableName returns the primary table of query without its schema.
+func extractTableName(query string) string {
+	return primaryTableName(ParseSQL(query).Tables)
+}
+
+func primaryTableName(tables []string) string {
+	if len(tables) == 0 {
+		return ""
+	}
+	name := tables[0]
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		name = name[dot+1:]
+	}
+	return name
+}
+
+type sqlTokenKind int
+
+const (
+	sqlWord   sqlTokenKind = iota // keyword or bare identifier
+	sqlQuoted                     // "quoted", `quoted` or [quoted] identifier
+	sqlPunct                      // ( ) , . ;
+	sqlValue                      // string or numeric literal, or placeholder
+	sqlOther                      // operators and anything else
+)
+
+// sqlToken is one lexical token. raw is the source text; text is the same except for
+// quoted identifiers, where it is the unquoted name.
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	raw  string
+}
+
+func (t sqlToken) is(kind sqlTokenKind, text string) bool {
+	return t.kind == kind && strings.EqualFold(t.text, text)
+}
+
+func (t sqlToken) isIdent() bool {
+	return t.kind == sqlQuoted || (t.kind == sqlWord && !sqlKeywords[strings.ToUpper(t.text)])
+}
+
+// sqlKeywords are words that end a table reference or cannot be aliases and function names.
+var sqlKeywords = toSet(
+	"ALL", "AND", "ANY", "AS", "ASC", "BETWEEN", "BY", "CASE", "CONFLICT", "CROSS", "DEFAULT",
+	"DELETE", "DESC", "DISTINCT", "DO", "DUPLICATE", "ELSE", "END", "EXCEPT", "EXISTS", "FETCH",
+	"FILTER", "FOR", "FORCE", "FROM", "FULL", "GROUP", "HAVING", "IF", "IGNORE", "ILIKE", "IN",
+	"INNER", "INSERT", "INTERSECT", "INTO", "IS", "JOIN", "KEY", "LATERAL", "LEFT", "LIKE", "LIMIT",
+	"LOCK", "MATCHED", "MATERIALIZED", "MERGE", "MINUS", "NATURAL", "NOT", "NOWAIT", "NULL", "OFFSET",
+	"ON", "ONLY", "OR", "ORDER", "OUTER", "OUTPUT", "OVER", "PARTITION", "RECURSIVE", "RETURNING",
+	"RIGHT", "SELECT", "SET", "SHARE", "SKIP", "SOME", "STDIN", "STDOUT", "STRAIGHT_JOIN", "TABLE",
+	"TABLESAMPLE", "THEN", "TOP", "UNION", "UPDATE", "USE", "USING", "VALUE", "VALUES", "WHEN",
+	"WHERE", "WINDOW", "WITH",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// tokenizeSQL splits query into tokens for ParseSQL and FingerprintQuery, dropping
+// whitespace and comments. Backslash escapes in string literals are honored for MySQL only.
+func tokenizeSQL(query, dialect string) []sqlToken {
+	backslash := dialect == DialectMySQL
+	toks := make([]sqlToken, 0, len(query)/4)
+	for i := 0; i < len(query); {
+		ch := query[i]
+		start := i
+		kind := sqlOther
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+			continue
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			continue
+		case ch == '/' && i+1 < len(query) && query[i+1] == '*':
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(query)
+			}
+			continue
+		case ch == '\'':
+			i = skipQuoted(query, i, ch, backslash)
+			kind = sqlValue
+		case ch == '"' || ch == '`':
+			i = skipQuoted(query, i, ch, false)
+			kind = sqlQuoted
+		case ch == '[':
+			if end := strings.IndexByte(query[i:], ']'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(query)
+			}
+			kind = sqlQuoted
+		case ch == '?':
+			i++
+			kind = sqlValue
+		case (ch == '$' || ch == '@' || ch == ':') && i+1 < len(query) && isWordByte(query[i+1]) && (ch != ':' || i == 0 || query[i-1] != ':'):
+			i = scan(query, i+1, isWordByte)
+			kind = sqlValue
+		case isDigit(ch) || (ch == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			i = scan(query, i, isNumberByte)
+			kind = sqlValue
+		case isWordByte(ch) && ch != '$':
+			i = scan(query, i, isWordByte)
+			kind = sqlWord
+		case ch == '(' || ch == ')' || ch == ',' || ch == '.' || ch == ';':
+			i++
+			kind = sqlPunct
+		case isOperatorByte(ch):
+			i = scan(query, i, isOperatorByte)
+		default:
+			i++
+		}
+		raw := query[start:i]
+		text := raw
+		if kind == sqlQuoted {
+			text = unquoteIdent(raw)
+		}
+		toks = append(toks, sqlToken{kind: kind, text: text, raw: raw})
+	}
+	return toks
+}
+
+// unquoteIdent strips the quotes of a "quoted", `quoted` or [quoted] identifier and
+// undoubles embedded quotes.
+func unquoteIdent(raw string) string {
+	open := raw[0]
+	if open == '[' {
+		return strings.TrimSuffix(raw[1:], "]")
+	}
+	quote := string(open)
+	return strings.ReplaceAll(strings.TrimSuffix(raw[1:], quote), quote+quote, quote)
+}
+
+func scan(s string, i int, accept func(byte) bool) int {
+	for i < len(s) && accept(s[i]) {
+		i++
+	}
+	return i
+}
+
+// skipQuoted returns the index just past the quoted section starting at start, treating a
+// doubled quote, and with backslash set a backslash inside single quotes, as an escape.
+func skipQuoted(s string, start int, quote byte, backslash bool) int {
+	for i := start + 1; i < len(s); i++ {
+		if backslash && s[i] == '\\' && quote == '\'' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func isDigit(ch byte) bool { return ch >= '0' && ch <= '9' }
+
+// isNumberByte accepts decimal, float, exponent and hex literal bytes.
+func isNumberByte(ch byte) bool {
+	return isDigit(ch) || ch == '.' || ch == 'x' || ch == 'X' || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+func isWordByte(ch byte) bool {
+	return ch == '_' || ch == '$' || isDigit(ch) || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch >= 0x80
+}
+
+func isOperatorByte(ch byte) bool {
+	return strings.IndexByte("<>=!|&+-*/%^~:", ch) >= 0
+}
+
+// statementVerb returns the index of the main statement verb (-1 if none) and the
+// lower-cased names of the CTEs declared by a leading WITH clause.
+func statementVerb(toks []sqlToken) (int, map[string]bool) {
+	i := 0
+	for i < len(toks) && toks[i].is(sqlPunct, "(") {
+		i++
+	}
+	if i >= len(toks) || toks[i].kind != sqlWord {
+		return -1, nil
+	}
+	if !toks[i].is(sqlWord, "WITH") {
+		return i, nil
+	}
+
+	ctes := make(map[string]bool)
+	i++
+	if i < len(toks) && toks[i].is(sqlWord, "RECURSIVE") {
+		i++
+	}
+	for i < len(toks) {
+		if toks[i].kind == sqlWord || toks[i].kind == sqlQuoted {
+			ctes[strings.ToLower(toks[i].text)] = true
+			i++
+		}
+		// Column list, AS [NOT] MATERIALIZED, then the CTE body.
+		for i < len(toks) && !toks[i].is(sqlWord, "AS") {
+			i = skipParens(toks, i)
+		}
+		for i < len(toks) && !toks[i].is(sqlPunct, "(") {
+			i++
+		}
+		i = skipParens(toks, i)
+		if i < len(toks) && toks[i].is(sqlPunct, ",") {
+			i++
+			continue
+		}
+		break
+	}
+	for i < len(toks) && toks[i].is(sqlPunct, "(") {
+		i++
+	}
+	if i >= len(toks) || toks[i].kind != sqlWord {
+		return -1, ctes
+	}
+	return i, ctes
+}
+
+// modifiesData reports whether toks, the WITH clause before the main verb, contain a
+// data-modifying CTE.
+func modifiesData(toks []sqlToken) bool {
+	for _, tok := range toks {
+		if tok.kind != sqlWord {
+			continue
+		}
+		switch strings.ToUpper(tok.text) {
+		case "INSERT", "UPDATE", "DELETE", "MERGE":
+			return true
+		}
+	}
+	return false
+}
+
+// locksOrStores reports whether a SELECT's tokens contain a row locking clause or INTO.
+func locksOrStores(toks []sqlToken) bool {
+	for i, tok := range toks {
+		if tok.kind != sqlWord || i+1 >= len(toks) {
+			continue
+		}
+		next := toks[i+1]
+		switch strings.ToUpper(tok.text) {
+		case "INTO":
+			return true
+		case "FOR":
+			if next.is(sqlWord, "UPDATE") || next.is(sqlWord, "SHARE") || next.is(sqlWord, "NO") || next.is(sqlWord, "KEY") {
+				return true
+			}
+		case "LOCK":
+			if next.is(sqlWord, "IN") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// skipParens returns the index after the balanced group opening at i, or i+1 when toks[i]
+// is not "(".
+func skipParens(toks []sqlToken, i int) int {
+	if i >= len(toks) || !toks[i].is(sqlPunct, "(") {
+		return i + 1
+	}
+	depth := 0
+	for ; i < len(toks); i++ {
+		switch {
+		case toks[i].is(sqlPunct, "("):
+			depth++
+		case toks[i].is(sqlPunct, ")"):
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// tableScanner collects table references following FROM, JOIN, INTO, USING, TABLE and a
+// leading UPDATE, TRUNCATE or COPY. Keywords inside function-call parentheses (EXTRACT(... FROM
+// ...), TRIM(... FROM ...)) are ignored.
+type tableScanner struct {
+	toks    []sqlToken
+	ctes    map[string]bool
+	seen    map[string]bool
+	verb    int
+	tables  []string
+	primary int // index in tables of the first table at statement level
+	hasTop  bool
+}
+
+func (p *tableScanner) scan() {
+	// calls[d] reports whether the parenthesis open at depth d is a function call or column list.
+	var calls []bool
+	for i := 0; i < len(p.toks); i++ {
+		tok := p.toks[i]
+		if tok.is(sqlPunct, "(") {
+			calls = append(calls, i > 0 && p.toks[i-1].isIdent())
+			continue
+		}
+		if tok.is(sqlPunct, ")") {
+			if len(calls) > 0 {
+				calls = calls[:len(calls)-1]
+			}
+			continue
+		}
+		if tok.kind != sqlWord || (len(calls) > 0 && calls[len(calls)-1]) {
+			continue
+		}
+		list := false
+		switch strings.ToUpper(tok.text) {
+		case "FROM", "USING":
+			list = true
+		case "JOIN", "INTO", "TABLE":
+		case "UPDATE", "TRUNCATE":
+			if i != p.verb {
+				continue
+			}
+			list = true
+		case "COPY":
+			if i != p.verb {
+				continue
+			}
+		default:
+			continue
+		}
+		p.references(i+1, len(calls), list)
+	}
+}
+
+// references reads the table reference at i and, when list is set, further
+// comma-separated references with their aliases.
+func (p *tableScanner) references(i, depth int, list bool) {
+	for i < len(p.toks) {
+		for i < len(p.toks) && p.toks[i].kind == sqlWord && isTableModifier(p.toks[i].text) {
+			i++
+		}
+		if i >= len(p.toks) || !p.toks[i].isIdent() {
+			return
+		}
+		name := p.toks[i].text
+		i++
+		for i+1 < len(p.toks) && p.toks[i].is(sqlPunct, ".") && (p.toks[i+1].kind == sqlWord || p.toks[i+1].kind == sqlQuoted) {
+			name += "." + p.toks[i+1].text
+			i += 2
+		}
+		if list && i < len(p.toks) && p.toks[i].is(sqlPunct, "(") {
+			return // table function such as generate_series(...)
+		}
+		p.add(name, depth)
+		if !list {
+			return
+		}
+		if i < len(p.toks) && p.toks[i].is(sqlWord, "AS") {
+			i++
+		}
+		if i < len(p.toks) && p.toks[i].isIdent() {
+			i++
+		}
+		if i >= len(p.toks) || !p.toks[i].is(sqlPunct, ",") {
+			return
+		}
+		i++
+	}
+}
+
+func isTableModifier(word string) bool {
+	switch strings.ToUpper(word) {
+	case "ONLY", "IF", "NOT", "EXISTS", "IGNORE", "TABLE":
+		return true
+	}
+	return false
+}
+
+func (p *tableScanner) add(name string, depth int) {
+	key := strings.ToLower(name)
+	if p.ctes[key] || p.seen[key] {
+		return
+	}
+	p.seen[key] = true
+	if depth == 0 && !p.hasTop {
+		p.hasTop = true
+		p.primary = len(p.tables)
+	}
+	p.tables = append(p.tables, name)
+}
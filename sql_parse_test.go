@@ -0,0 +1,54 @@
+package clockwork
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSQL(t *testing.T) {
+	tests := []struct {
+		sql       string
+		operation string
+		tables    []string
+	}{
+		{"WITH recent AS (SELECT * FROM orders) SELECT * FROM recent JOIN users u ON u.id = recent.user_id", "SELECT", []string{"users", "orders"}},
+		{"WITH RECURSIVE t(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM t) SELECT * FROM t", "SELECT", nil},
+		{`SELECT * FROM "Sales"."Order Items" WHERE note = 'FROM fake'`, "SELECT", []string{"Sales.Order Items"}},
+		{"-- FROM comments\nSELECT (SELECT count(*) FROM items i WHERE i.order_id = o.id) FROM orders o", "SELECT", []string{"orders", "items"}},
+		{"INSERT INTO archive (id) SELECT id FROM orders /* FROM hint */ WHERE status = 'done'", "INSERT", []string{"archive", "orders"}},
+		{"SELECT EXTRACT(YEAR FROM created_at), TRIM(BOTH ' ' FROM name) FROM events", "SELECT", []string{"events"}},
+		{"SELECT * FROM a, b AS bb, c cc WHERE a.id = b.id", "SELECT", []string{"a", "b", "c"}},
+		{"update t SET a = 1 FROM u WHERE t.id = u.id", "UPDATE", []string{"t", "u"}},
+		{"INSERT INTO t (a) VALUES (1) ON DUPLICATE KEY UPDATE a = 2", "INSERT", []string{"t"}},
+		{"SELECT * FROM [dbo].[Orders] WITH (NOLOCK)", "SELECT", []string{"dbo.Orders"}},
+		{"SELECT * FROM generate_series(1, 10) g", "SELECT", nil},
+		{"TRUNCATE TABLE logs", "TRUNCATE", []string{"logs"}},
+		{`COPY "public"."events" ("id", "name") FROM STDIN`, "COPY", []string{"public.events"}},
+		{"SHOW TABLES", "SHOW", nil},
+		{"", "", nil},
+	}
+	for _, tt := range tests {
+		info := ParseSQL(tt.sql)
+		require.Equal(t, tt.operation, info.Operation, tt.sql)
+		require.Equal(t, tt.tables, info.Tables, tt.sql)
+	}
+}
+
+func TestParseSQL_ReadOnly(t *testing.T) {
+	require.True(t, ParseSQL("(SELECT 1) UNION (SELECT 2)").ReadOnly)
+	require.True(t, ParseSQL("WITH r AS (SELECT * FROM orders) SELECT * FROM r").ReadOnly)
+	require.False(t, ParseSQL("WITH d AS (DELETE FROM orders RETURNING id) SELECT * FROM d").ReadOnly)
+	require.False(t, ParseSQL("UPDATE orders SET seen = true").ReadOnly)
+	require.False(t, ParseSQL("WITH r AS (SELECT 1) INSERT INTO t SELECT * FROM r").ReadOnly)
+	require.False(t, ParseSQL("SELECT * FROM orders WHERE id = 1 FOR UPDATE").ReadOnly)
+	require.False(t, ParseSQL("SELECT * FROM orders FOR NO KEY UPDATE SKIP LOCKED").ReadOnly)
+	require.False(t, ParseSQL("SELECT * FROM orders FOR SHARE OF orders").ReadOnly)
+	require.False(t, ParseSQL("SELECT * FROM orders LOCK IN SHARE MODE").ReadOnly)
+	require.False(t, ParseSQL("SELECT * INTO archived_orders FROM orders").ReadOnly)
+	require.False(t, ParseSQL("SELECT count(*) INTO @total FROM orders").ReadOnly)
+	require.True(t, ParseSQL("SELECT substring(name FROM 1 FOR 3) FROM orders").ReadOnly)
+
+	mysql := `SELECT * FROM orders WHERE note = 'it\'s' FOR UPDATE`
+	require.False(t, ParseSQLDialect(mysql, DialectMySQL).ReadOnly)
+}
@@ -11,6 +11,12 @@ type Storage interface {
 	Store(ctx context.Context, metadata *Metadata) error
 	Get(ctx context.Context, id string) (*Metadata, error)
 	List(ctx context.Context, limit int) ([]*Metadata, error)
+	// Previous returns up to limit entries stored before id, oldest first.
+	Previous(ctx context.Context, id string, limit int) ([]*Metadata, error)
+	// Next returns up to limit entries stored after id, oldest first.
+	Next(ctx context.Context, id string, limit int) ([]*Metadata, error)
+	// Search returns entries matching query, most recent first.
+	Search(ctx context.Context, query Query) ([]*Metadata, error)
 	Cleanup(ctx context.Context, maxAge time.Duration) error
 }
 
@@ -12,6 +12,8 @@ import (
 	"github.com/bradfitz/gomemcache/memcache"
 )
 
+const searchBatchSize = 100
+
 // Config holds Memcache storage configuration.
 type Config struct {
 	Endpoints   []string
@@ -142,17 +144,88 @@ func (s *Storage) List(ctx context.Context, limit int) ([]*clockwork.Metadata, e
 		limit = len(ids)
 	}
 
+	return s.loadAll(ids[:limit]), nil
+}
+
+// Previous returns up to limit entries stored before id, oldest first.
+func (s *Storage) Previous(ctx context.Context, id string, limit int) ([]*clockwork.Metadata, error) {
+	ids, pos, err := s.indexPosition(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The index is newest first, so older entries follow id.
+	older := ids[pos+1:]
+	if limit > 0 && limit < len(older) {
+		older = older[:limit]
+	}
+
+	out := s.loadAll(older)
+	reverseMetadata(out)
+	return out, nil
+}
+
+// Next returns up to limit entries stored after id, oldest first.
+func (s *Storage) Next(ctx context.Context, id string, limit int) ([]*clockwork.Metadata, error) {
+	ids, pos, err := s.indexPosition(id)
+	if err != nil {
+		return nil, err
+	}
+
+	newer := ids[:pos]
+	if limit > 0 && limit < len(newer) {
+		newer = newer[len(newer)-limit:]
+	}
+
+	out := s.loadAll(newer)
+	reverseMetadata(out)
+	return out, nil
+}
+
+// Search returns entries matching query, most recent first.
+func (s *Storage) Search(ctx context.Context, query clockwork.Query) ([]*clockwork.Metadata, error) {
+	s.mu.Lock()
+	ids, err := s.loadIndexLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 || limit > len(ids) {
+		limit = len(ids)
+	}
+
+	match := query.Matcher()
 	out := make([]*clockwork.Metadata, 0, limit)
-	for i := 0; i < limit; i++ {
-		item, err := s.client.Get(s.reqKey(ids[i]))
+	for start := 0; start < len(ids) && len(out) < limit; start += searchBatchSize {
+		batch := ids[start:min(start+searchBatchSize, len(ids))]
+		keys := make([]string, len(batch))
+		for i, id := range batch {
+			keys[i] = s.reqKey(id)
+		}
+		items, err := s.client.GetMulti(keys)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("memcache get metadata: %w", err)
 		}
-		var metadata clockwork.Metadata
-		if err := json.Unmarshal(item.Value, &metadata); err != nil {
-			continue
+
+		// GetMulti returns a map, so walk keys to keep index order.
+		for _, key := range keys {
+			item, ok := items[key]
+			if !ok {
+				continue
+			}
+			var metadata clockwork.Metadata
+			if err := json.Unmarshal(item.Value, &metadata); err != nil {
+				continue
+			}
+			if match(&metadata) {
+				out = append(out, &metadata)
+				if len(out) >= limit {
+					break
+				}
+			}
 		}
-		out = append(out, &metadata)
 	}
 
 	return out, nil
@@ -163,10 +236,69 @@ func (s *Storage) Cleanup(ctx context.Context, maxAge time.Duration) error {
 	return nil
 }
 
+// StoreProfile saves a pprof profile with the same TTL as metadata. Profiles larger than
+// the server's item size limit (1 MB by default) are rejected by Memcached.
+func (s *Storage) StoreProfile(ctx context.Context, id, kind string, data []byte) error {
+	item := &memcache.Item{
+		Key:        s.profileKey(id, kind),
+		Value:      data,
+		Expiration: s.ttlSeconds,
+	}
+	if err := s.client.Set(item); err != nil {
+		return fmt.Errorf("memcache set profile: %w", err)
+	}
+	return nil
+}
+
+// GetProfile fetches a pprof profile saved with StoreProfile.
+func (s *Storage) GetProfile(ctx context.Context, id, kind string) ([]byte, error) {
+	item, err := s.client.Get(s.profileKey(id, kind))
+	if err != nil {
+		return nil, fmt.Errorf("memcache get profile: %w", err)
+	}
+	return item.Value, nil
+}
+
+func (s *Storage) profileKey(id, kind string) string {
+	return s.prefix + ":profile:" + kind + ":" + id
+}
+
 func (s *Storage) reqKey(id string) string {
 	return s.prefix + ":req:" + id
 }
 
+func (s *Storage) indexPosition(id string) ([]string, int, error) {
+	s.mu.Lock()
+	ids, err := s.loadIndexLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i, v := range ids {
+		if v == id {
+			return ids, i, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("clockwork metadata not found: %s", id)
+}
+
+func (s *Storage) loadAll(ids []string) []*clockwork.Metadata {
+	out := make([]*clockwork.Metadata, 0, len(ids))
+	for _, id := range ids {
+		item, err := s.client.Get(s.reqKey(id))
+		if err != nil {
+			continue
+		}
+		var metadata clockwork.Metadata
+		if err := json.Unmarshal(item.Value, &metadata); err != nil {
+			continue
+		}
+		out = append(out, &metadata)
+	}
+	return out
+}
+
 func (s *Storage) loadIndexLocked() ([]string, error) {
 	item, err := s.client.Get(s.indexKey)
 	if err != nil {
@@ -199,6 +331,12 @@ func (s *Storage) saveIndexLocked(ids []string) error {
 	return nil
 }
 
+func reverseMetadata(items []*clockwork.Metadata) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
+
 func prependUnique(in []string, id string) []string {
 	out := make([]string, 0, len(in)+1)
 	out = append(out, id)
@@ -11,6 +11,8 @@ import (
 	redis "github.com/redis/go-redis/v9"
 )
 
+const searchBatchSize = 100
+
 // Config holds Redis storage configuration.
 type Config struct {
 	Endpoint   string
@@ -113,28 +115,109 @@ func (s *Storage) Get(ctx context.Context, id string) (*clockwork.Metadata, erro
 // List returns most recent metadata first.
 func (s *Storage) List(ctx context.Context, limit int) ([]*clockwork.Metadata, error) {
 	if limit <= 0 {
-		limit = s.maxEntries
+		limit = s.defaultLimit()
+	}
+
+	ids, err := s.client.LRange(ctx, s.indexKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis list index: %w", err)
+	}
+
+	return s.loadAll(ctx, ids), nil
+}
+
+// Previous returns up to limit entries stored before id, oldest first.
+func (s *Storage) Previous(ctx context.Context, id string, limit int) ([]*clockwork.Metadata, error) {
+	pos, err := s.indexPosition(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 	if limit <= 0 {
-		limit = 50
+		limit = s.defaultLimit()
 	}
 
-	ids, err := s.client.LRange(ctx, s.indexKey, 0, int64(limit-1)).Result()
+	// The index is newest first, so older entries follow id.
+	ids, err := s.client.LRange(ctx, s.indexKey, pos+1, pos+int64(limit)).Result()
 	if err != nil {
 		return nil, fmt.Errorf("redis list index: %w", err)
 	}
 
-	out := make([]*clockwork.Metadata, 0, len(ids))
-	for _, id := range ids {
-		value, err := s.client.Get(ctx, s.reqKey(id)).Bytes()
+	out := s.loadAll(ctx, ids)
+	reverseMetadata(out)
+	return out, nil
+}
+
+// Next returns up to limit entries stored after id, oldest first.
+func (s *Storage) Next(ctx context.Context, id string, limit int) ([]*clockwork.Metadata, error) {
+	pos, err := s.indexPosition(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = s.defaultLimit()
+	}
+	if pos == 0 {
+		return []*clockwork.Metadata{}, nil
+	}
+
+	start := pos - int64(limit)
+	if start < 0 {
+		start = 0
+	}
+	ids, err := s.client.LRange(ctx, s.indexKey, start, pos-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis list index: %w", err)
+	}
+
+	out := s.loadAll(ctx, ids)
+	reverseMetadata(out)
+	return out, nil
+}
+
+// Search returns entries matching query, most recent first.
+// The index is scanned in batches so only as much of it is read as needed to fill the limit.
+func (s *Storage) Search(ctx context.Context, query clockwork.Query) ([]*clockwork.Metadata, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = s.defaultLimit()
+	}
+
+	match := query.Matcher()
+	out := make([]*clockwork.Metadata, 0, limit)
+	for start := int64(0); len(out) < limit; start += searchBatchSize {
+		ids, err := s.client.LRange(ctx, s.indexKey, start, start+searchBatchSize-1).Result()
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("redis list index: %w", err)
 		}
-		var metadata clockwork.Metadata
-		if err := json.Unmarshal(value, &metadata); err != nil {
-			continue
+		if len(ids) == 0 {
+			break
+		}
+
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = s.reqKey(id)
+		}
+		values, err := s.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis get metadata: %w", err)
+		}
+
+		for _, value := range values {
+			raw, ok := value.(string)
+			if !ok {
+				continue
+			}
+			var metadata clockwork.Metadata
+			if err := json.Unmarshal([]byte(raw), &metadata); err != nil {
+				continue
+			}
+			if match(&metadata) {
+				out = append(out, &metadata)
+				if len(out) >= limit {
+					break
+				}
+			}
 		}
-		out = append(out, &metadata)
 	}
 
 	return out, nil
@@ -145,6 +228,67 @@ func (s *Storage) Cleanup(ctx context.Context, maxAge time.Duration) error {
 	return nil
 }
 
+// StoreProfile saves a pprof profile with the same TTL as metadata.
+func (s *Storage) StoreProfile(ctx context.Context, id, kind string, data []byte) error {
+	if err := s.client.Set(ctx, s.profileKey(id, kind), data, s.ttl).Err(); err != nil {
+		return fmt.Errorf("redis store profile: %w", err)
+	}
+	return nil
+}
+
+// GetProfile fetches a pprof profile saved with StoreProfile.
+func (s *Storage) GetProfile(ctx context.Context, id, kind string) ([]byte, error) {
+	data, err := s.client.Get(ctx, s.profileKey(id, kind)).Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("redis get profile: %w", err)
+	}
+	return data, nil
+}
+
+func (s *Storage) profileKey(id, kind string) string {
+	return s.prefix + ":profile:" + kind + ":" + id
+}
+
 func (s *Storage) reqKey(id string) string {
 	return s.prefix + ":req:" + id
 }
+
+func (s *Storage) defaultLimit() int {
+	if s.maxEntries > 0 {
+		return s.maxEntries
+	}
+	return 50
+}
+
+func (s *Storage) indexPosition(ctx context.Context, id string) (int64, error) {
+	pos, err := s.client.LPos(ctx, s.indexKey, id, redis.LPosArgs{}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, fmt.Errorf("clockwork metadata not found: %s", id)
+		}
+		return 0, fmt.Errorf("redis index position: %w", err)
+	}
+	return pos, nil
+}
+
+func (s *Storage) loadAll(ctx context.Context, ids []string) []*clockwork.Metadata {
+	out := make([]*clockwork.Metadata, 0, len(ids))
+	for _, id := range ids {
+		value, err := s.client.Get(ctx, s.reqKey(id)).Bytes()
+		if err != nil {
+			continue
+		}
+		var metadata clockwork.Metadata
+		if err := json.Unmarshal(value, &metadata); err != nil {
+			continue
+		}
+		out = append(out, &metadata)
+	}
+	return out
+}
+
+func reverseMetadata(items []*clockwork.Metadata) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
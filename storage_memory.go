@@ -14,6 +14,7 @@ type memoryEntry struct {
 	metadata  *Metadata
 	createdAt time.Time
 	bytes     int64
+	profiles  map[string][]byte
 }
 
 // InMemoryStorage keeps bounded metadata in-memory.
@@ -116,6 +117,77 @@ func (s *InMemoryStorage) List(ctx context.Context, limit int) ([]*Metadata, err
 	return out, nil
 }
 
+// Previous returns up to limit entries stored before id, oldest first.
+func (s *InMemoryStorage) Previous(ctx context.Context, id string, limit int) ([]*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	elem, ok := s.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("clockwork metadata not found: %s", id)
+	}
+	if limit <= 0 {
+		limit = s.maxEntries
+	}
+
+	out := make([]*Metadata, 0, min(limit, s.entries.Len()))
+	for elem = elem.Prev(); elem != nil && len(out) < limit; elem = elem.Prev() {
+		entry, _ := elem.Value.(*memoryEntry)
+		if entry != nil && entry.metadata != nil {
+			out = append(out, entry.metadata)
+		}
+	}
+	reverseMetadata(out)
+
+	return out, nil
+}
+
+// Next returns up to limit entries stored after id, oldest first.
+func (s *InMemoryStorage) Next(ctx context.Context, id string, limit int) ([]*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	elem, ok := s.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("clockwork metadata not found: %s", id)
+	}
+	if limit <= 0 {
+		limit = s.maxEntries
+	}
+
+	out := make([]*Metadata, 0, min(limit, s.entries.Len()))
+	for elem = elem.Next(); elem != nil && len(out) < limit; elem = elem.Next() {
+		entry, _ := elem.Value.(*memoryEntry)
+		if entry != nil && entry.metadata != nil {
+			out = append(out, entry.metadata)
+		}
+	}
+
+	return out, nil
+}
+
+// Search returns entries matching query, most recent first.
+func (s *InMemoryStorage) Search(ctx context.Context, query Query) ([]*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = s.maxEntries
+	}
+
+	match := query.Matcher()
+	out := make([]*Metadata, 0, min(limit, s.entries.Len()))
+	for elem := s.entries.Back(); elem != nil && len(out) < limit; elem = elem.Prev() {
+		entry, _ := elem.Value.(*memoryEntry)
+		if entry != nil && match(entry.metadata) {
+			out = append(out, entry.metadata)
+		}
+	}
+
+	return out, nil
+}
+
 // Cleanup removes entries older than maxAge.
 func (s *InMemoryStorage) Cleanup(ctx context.Context, maxAge time.Duration) error {
 	if maxAge <= 0 {
@@ -139,6 +211,47 @@ func (s *InMemoryStorage) Cleanup(ctx context.Context, maxAge time.Duration) err
 	return nil
 }
 
+// StoreProfile keeps a pprof profile with the stored metadata; it counts toward the size limit.
+func (s *InMemoryStorage) StoreProfile(ctx context.Context, id, kind string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.byID[id]
+	if !ok {
+		return fmt.Errorf("clockwork metadata not found: %s", id)
+	}
+	entry, _ := elem.Value.(*memoryEntry)
+	if entry == nil {
+		return fmt.Errorf("clockwork metadata not found: %s", id)
+	}
+	if entry.profiles == nil {
+		entry.profiles = make(map[string][]byte, 2)
+	}
+	delta := int64(len(data) - len(entry.profiles[kind]))
+	entry.profiles[kind] = data
+	entry.bytes += delta
+	s.totalBytes += delta
+
+	s.evictLocked()
+	return nil
+}
+
+// GetProfile fetches a pprof profile stored with StoreProfile.
+func (s *InMemoryStorage) GetProfile(ctx context.Context, id, kind string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	elem, ok := s.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("clockwork metadata not found: %s", id)
+	}
+	entry, _ := elem.Value.(*memoryEntry)
+	if entry == nil || len(entry.profiles[kind]) == 0 {
+		return nil, fmt.Errorf("clockwork %s profile not found: %s", kind, id)
+	}
+	return entry.profiles[kind], nil
+}
+
 func (s *InMemoryStorage) evictLocked() {
 	for s.entries.Len() > s.maxEntries || (s.maxBytes > 0 && s.totalBytes > s.maxBytes) {
 		elem := s.entries.Front()
@@ -161,6 +274,12 @@ func (s *InMemoryStorage) removeElementLocked(elem *list.Element) {
 	s.entries.Remove(elem)
 }
 
+func reverseMetadata(items []*Metadata) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -36,3 +36,27 @@ func TestInMemoryStorage_ListMostRecentFirst(t *testing.T) {
 	require.Equal(t, "c", items[0].ID)
 	require.Equal(t, "b", items[1].ID)
 }
+
+func TestInMemoryStorage_PreviousAndNextOldestFirst(t *testing.T) {
+	store := NewInMemoryStorage(10, 1024*1024)
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, store.Store(ctx, &Metadata{ID: id}))
+	}
+
+	previous, err := store.Previous(ctx, "d", 2)
+	require.NoError(t, err)
+	require.Len(t, previous, 2)
+	require.Equal(t, "b", previous[0].ID)
+	require.Equal(t, "c", previous[1].ID)
+
+	next, err := store.Next(ctx, "b", 0)
+	require.NoError(t, err)
+	require.Len(t, next, 3)
+	require.Equal(t, "c", next[0].ID)
+	require.Equal(t, "e", next[2].ID)
+
+	_, err = store.Previous(ctx, "missing", 1)
+	require.Error(t, err)
+}
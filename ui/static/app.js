@@ -0,0 +1,427 @@
+(function () {
+  "use strict";
+
+  var base = document.body.dataset.base || "/__clockwork";
+  var list = document.getElementById("request-list");
+  var empty = document.getElementById("request-empty");
+  var details = document.getElementById("details");
+  var selectedRow = null;
+
+  var tabs = [
+    { name: "Request", render: renderRequest },
+    { name: "Database", render: renderDatabase },
+    { name: "Models", render: renderModels },
+    { name: "Cache", render: renderCache },
+    { name: "Redis", render: renderRedis },
+    { name: "HTTP", render: renderHTTP },
+    { name: "Log", render: renderLog },
+    { name: "Timeline", render: renderTimeline },
+    { name: "User data", render: renderUserData }
+  ];
+
+  function el(tag, text, className) {
+    var node = document.createElement(tag);
+    if (text !== undefined && text !== null) {
+      node.textContent = String(text);
+    }
+    if (className) {
+      node.className = className;
+    }
+    return node;
+  }
+
+  function table(headers, rows) {
+    if (!rows.length) {
+      return el("p", "Nothing recorded.", "empty");
+    }
+    var t = el("table");
+    var head = el("tr");
+    headers.forEach(function (h) { head.appendChild(el("th", h)); });
+    t.appendChild(el("thead")).appendChild(head);
+    var body = t.appendChild(el("tbody"));
+    rows.forEach(function (cells) {
+      var tr = body.appendChild(el("tr"));
+      cells.forEach(function (cell) {
+        var td = tr.appendChild(el("td"));
+        if (cell instanceof Node) {
+          td.appendChild(cell);
+        } else {
+          td.textContent = cell === undefined || cell === null ? "" : String(cell);
+        }
+      });
+    });
+    return t;
+  }
+
+  function ms(value) {
+    return (Number(value) || 0).toFixed(2) + " ms";
+  }
+
+  function formatTime(seconds) {
+    if (!seconds) {
+      return "";
+    }
+    return new Date(seconds * 1000).toLocaleTimeString();
+  }
+
+  function statusClass(status) {
+    if (status >= 500) {
+      return "status-error";
+    }
+    if (status >= 400) {
+      return "status-warning";
+    }
+    return "";
+  }
+
+  function json(value) {
+    return el("pre", typeof value === "string" ? value : JSON.stringify(value, null, 2));
+  }
+
+  function loadRequests() {
+    fetch(base + "/app/requests", { headers: { Accept: "application/json" } })
+      .then(function (res) { return res.json(); })
+      .then(function (items) {
+        list.textContent = "";
+        items = Array.isArray(items) ? items : [];
+        empty.hidden = items.length > 0;
+        items.forEach(function (item) {
+          var tr = el("tr");
+          tr.appendChild(el("td", item.method));
+          tr.appendChild(el("td", item.uri));
+          tr.appendChild(el("td", item.responseStatus, statusClass(item.responseStatus)));
+          tr.appendChild(el("td", ms(item.responseDuration)));
+          tr.appendChild(el("td", formatTime(item.time)));
+          tr.addEventListener("click", function () { select(tr, item.id); });
+          list.appendChild(tr);
+        });
+      })
+      .catch(function () {
+        empty.hidden = false;
+        empty.textContent = "Failed to load requests.";
+      });
+  }
+
+  function select(row, id) {
+    if (selectedRow) {
+      selectedRow.classList.remove("selected");
+    }
+    selectedRow = row;
+    row.classList.add("selected");
+
+    fetch(base + "/" + encodeURIComponent(id), { headers: { Accept: "application/json" } })
+      .then(function (res) { return res.json(); })
+      .then(renderDetails)
+      .catch(function () {
+        details.textContent = "";
+        details.appendChild(el("p", "Failed to load request.", "empty"));
+      });
+  }
+
+  function renderDetails(meta) {
+    details.textContent = "";
+    details.appendChild(el("p", meta.method + " " + meta.uri, "summary"));
+    details.appendChild(el("p", [
+      "Status " + meta.responseStatus,
+      ms(meta.responseDuration),
+      (meta.databaseQueriesCount || 0) + " queries in " + ms(meta.databaseDuration),
+      meta.controller || ""
+    ].filter(Boolean).join(" · "), "meta"));
+
+    var bar = details.appendChild(el("div", null, "tabs"));
+    var content = details.appendChild(el("div"));
+    tabs.forEach(function (tab, index) {
+      var button = bar.appendChild(el("button", tab.name));
+      button.type = "button";
+      button.addEventListener("click", function () {
+        Array.prototype.forEach.call(bar.children, function (b) { b.classList.remove("active"); });
+        button.classList.add("active");
+        content.textContent = "";
+        content.appendChild(tab.render(meta));
+      });
+      if (index === 0) {
+        button.click();
+      }
+    });
+  }
+
+  function renderRequest(meta) {
+    var rows = [
+      ["ID", meta.id],
+      ["URL", meta.url || meta.uri],
+      ["Trace", meta.traceId || ""],
+      ["Parent", meta.parent || ""],
+      ["Memory", (meta.memoryUsage || 0) + " bytes"]
+    ];
+    var user = meta.authenticatedUser;
+    if (user) {
+      rows.push(["User", [user.username, user.email, user.id ? "#" + user.id : ""].filter(Boolean).join(" ")]);
+    }
+    Object.keys(meta.headers || {}).sort().forEach(function (name) {
+      rows.push([name, meta.headers[name]]);
+    });
+
+    var node = el("div");
+    node.appendChild(table(["Name", "Value"], rows));
+    section(node, "Query", meta.getData);
+    section(node, "Form data", meta.postData);
+    section(node, "Cookies", meta.cookies);
+    section(node, "Session", meta.sessionData);
+    if ((meta.subrequests || []).length) {
+      node.appendChild(el("h3", "Subrequests"));
+      node.appendChild(table(["URL", "Clockwork ID"], meta.subrequests.map(function (sub) {
+        var link = el("a", sub.id);
+        try {
+          link.href = new URL(sub.url).origin + (sub.path || "/__clockwork/") + encodeURIComponent(sub.id);
+          link.target = "_blank";
+          link.rel = "noopener";
+        } catch (e) {
+          link = sub.id;
+        }
+        return [sub.url, link];
+      })));
+    }
+    if ((meta.profiles || []).length) {
+      node.appendChild(el("h3", "Profiles"));
+      node.appendChild(table(["Kind", "Download"], meta.profiles.map(function (kind) {
+        var link = el("a", meta.id + "-" + kind + ".pprof");
+        link.href = base + "/" + encodeURIComponent(meta.id) + "/profile?kind=" + encodeURIComponent(kind);
+        return [kind, link];
+      })));
+    }
+    if (meta.requestData !== undefined) {
+      node.appendChild(el("h3", "Request body"));
+      node.appendChild(json(meta.requestData));
+    }
+    if (meta.responseData !== undefined) {
+      node.appendChild(el("h3", "Response body"));
+      node.appendChild(json(meta.responseData));
+    }
+    return node;
+  }
+
+  function section(node, title, data) {
+    var keys = Object.keys(data || {});
+    if (!keys.length) {
+      return;
+    }
+    node.appendChild(el("h3", title));
+    node.appendChild(table(["Name", "Value"], keys.sort().map(function (key) {
+      var value = data[key];
+      return [key, typeof value === "object" && value !== null ? json(value) : value];
+    })));
+  }
+
+  function renderDatabase(meta) {
+    var queries = table(["Query", "Duration", "Rows", "Connection", "Model", "File"], (meta.databaseQueries || []).map(function (q) {
+      var query = el("div");
+      query.appendChild(el("pre", q.query, q.slow ? "slow" : ""));
+      if (q.runnable) {
+        query.appendChild(el("pre", q.runnable, "runnable"));
+      } else if ((q.bindings || []).length) {
+        query.appendChild(el("pre", JSON.stringify(q.bindings), "runnable"));
+      }
+      if (q.error) {
+        query.appendChild(el("span", q.error, "status-error"));
+      }
+      if (q.explain) {
+        var plan = query.appendChild(el("details"));
+        plan.appendChild(el("summary", "Query plan"));
+        plan.appendChild(el("pre", q.explain, "runnable"));
+      }
+      return [
+        query,
+        ms(q.duration),
+        q.rowsAffected || "",
+        q.connection,
+        q.model || "",
+        q.file ? q.file + ":" + q.line : ""
+      ];
+    }));
+    var groups = queryGroups(meta.databaseQueries || []);
+    if (!(meta.queryWarnings || []).length && groups.length < 2) {
+      return queries;
+    }
+
+    var node = el("div");
+    if ((meta.queryWarnings || []).length) {
+      node.appendChild(el("h3", "Warnings"));
+      node.appendChild(table(["Type", "Query", "Count", "Duration", "File"], meta.queryWarnings.map(function (w) {
+        return [
+          el("span", w.type === "n+1" ? "N+1" : "Duplicate", "status-warning"),
+          el("pre", w.query),
+          w.count,
+          ms(w.duration),
+          w.file ? w.file + ":" + w.line : ""
+        ];
+      })));
+    }
+    if (groups.length > 1) {
+      node.appendChild(el("h3", "By table"));
+      node.appendChild(table(["Table", "Operation", "Count", "Duration"], groups.map(function (g) {
+        return [g.table, g.operation, g.count, ms(g.duration)];
+      })));
+    }
+    node.appendChild(el("h3", "Queries"));
+    node.appendChild(queries);
+    return node;
+  }
+
+  // queryGroups totals queries by primary table and operation, slowest group first.
+  function queryGroups(queries) {
+    var byKey = {};
+    var groups = [];
+    queries.forEach(function (q) {
+      var tableName = (q.tables || [])[0] || q.model || "";
+      var key = tableName + "\u0000" + (q.operation || "");
+      var g = byKey[key];
+      if (!g) {
+        g = byKey[key] = { table: tableName, operation: q.operation || "", count: 0, duration: 0 };
+        groups.push(g);
+      }
+      g.count++;
+      g.duration += q.duration || 0;
+    });
+    return groups.sort(function (a, b) { return b.duration - a.duration; });
+  }
+
+  function renderModels(meta) {
+    var counts = {};
+    [["retrieved", meta.modelsRetrieved], ["created", meta.modelsCreated], ["updated", meta.modelsUpdated], ["deleted", meta.modelsDeleted]].forEach(function (pair) {
+      Object.keys(pair[1] || {}).forEach(function (model) {
+        counts[model] = counts[model] || {};
+        counts[model][pair[0]] = pair[1][model];
+      });
+    });
+    var node = el("div");
+    node.appendChild(table(["Model", "Retrieved", "Created", "Updated", "Deleted"], Object.keys(counts).sort().map(function (model) {
+      var c = counts[model];
+      return [model, c.retrieved || "", c.created || "", c.updated || "", c.deleted || ""];
+    })));
+    if ((meta.modelsActions || []).length) {
+      node.appendChild(el("h3", "Actions"));
+      node.appendChild(table(["Action", "Model", "Key", "Count", "Duration", "File"], meta.modelsActions.map(function (a) {
+        return [
+          a.action,
+          a.model,
+          a.key === undefined ? "" : String(a.key),
+          a.count || "",
+          a.duration ? ms(a.duration) : "",
+          a.file ? a.file + ":" + a.line : ""
+        ];
+      })));
+    }
+    return node;
+  }
+
+  function renderCache(meta) {
+    var node = el("div");
+    var reads = meta.cacheReads || 0;
+    var ratio = reads ? Math.round((meta.cacheHits || 0) / reads * 100) + "% hit ratio" : "no reads";
+    node.appendChild(el("p", [
+      reads + " reads",
+      (meta.cacheHits || 0) + " hits",
+      (meta.cacheWrites || 0) + " writes",
+      (meta.cacheDeletes || 0) + " deletes",
+      ratio,
+      ms(meta.cacheTime)
+    ].join(" · "), "summary"));
+    node.appendChild(table(["Type", "Key", "Store", "Size", "TTL", "Duration"], (meta.cacheQueries || []).map(function (q) {
+      return [
+        el("span", q.error ? q.type + ": " + q.error : q.type, q.error ? "status-error" : ""),
+        q.key,
+        q.connection || "",
+        q.size ? q.size + " bytes" : "",
+        q.expiration ? q.expiration + " s" : "",
+        ms(q.duration)
+      ];
+    })));
+    return node;
+  }
+
+  function renderRedis(meta) {
+    return table(["Command", "Parameters", "Duration", "Connection", "File"], (meta.redisCommands || []).map(function (c) {
+      var command = el("span", c.error ? c.command + ": " + c.error : c.command, c.error ? "status-error" : "");
+      return [
+        command,
+        (c.parameters || []).map(String).join(" "),
+        ms(c.duration),
+        c.connection || "",
+        c.file ? c.file + ":" + c.line : ""
+      ];
+    }));
+  }
+
+  function renderHTTP(meta) {
+    return table(["Method", "URL", "Status", "Size", "Duration"], (meta.httpRequests || []).map(function (call) {
+      var request = call.request || {};
+      var response = call.response || {};
+      var status = el("span", call.error || String(response.status || ""), call.error ? "status-error" : statusClass(response.status));
+      return [request.method, request.url, status, (response.size || 0) + " bytes", ms(call.duration)];
+    }));
+  }
+
+  function renderLog(meta) {
+    return table(["Time", "Level", "Message", "Context"], (meta.log || []).map(function (entry) {
+      return [formatTime(entry.time), entry.level, entry.message, entry.context ? json(entry.context) : ""];
+    }));
+  }
+
+  // nestEvents orders events so children follow their parent and records each event's depth.
+  function nestEvents(events) {
+    var byId = {};
+    var children = {};
+    var roots = [];
+    events.forEach(function (event) {
+      if (event.id) {
+        byId[event.id] = event;
+      }
+    });
+    events.forEach(function (event) {
+      if (event.parent && byId[event.parent]) {
+        (children[event.parent] = children[event.parent] || []).push(event);
+      } else {
+        roots.push(event);
+      }
+    });
+    var byStart = function (a, b) { return (a.start || 0) - (b.start || 0); };
+    var out = [];
+    (function walk(list, depth) {
+      list.sort(byStart).forEach(function (event) {
+        out.push({ event: event, depth: depth });
+        walk(children[event.id] || [], depth + 1);
+      });
+    })(roots, 0);
+    return out;
+  }
+
+  function renderTimeline(meta) {
+    var events = nestEvents(meta.timelineData || []);
+    var start = meta.time || 0;
+    var total = meta.responseDuration || 1;
+    return table(["Event", "Duration", ""], events.map(function (item) {
+      var event = item.event;
+      var label = el("span", event.name + (event.description ? ": " + event.description : ""));
+      label.style.paddingLeft = (item.depth * 16) + "px";
+      var track = el("div", null, "timeline-track");
+      var bar = track.appendChild(el("div", null, "timeline-bar"));
+      var offset = Math.max(0, (event.start - start) * 1000);
+      bar.style.marginLeft = Math.min(100, (offset / total) * 100) + "%";
+      bar.style.width = Math.min(100, ((event.duration || 0) / total) * 100) + "%";
+      if (event.color) {
+        bar.style.background = event.color;
+      }
+      return [label, ms(event.duration), track];
+    }));
+  }
+
+  function renderUserData(meta) {
+    var data = meta.userData || {};
+    return table(["Key", "Value"], Object.keys(data).sort().map(function (key) {
+      return [key, json(data[key])];
+    }));
+  }
+
+  document.getElementById("refresh").addEventListener("click", loadRequests);
+  loadRequests();
+})();
@@ -0,0 +1,147 @@
+// Package ui serves the embedded Clockwork web app at /__clockwork/app.
+//
+// The app is a single page assembled from the embedded static assets. It lists
+// recent requests via Clockwork.ListMetadata and loads request details from the
+// GET /__clockwork/:id metadata route, so it works without the browser extension.
+package ui
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RezaKargar/go-clockwork"
+)
+
+const (
+	// AppPath is the route serving the web app.
+	AppPath = "/__clockwork/app"
+	// RequestsPath is the route serving the request list consumed by the web app.
+	RequestsPath = AppPath + "/requests"
+
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+var indexTemplate = template.Must(template.ParseFS(staticFiles, "static/index.html"))
+
+type indexData struct {
+	Base   string
+	Style  template.CSS
+	Script template.JS
+}
+
+// RequestSummary is the list entry returned by the requests route.
+type RequestSummary struct {
+	ID                   string  `json:"id"`
+	Time                 float64 `json:"time"`
+	Method               string  `json:"method"`
+	URI                  string  `json:"uri"`
+	Controller           string  `json:"controller,omitempty"`
+	ResponseStatus       int     `json:"responseStatus"`
+	ResponseDuration     float64 `json:"responseDuration"`
+	DatabaseQueriesCount int     `json:"databaseQueriesCount"`
+}
+
+// Handler serves the web app on AppPath and the request list on RequestsPath.
+func Handler(cw *clockwork.Clockwork) http.Handler {
+	page, err := renderIndex()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cw == nil || !cw.IsEnabled() {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/requests") {
+			serveRequests(w, r, cw)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, "clockwork app is unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(page)
+	})
+}
+
+// RedirectHandler redirects GET /__clockwork to the web app.
+func RedirectHandler() http.Handler {
+	return http.RedirectHandler(AppPath, http.StatusFound)
+}
+
+func serveRequests(w http.ResponseWriter, r *http.Request, cw *clockwork.Clockwork) {
+	limit := defaultListLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			limit = min(parsed, maxListLimit)
+		}
+	}
+
+	items, err := cw.ListMetadata(r.Context(), limit)
+	status := http.StatusOK
+	var payload interface{}
+	if err != nil {
+		status = http.StatusInternalServerError
+		payload = map[string]string{"error": "failed to list metadata"}
+	} else {
+		summaries := make([]RequestSummary, 0, len(items))
+		for _, item := range items {
+			if item == nil {
+				continue
+			}
+			summaries = append(summaries, RequestSummary{
+				ID:                   item.ID,
+				Time:                 item.Time,
+				Method:               item.Method,
+				URI:                  item.URI,
+				Controller:           item.Controller,
+				ResponseStatus:       item.ResponseStatus,
+				ResponseDuration:     item.ResponseDuration,
+				DatabaseQueriesCount: item.DatabaseQueriesCount,
+			})
+		}
+		payload = summaries
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Clockwork-Version", clockwork.ProtocolVersion)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func renderIndex() ([]byte, error) {
+	style, err := staticFiles.ReadFile("static/app.css")
+	if err != nil {
+		return nil, err
+	}
+	script, err := staticFiles.ReadFile("static/app.js")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = indexTemplate.Execute(&buf, indexData{
+		Base:   strings.TrimSuffix(AppPath, "/app"),
+		Style:  template.CSS(style),
+		Script: template.JS(script),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
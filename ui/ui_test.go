@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RezaKargar/go-clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_ServesAppPage(t *testing.T) {
+	cw := clockwork.NewClockwork(clockwork.DefaultConfig(), clockwork.NewInMemoryStorage(10, 1024*1024))
+
+	res := httptest.NewRecorder()
+	Handler(cw).ServeHTTP(res, httptest.NewRequest(http.MethodGet, AppPath, nil))
+
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Contains(t, res.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, res.Body.String(), `data-base="/__clockwork"`)
+	require.Contains(t, res.Body.String(), "loadRequests")
+}
+
+func TestHandler_ListsRequestSummaries(t *testing.T) {
+	cw := clockwork.NewClockwork(clockwork.DefaultConfig(), clockwork.NewInMemoryStorage(10, 1024*1024))
+	require.NoError(t, cw.SaveMetadata(context.Background(), &clockwork.Metadata{ID: "a", Method: "GET", URI: "/a"}))
+	require.NoError(t, cw.SaveMetadata(context.Background(), &clockwork.Metadata{ID: "b", Method: "POST", URI: "/b", ResponseStatus: 500}))
+
+	res := httptest.NewRecorder()
+	Handler(cw).ServeHTTP(res, httptest.NewRequest(http.MethodGet, RequestsPath+"?limit=1", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var items []RequestSummary
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &items))
+	require.Len(t, items, 1)
+	require.Equal(t, "b", items[0].ID)
+	require.Equal(t, 500, items[0].ResponseStatus)
+}